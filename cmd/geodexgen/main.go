@@ -33,7 +33,12 @@ var rootCmd = &cobra.Command{
 
 		// setup GeoDex
 		ddbBasePath, _ := cmd.Flags().GetString("geodex")
-		ddb := geodex.NewDiskDB(&ddbBasePath)
+		ddb, err := geodex.NewDiskDB(&ddbBasePath)
+		if err != nil {
+			log.WithError(err).Error("can't open GeoDex, stop silpht while importing")
+			return
+		}
+		defer ddb.Close()
 
 		// setup MAD MariaDB connection
 		sdbHostname, _ := cmd.Flags().GetString("sql-hostname")
@@ -54,21 +59,28 @@ var rootCmd = &cobra.Command{
 		}
 		log.Infof("Connected to sqldb %s running %s", sdbHostname, version)
 
-		// setup silpht Tile38 connection
+		// setup silpht Tile38 connection, or just an in-memory index for the BOQ import
+		// if silpht keeps its index in memory too
+		var index geodex.FortIndex
 		tdbHostname, _ := cmd.Flags().GetString("t-hostname")
 		tdbPassword, _ := cmd.Flags().GetString("t-password")
-		tdb, err := geodex.NewTDB(tdbHostname, tdbPassword)
-		if err != nil {
-			log.WithError(err).Error("tdb connection failed")
-			return
+		if tdbHostname != "" {
+			index, err = geodex.NewTDB(tdbHostname, tdbPassword)
+			if err != nil {
+				log.WithError(err).Error("tdb connection failed")
+				return
+			}
+		} else {
+			log.Info("no Tile38 host given, only writing the GeoDex storage")
+			index = geodex.NewMemIndex()
 		}
-		defer tdb.Close()
+		defer index.Close()
 
 		// setup done
 		timeTrack(tStart, "setup")
 		tStart = time.Now()
 
-		// get Pokestops from MAD and insert them into tile38
+		// get Pokestops from MAD and insert them into the index
 		ps, err := sdb.NewMADPokestopScanner()
 		if err != nil {
 			log.WithError(err).Error("selecting pokestops failed")
@@ -76,7 +88,7 @@ var rootCmd = &cobra.Command{
 		}
 		defer ps.Close()
 
-		psCount := 0
+		var forts []*geodex.Fort
 		for ps.Next() {
 			p, err := ps.ScanPokestop()
 			if err != nil {
@@ -85,15 +97,18 @@ var rootCmd = &cobra.Command{
 			}
 
 			f := p.ToFort()
-			tdb.InsertFort(f)
-			ddb.MergeFort(f)
-			psCount++
+			index.InsertFort(f)
+			forts = append(forts, f)
+		}
+		if err = ddb.MergeForts(forts); err != nil {
+			log.WithError(err).Error("saving pokestops failed")
+			return
 		}
-		log.Infoln("Pokestops read from MAD:", psCount)
+		log.Infoln("Pokestops read from MAD:", len(forts))
 		timeTrack(tStart, "mad pokestop import")
 		tStart = time.Now()
 
-		// get Gyms from MAD and insert them into tile38
+		// get Gyms from MAD and insert them into the index
 		gs, err := sdb.NewMADGymScanner()
 		if err != nil {
 			log.WithError(err).Error("selecting gyms failed")
@@ -101,7 +116,7 @@ var rootCmd = &cobra.Command{
 		}
 		defer gs.Close()
 
-		gsCount := 0
+		forts = nil
 		for gs.Next() {
 			g, err := gs.ScanGym()
 			if err != nil {
@@ -110,11 +125,14 @@ var rootCmd = &cobra.Command{
 			}
 
 			f := g.ToFort()
-			tdb.InsertFort(f)
-			ddb.MergeFort(f)
-			gsCount++
+			index.InsertFort(f)
+			forts = append(forts, f)
+		}
+		if err = ddb.MergeForts(forts); err != nil {
+			log.WithError(err).Error("saving gyms failed")
+			return
 		}
-		log.Infoln("Gyms read from MAD:", gsCount)
+		log.Infoln("Gyms read from MAD:", len(forts))
 		timeTrack(tStart, "mad gym import")
 		tStart = time.Now()
 
@@ -131,8 +149,9 @@ var rootCmd = &cobra.Command{
 			boqCellCount := 0
 			boqPOICount := 0
 			boqGymCount := 0
-			namesAdded := 0
 			namesKept := 0
+			// saved together after parsing
+			named := make(map[string]*geodex.Fort)
 			for {
 				done := false
 
@@ -153,14 +172,20 @@ var rootCmd = &cobra.Command{
 								continue
 							}
 
-							// get gym GUID from tile db
+							// get gym GUID from the index
 							gymLocation := pogo.Location{
 								Latitude:  poi.Location.Coordinates[1],
 								Longitude: poi.Location.Coordinates[0],
 							}
-							tFort, err := tdb.GetNearestFort(gymLocation, 0.1)
+							tFort, err := index.GetNearestFort(gymLocation, 0.1)
 							if err != nil {
-								// fort doesn't exist in tile38 db, that's ok
+								// fort doesn't exist in the index, that's ok
+								continue
+							}
+
+							if _, ok := named[*tFort.GUID]; ok {
+								// named by an earlier BOQ file
+								namesKept++
 								continue
 							}
 
@@ -176,13 +201,10 @@ var rootCmd = &cobra.Command{
 								continue
 							}
 
-							// set name and save to disk
-							dFort.Name = &poi.Name
-							if err = ddb.SaveFort(dFort); err != nil {
-								log.Errorf("couldn't edit fort %s", *tFort.GUID)
-								return
-							}
-							namesAdded++
+							// set name
+							name := poi.Name
+							dFort.Name = &name
+							named[*tFort.GUID] = dFort
 						}
 					}
 				case <-boqDone: // boq.Run() ended
@@ -194,19 +216,32 @@ var rootCmd = &cobra.Command{
 				}
 			}
 
+			forts = nil
+			for _, f := range named {
+				forts = append(forts, f)
+			}
+			if err = ddb.MergeForts(forts); err != nil {
+				log.WithError(err).Error("saving gym names failed")
+				return
+			}
+
 			log.Infof("processed BOQ data: %d cells containing %d POIs with %d gyms",
 				boqCellCount, boqPOICount, boqGymCount)
 			log.Infof("added names to %d gyms, got %d gyms which already had a name",
-				namesAdded, namesKept)
+				len(named), namesKept)
 
 			timeTrack(tStart, "boq import")
 			tStart = time.Now()
 		}
 
+		gymCount, _ := ddb.CountFortsOfType(geodex.FortTypeGym)
+		stopCount, _ := ddb.CountFortsOfType(geodex.FortTypeStop)
+		log.Infof("GeoDex contains %d gyms and %d pokestops", gymCount, stopCount)
+
 		// the thing we're doing this for
 		defer timeTrack(tStart, "example lookup")
-		printFortName(tdb, ddb, 52.5395, 13.4161)
-		printFortName(tdb, ddb, 52.5399, 13.4208)
+		printFortName(index, ddb, 52.5395, 13.4161)
+		printFortName(index, ddb, 52.5399, 13.4208)
 	},
 }
 
@@ -216,12 +251,12 @@ func timeTrack(start time.Time, name string) {
 	log.Printf("> %s took %s", name, elapsed)
 }
 
-func printFortName(tdb *geodex.TDB, ddb *geodex.DiskDB, lat, lon float64) {
+func printFortName(index geodex.FortIndex, ddb *geodex.DiskDB, lat, lon float64) {
 	searchCenter := pogo.Location{
 		Latitude:  lat,
 		Longitude: lon,
 	}
-	sFort, err := tdb.GetNearestFort(searchCenter, 1000)
+	sFort, err := index.GetNearestFort(searchCenter, 1000)
 	if err != nil {
 		log.WithError(err).Error("can't find nearby fort")
 		return
@@ -242,7 +277,7 @@ func main() {
 	rootCmd.PersistentFlags().String("sql-username", "rocketdb", "SQL DB user")
 	rootCmd.PersistentFlags().String("sql-password", "rocketdb", "SQL DB password")
 
-	rootCmd.PersistentFlags().String("t-hostname", "", "Tile38 DB hostname")
+	rootCmd.PersistentFlags().String("t-hostname", "", "Tile38 DB hostname, leave empty if silpht runs without Tile38")
 	rootCmd.PersistentFlags().String("t-password", "", "Tile38 DB password")
 
 	rootCmd.PersistentFlags().String("geodex", "geodex-storage", "GeoDex storage path")
@@ -250,7 +285,6 @@ func main() {
 	rootCmd.PersistentFlags().StringArrayP("boq", "b", []string{}, "BookOfQuests JSON file(s)")
 
 	rootCmd.MarkPersistentFlagRequired("sql-hostname")
-	rootCmd.MarkPersistentFlagRequired("t-hostname")
 	rootCmd.MarkPersistentFlagRequired("geodex")
 
 	if err := rootCmd.Execute(); err != nil {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -11,7 +13,9 @@ import (
 	"github.com/spezifisch/silphtelescope/internal/db"
 	"github.com/spezifisch/silphtelescope/pkg/geodex"
 	"github.com/spezifisch/silphtelescope/pkg/http"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
 	"github.com/spezifisch/silphtelescope/pkg/matrix"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 	"github.com/spezifisch/silphtelescope/pkg/roomservice"
 )
@@ -26,27 +30,85 @@ var (
 			a.run()
 		},
 	}
+	replayCmd = &cobra.Command{
+		Use:   "replay <recording.jsonl>",
+		Short: "Run the bot with a webhook recording instead of live scanner data",
+		Long: `Feeds a recording made with RecordPath through the webhook parsers,
+with timestamps shifted to now. The webhooks are disabled meanwhile.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			speed, _ := cmd.Flags().GetFloat64("speed")
+			a := app{
+				replayFile:  args[0],
+				replaySpeed: speed,
+			}
+			a.run()
+		},
+	}
 )
 
 type app struct {
-	poster *roomservice.Poster
-	matrix *matrix.Matrix
+	poster   *roomservice.Poster
+	matrix   *matrix.Matrix
+	sources  *ingest.Manager
+	queue    *ingest.Queue
+	recorder *ingest.Recorder
+
+	// replay this recording instead of running webhooks
+	replayFile  string
+	replaySpeed float64
 }
 
 func (a *app) run() {
 	// http
 	http.Bind = requireString("HTTPBind")
+	allowedNets, err := http.ParseAllowedNets(viper.GetStringSlice("WebhookAllowedNets"))
+	if err != nil {
+		log.WithError(err).Error("invalid WebhookAllowedNets")
+		return
+	}
+	http.Auth = http.WebhookAuth{
+		Token:           viper.GetString("WebhookToken"),
+		TokenHeader:     viper.GetString("WebhookTokenHeader"),
+		HMACSecret:      viper.GetString("WebhookHMACSecret"),
+		SignatureHeader: viper.GetString("WebhookSignatureHeader"),
+		AllowedNets:     allowedNets,
+	}
+	if http.Auth.Token == "" && http.Auth.HMACSecret == "" && len(allowedNets) == 0 {
+		log.Warn("webhook authentication is disabled, anyone who can reach HTTPBind can post data")
+	}
+	// ingest
+	overflowPolicy, err := ingest.ParseOverflowPolicy(viper.GetString("IngestOverflowPolicy"))
+	if err != nil {
+		log.WithError(err).Error("invalid IngestOverflowPolicy")
+		return
+	}
+	queueSize := viper.GetInt("IngestQueueSize")
+	if recordPath := viper.GetString("RecordPath"); recordPath != "" && a.replayFile == "" {
+		a.recorder, err = ingest.NewRecorder(recordPath, viper.GetInt64("RecordMaxSizeMB")*1024*1024, viper.GetInt("RecordKeep"))
+		if err != nil {
+			log.WithError(err).Errorf("can't open recording %s", recordPath)
+			return
+		}
+		log.Infof("recording webhooks to %s", recordPath)
+	}
 	// matrix
 	homeserver := requireString("Homeserver")
 	userID := requireString("user_id")
 	accessToken := requireString("access_token")
+	// permissions
+	admins := viper.GetStringSlice("Admins")
+	if len(admins) == 0 {
+		log.Warn("no bot admins configured, admin commands are disabled")
+	}
 	// internal/db
 	dbBasePath := requireString("DBBasePath")
 	// pokedex
 	pokedexFile := requireString("Pokedex")
 	// geodex
 	geoDexBasePath := requireString("GeoDexBasePath")
-	t38Hostname := requireString("Tile38Hostname")
+	// the forts are kept in memory without tile38
+	t38Hostname := viper.GetString("Tile38Hostname")
 	t38Password := viper.GetString("Tile38Password")
 
 	// read pokedex
@@ -64,12 +126,20 @@ func (a *app) run() {
 			log.WithError(err).Errorf("failed initializing GeoDex (t38 host: %s, diskdb: %s)",
 				t38Hostname, geoDexBasePath)
 		} else {
-			log.Info("GeoDex initialized")
+			if t38Hostname == "" {
+				log.Info("GeoDex initialized with in-memory fort index")
+			} else {
+				log.Info("GeoDex initialized with Tile38")
+			}
 			break
 		}
 
 		time.Sleep(1 * time.Second)
 	}
+	if geoDex != nil {
+		// geodexgen can only write the fort db after silpht closed it
+		defer geoDex.Close()
+	}
 
 	// setup db for dynamic storage
 	db := db.NewDB(&dbBasePath)
@@ -77,30 +147,123 @@ func (a *app) run() {
 	// setup matrix client
 	a.matrix = matrix.New(homeserver, userID, accessToken)
 
-	// wire roomservice channels (RX) to http server (TX)
+	// wire roomservice channels (RX) to ingest sources (TX)
 	a.poster = roomservice.NewPoster(a.matrix, db)
 	a.matrix.SetPoster(a.poster)
 	a.poster.ResumeStateOnStartup = true
 	a.poster.MainControl = a
+	a.poster.Admins = admins
+	a.poster.PowerLevels = a.matrix
 	a.poster.Pokedex = dex
 	a.poster.GeoDex = geoDex
+	a.poster.GymStateTTL = viper.GetDuration("GymStateTTL")
 	a.poster.GymUpdates = make(chan pogo.Gym, 50)
 	a.poster.RaidUpdates = make(chan pogo.Raid, 50)
 	a.poster.SpawnUpdates = make(chan pogo.Spawn, 200)
+	a.poster.WeatherUpdates = make(chan pogo.Weather, 50)
+	a.poster.InvasionUpdates = make(chan pogo.Invasion, 50)
+	a.poster.QuestUpdates = make(chan pogo.Quest, 200)
+	a.poster.LureUpdates = make(chan pogo.Lure, 50)
+	a.poster.FortUpdates = make(chan pogo.Fort, 200)
 
-	// sender
-	http.GymUpdates = a.poster.GymUpdates
-	http.RaidUpdates = a.poster.RaidUpdates
-	http.SpawnUpdates = a.poster.SpawnUpdates
+	// senders
+	if a.replayFile != "" {
+		// nothing to drop, the replay waits for the poster
+		a.sources = ingest.NewManager(a.poster.Sink())
+		a.poster.Ingest = a.sources
+		a.sources.Add(http.NewReplaySource(a.replayFile, a.replaySpeed))
+	} else {
+		// the queue decouples webhooks from slow matrix sends
+		a.queue = ingest.NewQueue(queueSize, overflowPolicy)
+		a.sources = ingest.NewManager(a.queue.Sink())
+		a.poster.Ingest = a.sources
+		for _, src := range []*http.WebhookSource{http.NewMADSource(), http.NewRDMSource()} {
+			src.Recorder = a.recorder
+			http.AddWebhook(src)
+			a.sources.Add(src)
+		}
+		go a.queue.Run(a.poster.Sink())
+		metrics.OnScrape(func() {
+			metrics.QueueLength.WithLabelValues("ingest").Set(float64(a.queue.Status().Length))
+		})
+	}
+	metrics.OnScrape(a.poster.UpdateMetrics)
+
+	// admin API
+	if token := viper.GetString("AdminAPIToken"); token != "" {
+		http.AddAdminAPI(a.poster, token)
+	}
+
+	// links posted by the web and stream commands start with this URL
+	publicURL := viper.GetString("PublicURL")
+	if (viper.GetBool("WebUI") || viper.GetBool("EventStream")) && publicURL == "" {
+		log.Warn("PublicURL is missing, WebUI and EventStream are disabled")
+	} else {
+		if viper.GetBool("WebUI") {
+			a.poster.WebUI = http.AddWebUI(a.poster, dex, publicURL)
+		}
+		if viper.GetBool("EventStream") {
+			a.poster.Stream = http.AddEventStream(a.poster, publicURL)
+		}
+	}
+
+	// public map data
+	if viper.GetBool("GeoJSONFeed") {
+		http.AddGeoJSONFeed(a.poster)
+	}
+
+	// health checks
+	maxSyncAge := viper.GetDuration("HealthMaxSyncAge")
+	maxDataAge := viper.GetDuration("HealthMaxDataAge")
+	http.AddHealthCheck(http.HealthCheck{
+		Name:     "matrix_sync",
+		Liveness: true,
+		Check: func() (string, error) {
+			return a.matrix.CheckSync(maxSyncAge)
+		},
+	})
+	if t38Hostname != "" {
+		http.AddHealthCheck(http.HealthCheck{
+			Name: "tile38",
+			Check: func() (string, error) {
+				if geoDex == nil {
+					return "", errors.New("GeoDex not initialized")
+				}
+				return "", geoDex.Index.Ping()
+			},
+		})
+	}
+	http.AddHealthCheck(http.HealthCheck{
+		Name: "pokedex",
+		Check: func() (status string, err error) {
+			if dex.Len() == 0 {
+				return "", errors.New("pokedex is empty")
+			}
+			return fmt.Sprintf("%d entries", dex.Len()), nil
+		},
+	})
+	http.AddHealthCheck(http.HealthCheck{
+		Name: "scanner_data",
+		Check: func() (string, error) {
+			return a.poster.CheckData(maxDataAge)
+		},
+	})
 
 	go a.poster.Run() // filters relevant data and posts to matrix rooms
 	go a.matrix.Run() // matrix sync loop, handles commands
-	http.Run()        // handles admin webinterface and MAD webhook
+	http.Run()        // handles admin webinterface and scanner webhooks
 }
 
 // Stop is called by Poster when graceful shutdown command is received
 func (a *app) Stop() {
 	a.matrix.Stop()
+	a.sources.Stop()
+	if a.queue != nil {
+		a.queue.Stop()
+	}
+	if a.recorder != nil {
+		a.recorder.Close()
+	}
 	http.Stop()
 }
 
@@ -127,19 +290,21 @@ func main() {
 	rootCmd.PersistentFlags().StringP("homeserver", "s", "https://matrix.example.com", "matrix homeserver")
 	rootCmd.PersistentFlags().StringP("userid", "u", "@foo:matrix.example.com", "user id for matrix homeserver")
 	rootCmd.PersistentFlags().StringP("token", "t", "", "access token for matrix homeserver")
+	rootCmd.PersistentFlags().StringSliceP("admins", "", []string{}, "matrix user ids of bot admins")
 
 	rootCmd.PersistentFlags().StringP("db", "d", "db-silpht", "base path for dynamic data storage")
 
 	rootCmd.PersistentFlags().StringP("pokedex", "", "./data/pokedex.json", "path to pokedex.json generated by pokedexgen")
 
 	rootCmd.PersistentFlags().StringP("geodex", "", "./tmp/geodex", "path to geodex generated by geodexgen")
-	rootCmd.PersistentFlags().StringP("t38hostname", "", "localhost:9851", "hostname for tile38 server")
+	rootCmd.PersistentFlags().StringP("t38hostname", "", "", "hostname for tile38 server, the fort index is kept in memory if empty")
 	rootCmd.PersistentFlags().StringP("t38password", "", "", "password for tile38 server if needed")
 
 	viper.BindPFlag("HTTPBind", rootCmd.PersistentFlags().Lookup("bind"))
 	viper.BindPFlag("Homeserver", rootCmd.PersistentFlags().Lookup("homeserver"))
 	viper.BindPFlag("user_id", rootCmd.PersistentFlags().Lookup("userid"))
 	viper.BindPFlag("access_token", rootCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag("Admins", rootCmd.PersistentFlags().Lookup("admins"))
 	viper.BindPFlag("DBBasePath", rootCmd.PersistentFlags().Lookup("db"))
 	viper.BindPFlag("Pokedex", rootCmd.PersistentFlags().Lookup("pokedex"))
 	viper.BindPFlag("GeoDexBasePath", rootCmd.PersistentFlags().Lookup("geodex"))
@@ -150,6 +315,16 @@ func main() {
 	viper.SetDefault("DBBasePath", "db-silpht")
 	viper.SetDefault("Pokedex", "./data/pokedex.json")
 	viper.SetDefault("Tile38Password", "")
+	viper.SetDefault("IngestQueueSize", 1000)
+	viper.SetDefault("IngestOverflowPolicy", ingest.DropOldest.ToString())
+	viper.SetDefault("RecordMaxSizeMB", 100)
+	viper.SetDefault("RecordKeep", 3)
+	viper.SetDefault("HealthMaxSyncAge", 5*time.Minute)
+	viper.SetDefault("HealthMaxDataAge", 0)
+	viper.SetDefault("GymStateTTL", 7*24*time.Hour)
+
+	replayCmd.Flags().Float64("speed", 1, "replay speed factor, 0 replays as fast as possible")
+	rootCmd.AddCommand(replayCmd)
 
 	http.Init()
 
@@ -18,6 +18,13 @@ func (db *DB) SaveRoomConfig(roomID string, roomConfig *roomservice.RoomConfig)
 	}
 }
 
+// DeleteRoomConfig removes a room's RoomConfig
+func (db *DB) DeleteRoomConfig(roomID string) {
+	if err := db.dvRoomConfig.Erase(roomID); err != nil {
+		log.WithError(err).Warnf("failed deleting RoomConfig of %s", roomID)
+	}
+}
+
 // SaveRoomConfigs is called by roomservice to persist them
 func (db *DB) SaveRoomConfigs(roomConfigs map[string]*roomservice.RoomConfig) {
 	for roomID, rc := range roomConfigs {
@@ -11,6 +11,16 @@ func IntArrayContains(s []int, e int) bool {
 	return false
 }
 
+// StringArrayContains checks if array s contains value e.
+func StringArrayContains(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
 // IntArrayUnorderedRemove removes element i from array s but changes element order.
 // Source: https://stackoverflow.com/a/37335777
 func IntArrayUnorderedRemove(s []int, i int) []int {
@@ -12,6 +12,7 @@ type Fort struct {
 	Latitude  float64  `json:"latitude"`
 	Longitude float64  `json:"longitude"`
 	Name      *string  `json:"name,omitempty"` // optional
+	URL       *string  `json:"url,omitempty"`  // optional, image
 	Type      FortType `json:"type"`
 }
 
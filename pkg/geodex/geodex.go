@@ -2,35 +2,84 @@ package geodex
 
 import (
 	"errors"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 )
 
 // GeoDex is the wrapper that should be used when using an already initialized DB
 type GeoDex struct {
-	Disk *DiskDB
-	Tile *TDB
+	Disk  *DiskDB
+	Index FortIndex
 }
 
-// NewGeoDex connects to Tile38 DB and sets up diskv ready to supply fort info
+// NewGeoDex opens the DiskDB ready to supply fort info and the fort index.
+// The index is Tile38 if tdbHostname is set, otherwise the forts are loaded from the DiskDB into a MemIndex.
 func NewGeoDex(ddbBasePath, tdbHostname, tdbPassword string) (gd *GeoDex, err error) {
-	d := NewDiskDB(&ddbBasePath)
-	t, err := NewTDB(tdbHostname, tdbPassword)
+	d, err := NewDiskDB(&ddbBasePath)
 	if err != nil {
 		return
 	}
 
+	var index FortIndex
+	if tdbHostname != "" {
+		index, err = NewTDB(tdbHostname, tdbPassword)
+		if err != nil {
+			// the db can only be opened once
+			d.Close()
+			return
+		}
+	} else {
+		var mem *MemIndex
+		mem, err = NewMemIndexFromDisk(d)
+		if err != nil {
+			d.Close()
+			return
+		}
+		log.Infof("loaded %d forts into the in-memory index", mem.Len())
+		index = mem
+	}
+
 	gd = &GeoDex{
-		Disk: d,
-		Tile: t,
+		Disk:  d,
+		Index: index,
 	}
 	return
 }
 
+// Close closes the DiskDB and the index
+func (gd *GeoDex) Close() {
+	gd.Index.Close()
+	gd.Disk.Close()
+}
+
+// UpdateFort merges the fort into the DiskDB like MergeFort and moves it in the index.
+// Nothing is written if the DiskDB already has the same info, changed is false then.
+func (gd *GeoDex) UpdateFort(f *Fort) (changed bool, err error) {
+	if f.GUID == nil {
+		return false, errors.New("cannot update fort with nil GUID")
+	}
+
+	old, err := gd.Disk.GetFort(*f.GUID)
+	if err == nil && reflect.DeepEqual(old, mergedFort(old, f)) {
+		return false, nil
+	}
+
+	if err = gd.Disk.MergeFort(f); err != nil {
+		return
+	}
+	if old == nil || old.Latitude != f.Latitude || old.Longitude != f.Longitude || old.Type != f.Type {
+		err = gd.Index.InsertFort(f)
+	}
+	return true, err
+}
+
 // LookupFortNear get the nearest fort within the radius and resolves its name
 func (gd *GeoDex) LookupFortNear(point pogo.Location, radiusM float64) (f *Fort, err error) {
-	// get nearest fort from tile38
-	f, err = gd.Tile.GetNearestFort(point, radiusM)
+	// get nearest fort from the index
+	f, err = gd.Index.GetNearestFort(point, radiusM)
 	if err != nil {
 		return
 	}
@@ -39,7 +88,7 @@ func (gd *GeoDex) LookupFortNear(point pogo.Location, radiusM float64) (f *Fort,
 		return
 	}
 
-	// get fort name from diskv because tile38 doesn't store the name
+	// get fort name from the DiskDB because the index doesn't store the name
 	diskFort, err := gd.Disk.GetFort(*f.GUID)
 	if err != nil {
 		return f, nil // that's ok, just return the fort without name
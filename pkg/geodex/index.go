@@ -0,0 +1,17 @@
+package geodex
+
+import "github.com/spezifisch/silphtelescope/pkg/pogo"
+
+// FortIndex finds forts by location. It doesn't need to store names, they are looked up in DiskDB.
+// TDB uses a Tile38 server, MemIndex keeps the forts in memory.
+type FortIndex interface {
+	// InsertFort adds the fort or moves it if the GUID exists
+	InsertFort(f *Fort) error
+	// GetNearestFort looks in the given radius (in meters) around the point for the nearest Fort
+	GetNearestFort(point pogo.Location, radiusM float64) (*Fort, error)
+	// Drop deletes all forts
+	Drop() error
+	// Ping checks if the index is usable
+	Ping() error
+	Close()
+}
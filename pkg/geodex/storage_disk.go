@@ -3,32 +3,84 @@ package geodex
 import (
 	"encoding/json"
 	"errors"
+	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
-	"github.com/peterbourgon/diskv"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 )
 
-// DiskDB stores fort info like names
+// file name of the fort db in the GeoDex base path
+const diskDBFile = "forts.db"
+
+var (
+	// GUID -> JSON encoded Fort
+	bucketForts = []byte("forts")
+	// one nested bucket per FortType with the GUIDs as keys
+	bucketTypes = []byte("types")
+)
+
+// DiskDB stores fort info like names in a single bolt file
 type DiskDB struct {
-	forts *diskv.Diskv
+	path string
+	db   *bolt.DB
 }
 
-// NewDiskDB returns a ready-to-use DiskDB object
-func NewDiskDB(basePath *string) (db *DiskDB) {
-	pathFortDex := filepath.Join(*basePath, "fort")
+// NewDiskDB opens the fort db, forts from the old diskv layout are migrated once.
+// Only one process can open it at a time.
+func NewDiskDB(basePath *string) (db *DiskDB, err error) {
+	if err = os.MkdirAll(*basePath, 0755); err != nil {
+		return
+	}
+
+	path := filepath.Join(*basePath, diskDBFile)
+	bdb, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		if err == bolt.ErrTimeout {
+			err = errors.New("fort db " + path + " is in use by another process")
+		}
+		return
+	}
+	err = bdb.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketForts); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketTypes)
+		return err
+	})
+	if err != nil {
+		bdb.Close()
+		return
+	}
 
-	return &DiskDB{
-		forts: diskv.New(diskv.Options{
-			BasePath:     pathFortDex,
-			Transform:    blockTransform,
-			CacheSizeMax: 1 * 1024 * 1024,
-		}),
+	db = &DiskDB{
+		path: path,
+		db:   bdb,
+	}
+	if err = db.migrateDiskv(*basePath); err != nil {
+		db.Close()
+		db = nil
 	}
+	return
 }
 
-// Drop deletes the whole db
-func (db *DiskDB) Drop() error {
-	return db.forts.EraseAll()
+// Close closes the db file
+func (db *DiskDB) Close() error {
+	return db.db.Close()
+}
+
+// Drop closes and deletes the whole db
+func (db *DiskDB) Drop() (err error) {
+	db.db.Close()
+	if err = os.Remove(db.path); err != nil {
+		return
+	}
+	// fails if there are other files like the migrated diskv directory
+	os.Remove(filepath.Dir(db.path))
+	return
 }
 
 // SaveFort saves the fort's info in the db
@@ -37,18 +89,91 @@ func (db *DiskDB) SaveFort(f *Fort) (err error) {
 		return errors.New("cannot save fort with nil GUID")
 	}
 
-	data, err := json.Marshal(f)
-	if err == nil {
-		db.forts.Write(*f.GUID, data)
-	}
-	return
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return putFort(tx, f)
+	})
 }
 
 // GetFort returns the fort's info from the db
 func (db *DiskDB) GetFort(GUID string) (f *Fort, err error) {
-	data, err := db.forts.Read(GUID)
-	if err != nil {
+	defer metrics.ObserveLookup("bolt", time.Now())
+
+	err = db.db.View(func(tx *bolt.Tx) (err error) {
+		f, err = getFort(tx, GUID)
 		return
+	})
+	return
+}
+
+// ForEachFort calls fn for every fort in the db, it stops at the first error.
+// fn must not write to the db.
+func (db *DiskDB) ForEachFort(fn func(f *Fort) error) (err error) {
+	return db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketForts).ForEach(func(_, data []byte) error {
+			f := &Fort{}
+			if err := json.Unmarshal(data, f); err != nil {
+				return err
+			}
+			return fn(f)
+		})
+	})
+}
+
+// ForEachFortOfType calls fn for every fort of the given type, it stops at the first error.
+// fn must not write to the db.
+func (db *DiskDB) ForEachFortOfType(t FortType, fn func(f *Fort) error) (err error) {
+	return db.db.View(func(tx *bolt.Tx) error {
+		types := tx.Bucket(bucketTypes).Bucket(typeKey(t))
+		if types == nil {
+			return nil
+		}
+		return types.ForEach(func(guid, _ []byte) error {
+			f, err := getFort(tx, string(guid))
+			if err != nil {
+				return err
+			}
+			return fn(f)
+		})
+	})
+}
+
+// CountFortsOfType returns how many forts of the given type are in the db
+func (db *DiskDB) CountFortsOfType(t FortType) (n int, err error) {
+	err = db.db.View(func(tx *bolt.Tx) error {
+		if types := tx.Bucket(bucketTypes).Bucket(typeKey(t)); types != nil {
+			n = types.Stats().KeyN
+		}
+		return nil
+	})
+	return
+}
+
+// MergeFort copies new values to an existing fort, or created a fort if it doesn't exist
+func (db *DiskDB) MergeFort(f *Fort) (err error) {
+	return db.MergeForts([]*Fort{f})
+}
+
+// MergeForts merges all forts like MergeFort in one transaction, nothing is saved if one fails
+func (db *DiskDB) MergeForts(forts []*Fort) (err error) {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		for _, f := range forts {
+			if err := mergeFort(tx, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func typeKey(t FortType) []byte {
+	return []byte(strconv.Itoa(int(t)))
+}
+
+// getFort returns an error if the fort doesn't exist
+func getFort(tx *bolt.Tx, guid string) (f *Fort, err error) {
+	data := tx.Bucket(bucketForts).Get([]byte(guid))
+	if data == nil {
+		return nil, errors.New("fort " + guid + " doesn't exist")
 	}
 
 	f = &Fort{}
@@ -56,20 +181,53 @@ func (db *DiskDB) GetFort(GUID string) (f *Fort, err error) {
 	return
 }
 
-// MergeFort copies new values to an existing fort, or created a fort if it doesn't exist
-func (db *DiskDB) MergeFort(f *Fort) (err error) {
+// putFort saves the fort and keeps the type index up to date
+func putFort(tx *bolt.Tx, f *Fort) (err error) {
+	if f.GUID == nil {
+		return errors.New("cannot save fort with nil GUID")
+	}
+	guid := []byte(*f.GUID)
+
+	if old, err := getFort(tx, *f.GUID); err == nil && old.Type != f.Type {
+		if types := tx.Bucket(bucketTypes).Bucket(typeKey(old.Type)); types != nil {
+			if err = types.Delete(guid); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+	if err = tx.Bucket(bucketForts).Put(guid, data); err != nil {
+		return
+	}
+
+	types, err := tx.Bucket(bucketTypes).CreateBucketIfNotExists(typeKey(f.Type))
+	if err != nil {
+		return
+	}
+	return types.Put(guid, []byte{})
+}
+
+func mergeFort(tx *bolt.Tx, f *Fort) (err error) {
 	if f.GUID == nil {
 		return errors.New("cannot save fort with nil GUID")
 	}
 
-	guid := *f.GUID
-	data, err := db.GetFort(guid)
+	data, err := getFort(tx, *f.GUID)
 	if err != nil {
 		// doesn't exist
-		return db.SaveFort(f)
+		return putFort(tx, f)
 	}
+	return putFort(tx, mergedFort(data, f))
+}
 
-	// never update GUID. the GUID is already the file path and name.
+// mergedFort returns a copy of old with the new values from f
+func mergedFort(old, f *Fort) *Fort {
+	data := *old
+	// never update GUID, it's the key
 	data.Latitude = f.Latitude
 	data.Longitude = f.Longitude
 	data.Type = f.Type
@@ -78,25 +236,8 @@ func (db *DiskDB) MergeFort(f *Fort) (err error) {
 		// That's the whole reason for this function.
 		data.Name = f.Name
 	}
-	return db.SaveFort(data)
-}
-
-// limit directory levels
-const maxSliceSize = 3
-
-// blockTransform based on: https://github.com/peterbourgon/diskv/blob/fc0553497cbfcf78f101d0bf8e82c6e627f4bbb0/examples/content-addressable-store/cas.go
-const transformBlockSize = 2 // grouping of chars per directory depth
-
-func blockTransform(s string) []string {
-	sliceSize := len(s) / transformBlockSize
-	if sliceSize > maxSliceSize {
-		sliceSize = maxSliceSize
-	}
-	pathSlice := make([]string, sliceSize)
-
-	for i := 0; i < sliceSize; i++ {
-		from, to := i*transformBlockSize, (i*transformBlockSize)+transformBlockSize
-		pathSlice[i] = s[from:to]
+	if f.URL != nil && *f.URL != "" {
+		data.URL = f.URL
 	}
-	return pathSlice
+	return &data
 }
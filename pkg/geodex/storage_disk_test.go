@@ -1,6 +1,8 @@
 package geodex
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,9 +12,14 @@ func TestDiskDB(t *testing.T) {
 	var err error
 
 	basePath := "test-data-2342"
-	db := NewDiskDB(&basePath)
+	db, err := NewDiskDB(&basePath)
+	assert.NoError(t, err)
 	defer db.Drop()
 
+	// only one user
+	_, err = NewDiskDB(&basePath)
+	assert.Error(t, err)
+
 	_, err = db.GetFort("nonexistent_guid")
 	assert.Error(t, err)
 
@@ -40,3 +47,91 @@ func TestDiskDB(t *testing.T) {
 	assert.Equal(t, goodGUID, *retFort.GUID)
 	assert.Equal(t, goodName, *retFort.Name)
 }
+
+func TestDiskDBMerge(t *testing.T) {
+	basePath := "test-data-2344"
+	db, err := NewDiskDB(&basePath)
+	assert.NoError(t, err)
+	defer db.Drop()
+
+	err = db.MergeForts([]*Fort{
+		newTestFort("a.16", 52.5, 13.4, ""),
+		newTestFort("b.16", 52.6, 13.5, "Gym B"),
+		{Type: FortTypeStop},
+	})
+	// nothing is saved if one fort is invalid
+	assert.Error(t, err)
+	_, err = db.GetFort("a.16")
+	assert.Error(t, err)
+
+	stop := newTestFort("c.12", 52.7, 13.6, "Stop C")
+	stop.Type = FortTypeStop
+	err = db.MergeForts([]*Fort{
+		newTestFort("a.16", 52.5, 13.4, ""),
+		newTestFort("b.16", 52.6, 13.5, "Gym B"),
+		stop,
+	})
+	assert.NoError(t, err)
+
+	// the name is only set once
+	assert.NoError(t, db.MergeFort(newTestFort("a.16", 52.51, 13.41, "Gym A")))
+	assert.NoError(t, db.MergeFort(newTestFort("b.16", 52.6, 13.5, "other name")))
+	f, err := db.GetFort("a.16")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gym A", *f.Name)
+	assert.Equal(t, 52.51, f.Latitude)
+	f, err = db.GetFort("b.16")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gym B", *f.Name)
+
+	count := func(ft FortType) (guids []string) {
+		assert.NoError(t, db.ForEachFortOfType(ft, func(f *Fort) error {
+			guids = append(guids, *f.GUID)
+			return nil
+		}))
+		n, err := db.CountFortsOfType(ft)
+		assert.NoError(t, err)
+		assert.Equal(t, len(guids), n)
+		return
+	}
+	assert.Equal(t, []string{"a.16", "b.16"}, count(FortTypeGym))
+	assert.Equal(t, []string{"c.12"}, count(FortTypeStop))
+	assert.Empty(t, count(FortTypePortal))
+
+	// a stop turned into a gym
+	stop.Type = FortTypeGym
+	assert.NoError(t, db.MergeFort(stop))
+	assert.Equal(t, []string{"a.16", "b.16", "c.12"}, count(FortTypeGym))
+	assert.Empty(t, count(FortTypeStop))
+}
+
+func TestDiskDBMigrateDiskv(t *testing.T) {
+	basePath := "test-data-2345"
+	defer os.RemoveAll(basePath)
+
+	old := newDiskv(filepath.Join(basePath, diskvFortDir))
+	assert.NoError(t, old.Write("a.16", []byte(`{"guid":"a.16","latitude":52.5,"longitude":13.4,"name":"Gym A","type":1}`)))
+	assert.NoError(t, old.Write("c.12", []byte(`{"guid":"c.12","latitude":52.7,"longitude":13.6,"type":2}`)))
+	assert.NoError(t, old.Write("broken", []byte(`{`)))
+
+	db, err := NewDiskDB(&basePath)
+	assert.NoError(t, err)
+
+	f, err := db.GetFort("a.16")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gym A", *f.Name)
+	assert.Equal(t, FortTypeGym, f.Type)
+	n, err := db.CountFortsOfType(FortTypeStop)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = db.GetFort("broken")
+	assert.Error(t, err)
+
+	// only once
+	assert.NoDirExists(t, filepath.Join(basePath, diskvFortDir))
+	assert.DirExists(t, filepath.Join(basePath, diskvFortDir+diskvMigratedSuffix))
+	assert.NoError(t, db.Close())
+	db, err = NewDiskDB(&basePath)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+}
@@ -0,0 +1,83 @@
+package geodex
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/peterbourgon/diskv"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// the old layout had one file per fort below this directory
+const diskvFortDir = "fort"
+
+// suffix for the old directory after migrating it
+const diskvMigratedSuffix = ".migrated"
+
+// migrateDiskv imports the forts from the old diskv layout in one transaction and renames its directory,
+// so that it only happens once
+func (db *DiskDB) migrateDiskv(basePath string) (err error) {
+	dir := filepath.Join(basePath, diskvFortDir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	log.Infof("migrating forts from %s to %s", dir, db.path)
+	old := newDiskv(dir)
+	count := 0
+	cancel := make(chan struct{})
+	defer close(cancel)
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		for guid := range old.Keys(cancel) {
+			data, err := old.Read(guid)
+			if err != nil {
+				return err
+			}
+			f := &Fort{}
+			if err = json.Unmarshal(data, f); err != nil {
+				log.WithError(err).Warnf("skipping invalid fort %s", guid)
+				continue
+			}
+			if err = mergeFort(tx, f); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	log.Infof("migrated %d forts, you can delete %s", count, dir+diskvMigratedSuffix)
+	return os.Rename(dir, dir+diskvMigratedSuffix)
+}
+
+func newDiskv(dir string) *diskv.Diskv {
+	return diskv.New(diskv.Options{
+		BasePath:  dir,
+		Transform: blockTransform,
+	})
+}
+
+// limit directory levels
+const maxSliceSize = 3
+
+// blockTransform based on: https://github.com/peterbourgon/diskv/blob/fc0553497cbfcf78f101d0bf8e82c6e627f4bbb0/examples/content-addressable-store/cas.go
+const transformBlockSize = 2 // grouping of chars per directory depth
+
+func blockTransform(s string) []string {
+	sliceSize := len(s) / transformBlockSize
+	if sliceSize > maxSliceSize {
+		sliceSize = maxSliceSize
+	}
+	pathSlice := make([]string, sliceSize)
+
+	for i := 0; i < sliceSize; i++ {
+		from, to := i*transformBlockSize, (i*transformBlockSize)+transformBlockSize
+		pathSlice[i] = s[from:to]
+	}
+	return pathSlice
+}
@@ -0,0 +1,168 @@
+package geodex
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// size of a grid cell in degrees, about 1.1 km north-south
+const memIndexCellSize = 0.01
+
+// meters per degree of latitude, close enough for search bounds
+const metersPerDegree = 111320.0
+
+type memCell struct {
+	lat, lon int
+}
+
+// MemIndex is an in-process FortIndex on a grid of lat/lon cells, it makes Tile38 optional
+type MemIndex struct {
+	mu    sync.RWMutex
+	cells map[memCell]map[string]*Fort // cell -> GUID -> Fort
+	forts map[string]*Fort             // GUID -> Fort
+}
+
+// NewMemIndex returns an empty index
+func NewMemIndex() *MemIndex {
+	return &MemIndex{
+		cells: make(map[memCell]map[string]*Fort),
+		forts: make(map[string]*Fort),
+	}
+}
+
+// NewMemIndexFromDisk returns an index with all forts from the DiskDB
+func NewMemIndexFromDisk(db *DiskDB) (idx *MemIndex, err error) {
+	idx = NewMemIndex()
+	err = db.ForEachFort(func(f *Fort) error {
+		return idx.InsertFort(f)
+	})
+	return
+}
+
+func memCellOf(lat, lon float64) memCell {
+	return memCell{
+		lat: int(math.Floor(lat / memIndexCellSize)),
+		lon: int(math.Floor(lon / memIndexCellSize)),
+	}
+}
+
+// Len returns the number of forts
+func (idx *MemIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.forts)
+}
+
+// Close does nothing, it's part of FortIndex
+func (idx *MemIndex) Close() {}
+
+// Ping always works, it's part of FortIndex
+func (idx *MemIndex) Ping() error {
+	return nil
+}
+
+// Drop deletes all forts
+func (idx *MemIndex) Drop() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.cells = make(map[memCell]map[string]*Fort)
+	idx.forts = make(map[string]*Fort)
+	return nil
+}
+
+// InsertFort adds the fort or moves it if the GUID exists
+func (idx *MemIndex) InsertFort(f *Fort) error {
+	if f.GUID == nil {
+		return errors.New("cannot insert fort with nil GUID")
+	}
+
+	// like Tile38 we only need the position
+	guid := *f.GUID
+	fort := &Fort{
+		GUID:      &guid,
+		Latitude:  f.Latitude,
+		Longitude: f.Longitude,
+		Type:      f.Type,
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if old, ok := idx.forts[guid]; ok {
+		oldCell := memCellOf(old.Latitude, old.Longitude)
+		delete(idx.cells[oldCell], guid)
+		if len(idx.cells[oldCell]) == 0 {
+			delete(idx.cells, oldCell)
+		}
+	}
+
+	cell := memCellOf(fort.Latitude, fort.Longitude)
+	if idx.cells[cell] == nil {
+		idx.cells[cell] = make(map[string]*Fort)
+	}
+	idx.cells[cell][guid] = fort
+	idx.forts[guid] = fort
+	return nil
+}
+
+// GetNearestFort looks in the given radius (in meters) around the point for the nearest Fort
+func (idx *MemIndex) GetNearestFort(point pogo.Location, radiusM float64) (f *Fort, err error) {
+	defer metrics.ObserveLookup("memory", time.Now())
+
+	// cells covering the bounding box of the search circle
+	dLat := radiusM / metersPerDegree
+	dLon := 180.0
+	if cosLat := math.Cos(point.Latitude * math.Pi / 180.0); cosLat > 0.0001 {
+		dLon = math.Min(dLon, dLat/cosLat)
+	}
+	minCell := memCellOf(point.Latitude-dLat, point.Longitude-dLon)
+	maxCell := memCellOf(point.Latitude+dLat, point.Longitude+dLon)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	bestDistance := radiusM
+	check := func(forts map[string]*Fort) {
+		for _, fort := range forts {
+			distance := point.DistanceTo(fort.Location())
+			if distance <= bestDistance {
+				bestDistance = distance
+				f = fort
+			}
+		}
+	}
+
+	cellCount := float64(maxCell.lat-minCell.lat+1) * float64(maxCell.lon-minCell.lon+1)
+	if cellCount > float64(len(idx.cells)) {
+		// huge radius, cheaper to look at the cells that aren't empty
+		for cell, forts := range idx.cells {
+			if cell.lat >= minCell.lat && cell.lat <= maxCell.lat && cell.lon >= minCell.lon && cell.lon <= maxCell.lon {
+				check(forts)
+			}
+		}
+	} else {
+		for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+			for lon := minCell.lon; lon <= maxCell.lon; lon++ {
+				check(idx.cells[memCell{lat, lon}])
+			}
+		}
+	}
+	if f == nil {
+		err = errors.New("no fort found")
+		return
+	}
+
+	// don't hand out the indexed fort
+	fortCopy := *f
+	guid := *f.GUID
+	fortCopy.GUID = &guid
+	f = &fortCopy
+	return
+}
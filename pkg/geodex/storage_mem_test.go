@@ -0,0 +1,144 @@
+package geodex
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+func newTestFort(guid string, lat, lon float64, name string) *Fort {
+	f := &Fort{
+		GUID:      &guid,
+		Latitude:  lat,
+		Longitude: lon,
+		Type:      FortTypeGym,
+	}
+	if name != "" {
+		f.Name = &name
+	}
+	return f
+}
+
+func TestMemIndex(t *testing.T) {
+	idx := NewMemIndex()
+	assert.NoError(t, idx.Ping())
+
+	_, err := idx.GetNearestFort(pogo.Location{Latitude: 52.5, Longitude: 13.4}, 1000)
+	assert.Error(t, err)
+
+	assert.Error(t, idx.InsertFort(&Fort{}))
+
+	assert.NoError(t, idx.InsertFort(newTestFort("a.16", 52.503355, 13.435746, "Good Gym")))
+	assert.NoError(t, idx.InsertFort(newTestFort("b.16", 52.504, 13.436, "")))
+	// other cell
+	assert.NoError(t, idx.InsertFort(newTestFort("c.16", 52.52, 13.41, "")))
+	assert.Equal(t, 3, idx.Len())
+
+	f, err := idx.GetNearestFort(pogo.Location{Latitude: 52.5034, Longitude: 13.4358}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.16", *f.GUID)
+	assert.Equal(t, FortTypeGym, f.Type)
+	// like tile38 only the position is indexed
+	assert.Nil(t, f.Name)
+
+	// changing the result doesn't change the index
+	*f.GUID = "changed"
+	f, err = idx.GetNearestFort(pogo.Location{Latitude: 52.5034, Longitude: 13.4358}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.16", *f.GUID)
+
+	// out of radius
+	_, err = idx.GetNearestFort(pogo.Location{Latitude: 52.51, Longitude: 13.42}, 100)
+	assert.Error(t, err)
+
+	// across cell borders
+	f, err = idx.GetNearestFort(pogo.Location{Latitude: 52.5199, Longitude: 13.4099}, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.16", *f.GUID)
+
+	// huge radius
+	f, err = idx.GetNearestFort(pogo.Location{Latitude: 48.137, Longitude: 11.575}, 1000000)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.16", *f.GUID)
+
+	// move a fort
+	assert.NoError(t, idx.InsertFort(newTestFort("a.16", 52.52, 13.411, "")))
+	assert.Equal(t, 3, idx.Len())
+	f, err = idx.GetNearestFort(pogo.Location{Latitude: 52.5034, Longitude: 13.4358}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.16", *f.GUID)
+	f, err = idx.GetNearestFort(pogo.Location{Latitude: 52.52, Longitude: 13.4112}, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.16", *f.GUID)
+
+	assert.NoError(t, idx.Drop())
+	assert.Equal(t, 0, idx.Len())
+	_, err = idx.GetNearestFort(pogo.Location{Latitude: 52.52, Longitude: 13.4112}, 1000)
+	assert.Error(t, err)
+}
+
+func TestGeoDexMemIndex(t *testing.T) {
+	basePath := "test-data-2343"
+	db, err := NewDiskDB(&basePath)
+	assert.NoError(t, err)
+	defer os.RemoveAll(basePath)
+
+	assert.NoError(t, db.SaveFort(newTestFort("a.16", 52.503355, 13.435746, "Good Gym")))
+	assert.NoError(t, db.SaveFort(newTestFort("c.16", 52.52, 13.41, "")))
+
+	count := 0
+	assert.NoError(t, db.ForEachFort(func(f *Fort) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+	db.Close()
+
+	gd, err := NewGeoDex(basePath, "", "")
+	assert.NoError(t, err)
+	defer gd.Close()
+	assert.Equal(t, 2, gd.Index.(*MemIndex).Len())
+
+	f, err := gd.LookupFortNear(pogo.Location{Latitude: 52.5034, Longitude: 13.4358}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.16", *f.GUID)
+	assert.Equal(t, "Good Gym", f.GetName())
+
+	f, err = gd.LookupFortNear(pogo.Location{Latitude: 52.52, Longitude: 13.41}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.16", *f.GUID)
+	assert.Nil(t, f.Name)
+
+	_, err = gd.LookupFortNear(pogo.Location{Latitude: 52.51, Longitude: 13.42}, 100)
+	assert.Error(t, err)
+
+	// new fort
+	changed, err := gd.UpdateFort(newTestFort("d.16", 52.51, 13.42, ""))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	f, err = gd.LookupFortNear(pogo.Location{Latitude: 52.51, Longitude: 13.42}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "d.16", *f.GUID)
+
+	// nothing new
+	changed, err = gd.UpdateFort(newTestFort("a.16", 52.503355, 13.435746, ""))
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	changed, err = gd.UpdateFort(newTestFort("a.16", 52.503355, 13.435746, "Other Name"))
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// name and position
+	changed, err = gd.UpdateFort(newTestFort("c.16", 52.53, 13.41, "Gym C"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	f, err = gd.LookupFortNear(pogo.Location{Latitude: 52.53, Longitude: 13.41}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "Gym C", f.GetName())
+
+	_, err = gd.UpdateFort(&Fort{})
+	assert.Error(t, err)
+}
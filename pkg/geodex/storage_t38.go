@@ -2,14 +2,16 @@ package geodex
 
 import (
 	"errors"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 
 	t38c "github.com/axvq/tile38-client"
 )
 
-// TDB is the silphtelescope geodex connection
+// TDB is the FortIndex on a Tile38 server
 type TDB struct {
 	db *t38c.Client
 }
@@ -34,6 +36,14 @@ func (tdb *TDB) Close() {
 	}
 }
 
+// Ping checks if Tile38 answers
+func (tdb *TDB) Ping() error {
+	if tdb.db == nil {
+		return errors.New("not connected")
+	}
+	return tdb.db.Ping()
+}
+
 // Drop deletes the whole fort database
 func (tdb *TDB) Drop() (err error) {
 	err = tdb.db.Keys.Drop("fort")
@@ -82,6 +92,8 @@ func (t FortType) ToString() (s string) {
 
 // GetNearestFort looks in the given radius (in meters) around the point for the nearest Fort
 func (tdb *TDB) GetNearestFort(point pogo.Location, radiusM float64) (f *Fort, err error) {
+	defer metrics.ObserveLookup("tile38", time.Now())
+
 	response, err := tdb.db.Search.Nearby("fort",
 		float64(point.Latitude), float64(point.Longitude), radiusM).
 		Format(t38c.FormatPoints).
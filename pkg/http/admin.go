@@ -0,0 +1,266 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+// RoomConfigEditor manages RoomConfigs, implemented by roomservice.Poster
+type RoomConfigEditor interface {
+	GetRoomIDs() []string
+	GetRoomConfig(roomID string) (*roomservice.RoomConfig, bool)
+	UpdateRoomConfig(rc *roomservice.RoomConfig) (created bool)
+	ChangeRoomConfig(roomID string, change *roomservice.RoomConfigChange, newValues *roomservice.RoomConfig) error
+	DeleteRoomConfig(roomID string) (deleted bool)
+}
+
+// RoomSettings is the body of PATCH /api/v1/rooms/<room>, only given settings are changed
+type RoomSettings struct {
+	AcceptCommands *bool
+	FormatText     *bool
+	WeatherAlerts  *bool
+}
+
+// FilterResponse is a spawn or raid filter with its index
+type FilterResponse struct {
+	Index  int
+	Filter roomservice.PokemonFilter
+}
+
+type apiError struct {
+	Error string
+}
+
+type adminAPI struct {
+	rooms RoomConfigEditor
+	token string
+}
+
+// AddAdminAPI serves the REST API for room configs at /api/v1, requests need "Authorization: Bearer <token>".
+// Call Init() first.
+func AddAdminAPI(rooms RoomConfigEditor, token string) {
+	api := &adminAPI{
+		rooms: rooms,
+		token: token,
+	}
+
+	g := e.Group("/api/v1", api.auth)
+	g.GET("/rooms", api.listRooms)
+	g.POST("/rooms", api.createRoom)
+	g.GET("/rooms/:room", api.getRoom)
+	g.PATCH("/rooms/:room", api.changeRoom)
+	g.DELETE("/rooms/:room", api.deleteRoom)
+	g.GET("/rooms/:room/filters", api.listFilters)
+	g.POST("/rooms/:room/filters", api.appendFilter)
+	g.GET("/rooms/:room/filters/:index", api.getFilter)
+	g.PUT("/rooms/:room/filters/:index", api.replaceFilter)
+	g.DELETE("/rooms/:room/filters/:index", api.removeFilter)
+}
+
+func (api *adminAPI) auth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if api.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(api.token)) != 1 {
+			log.Warnf("rejected admin API request from %s", c.Request().RemoteAddr)
+			return c.JSON(http.StatusUnauthorized, apiError{"invalid token"})
+		}
+		return next(c)
+	}
+}
+
+func fail(c echo.Context, code int, text string) error {
+	return c.JSON(code, apiError{text})
+}
+
+// getRoomParam returns the room ID, clients may escape the "!"
+func getRoomParam(c echo.Context) string {
+	roomID, err := url.PathUnescape(c.Param("room"))
+	if err != nil {
+		return c.Param("room")
+	}
+	return roomID
+}
+
+// getRoomAndFilter returns the room's config and the index of the filter in the path if it exists
+func (api *adminAPI) getRoomAndFilter(c echo.Context) (rc *roomservice.RoomConfig, index int, err error) {
+	rc, ok := api.rooms.GetRoomConfig(getRoomParam(c))
+	if !ok {
+		return nil, 0, fail(c, http.StatusNotFound, "room not found")
+	}
+	index, err2 := strconv.Atoi(c.Param("index"))
+	if err2 != nil || index < 0 || index >= len(rc.Filter) {
+		return nil, 0, fail(c, http.StatusNotFound, "filter not found")
+	}
+	return
+}
+
+func (api *adminAPI) listRooms(c echo.Context) error {
+	return c.JSON(http.StatusOK, api.rooms.GetRoomIDs())
+}
+
+func (api *adminAPI) createRoom(c echo.Context) error {
+	// rooms created from chat accept commands too, the body may still turn it off
+	rc := &roomservice.RoomConfig{AcceptCommands: true}
+	if err := c.Bind(rc); err != nil {
+		return fail(c, http.StatusBadRequest, "invalid RoomConfig")
+	}
+	if rc.RoomID == "" {
+		return fail(c, http.StatusBadRequest, "RoomID missing")
+	}
+	for _, f := range rc.Filter {
+		if err := f.Validate(); err != nil {
+			return fail(c, http.StatusBadRequest, err.Error())
+		}
+	}
+	if _, ok := api.rooms.GetRoomConfig(rc.RoomID); ok {
+		return fail(c, http.StatusConflict, "room exists")
+	}
+
+	// new configs are always in the current format
+	rc.Version = 0
+	api.rooms.UpdateRoomConfig(rc)
+	log.Infof("admin API: created RoomConfig for %s", rc.RoomID)
+
+	created, _ := api.rooms.GetRoomConfig(rc.RoomID)
+	return c.JSON(http.StatusCreated, created)
+}
+
+func (api *adminAPI) getRoom(c echo.Context) error {
+	rc, ok := api.rooms.GetRoomConfig(getRoomParam(c))
+	if !ok {
+		return fail(c, http.StatusNotFound, "room not found")
+	}
+	return c.JSON(http.StatusOK, rc)
+}
+
+func (api *adminAPI) changeRoom(c echo.Context) error {
+	roomID := getRoomParam(c)
+	settings := &RoomSettings{}
+	if err := c.Bind(settings); err != nil {
+		return fail(c, http.StatusBadRequest, "invalid RoomSettings")
+	}
+
+	change := &roomservice.RoomConfigChange{}
+	newValues := &roomservice.RoomConfig{}
+	if settings.AcceptCommands != nil {
+		change.ChangeAcceptCommands = true
+		newValues.AcceptCommands = *settings.AcceptCommands
+	}
+	if settings.FormatText != nil {
+		change.ChangeFormatText = true
+		newValues.FormatText = *settings.FormatText
+	}
+	if settings.WeatherAlerts != nil {
+		change.ChangeWeatherAlerts = true
+		newValues.WeatherAlerts = *settings.WeatherAlerts
+	}
+	if err := api.rooms.ChangeRoomConfig(roomID, change, newValues); err != nil {
+		return fail(c, http.StatusNotFound, err.Error())
+	}
+
+	rc, _ := api.rooms.GetRoomConfig(roomID)
+	return c.JSON(http.StatusOK, rc)
+}
+
+func (api *adminAPI) deleteRoom(c echo.Context) error {
+	roomID := getRoomParam(c)
+	if !api.rooms.DeleteRoomConfig(roomID) {
+		return fail(c, http.StatusNotFound, "room not found")
+	}
+	log.Infof("admin API: deleted RoomConfig of %s", roomID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *adminAPI) listFilters(c echo.Context) error {
+	rc, ok := api.rooms.GetRoomConfig(getRoomParam(c))
+	if !ok {
+		return fail(c, http.StatusNotFound, "room not found")
+	}
+
+	filters := []FilterResponse{}
+	for i, f := range rc.Filter {
+		filters = append(filters, FilterResponse{i, f})
+	}
+	return c.JSON(http.StatusOK, filters)
+}
+
+func (api *adminAPI) appendFilter(c echo.Context) error {
+	roomID := getRoomParam(c)
+	f := roomservice.PokemonFilter{}
+	if err := c.Bind(&f); err != nil {
+		return fail(c, http.StatusBadRequest, "invalid PokemonFilter")
+	}
+	if err := f.Validate(); err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	if _, ok := api.rooms.GetRoomConfig(roomID); !ok {
+		return fail(c, http.StatusNotFound, "room not found")
+	}
+	change := &roomservice.RoomConfigChange{
+		Operation: roomservice.RoomConfigOperationAppendFilter,
+	}
+	if err := api.rooms.ChangeRoomConfig(roomID, change, &roomservice.RoomConfig{Filter: []roomservice.PokemonFilter{f}}); err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	rc, _ := api.rooms.GetRoomConfig(roomID)
+	return c.JSON(http.StatusCreated, FilterResponse{len(rc.Filter) - 1, f})
+}
+
+func (api *adminAPI) getFilter(c echo.Context) error {
+	rc, index, err := api.getRoomAndFilter(c)
+	if rc == nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, FilterResponse{index, rc.Filter[index]})
+}
+
+func (api *adminAPI) replaceFilter(c echo.Context) error {
+	rc, index, err := api.getRoomAndFilter(c)
+	if rc == nil {
+		return err
+	}
+	f := roomservice.PokemonFilter{}
+	if err := c.Bind(&f); err != nil {
+		return fail(c, http.StatusBadRequest, "invalid PokemonFilter")
+	}
+	if err := f.Validate(); err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	change := &roomservice.RoomConfigChange{
+		Operation:    roomservice.RoomConfigOperationUpdateFilter,
+		FilterIndex:  index,
+		FilterChange: roomservice.FilterChangeReplace,
+	}
+	if err := api.rooms.ChangeRoomConfig(rc.RoomID, change, &roomservice.RoomConfig{Filter: []roomservice.PokemonFilter{f}}); err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusOK, FilterResponse{index, f})
+}
+
+func (api *adminAPI) removeFilter(c echo.Context) error {
+	rc, index, err := api.getRoomAndFilter(c)
+	if rc == nil {
+		return err
+	}
+
+	change := &roomservice.RoomConfigChange{
+		Operation:   roomservice.RoomConfigOperationRemoveFilter,
+		FilterIndex: index,
+		FilterList:  roomservice.FilterListPokemon,
+	}
+	if err := api.rooms.ChangeRoomConfig(rc.RoomID, change, nil); err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
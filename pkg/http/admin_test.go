@@ -0,0 +1,178 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+const testAdminToken = "s3cret"
+
+func serveAdminAPI(p *roomservice.Poster, method, path, body string, token string) *httptest.ResponseRecorder {
+	Init()
+	AddAdminAPI(p, testAdminToken)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminAPIAuth(t *testing.T) {
+	p := roomservice.NewPoster(nil, nil)
+
+	rec := serveAdminAPI(p, http.MethodGet, "/api/v1/rooms", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serveAdminAPI(p, http.MethodGet, "/api/v1/rooms", "", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serveAdminAPI(p, http.MethodGet, "/api/v1/rooms", "", testAdminToken)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestAdminAPIRooms(t *testing.T) {
+	p := roomservice.NewPoster(nil, nil)
+	api := func(method, path, body string) *httptest.ResponseRecorder {
+		return serveAdminAPI(p, method, path, body, testAdminToken)
+	}
+
+	rec := api(http.MethodPost, "/api/v1/rooms", `{"RoomID": "!foo:example.com", "FormatText": true}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rc, ok := p.GetRoomConfig("!foo:example.com")
+	if assert.True(t, ok) {
+		assert.True(t, rc.FormatText)
+		// like rooms created from chat
+		assert.True(t, rc.AcceptCommands)
+		assert.NotZero(t, rc.Version)
+	}
+	rec = api(http.MethodPost, "/api/v1/rooms", `{"RoomID": "!notify:example.com", "AcceptCommands": false}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rc, _ = p.GetRoomConfig("!notify:example.com")
+	assert.False(t, rc.AcceptCommands)
+	api(http.MethodDelete, "/api/v1/rooms/!notify:example.com", "")
+
+	rec = api(http.MethodPost, "/api/v1/rooms", `{"RoomID": "!foo:example.com"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = api(http.MethodPost, "/api/v1/rooms", `{"FormatText": true}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api(http.MethodPost, "/api/v1/rooms", `foo`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api(http.MethodPost, "/api/v1/rooms", `{"RoomID": "!baz:example.com", "Filter": [{"Area": {"RadiusM": -1}}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	api(http.MethodPost, "/api/v1/rooms", `{"RoomID": "!bar:example.com"}`)
+
+	rec = api(http.MethodGet, "/api/v1/rooms", "")
+	assert.Equal(t, `["!bar:example.com","!foo:example.com"]`+"\n", rec.Body.String())
+
+	// escaped room ID
+	rec = api(http.MethodGet, "/api/v1/rooms/%21foo:example.com", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rc = &roomservice.RoomConfig{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), rc))
+	assert.Equal(t, "!foo:example.com", rc.RoomID)
+	rec = api(http.MethodGet, "/api/v1/rooms/!baz:example.com", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// only the given settings change
+	rec = api(http.MethodPatch, "/api/v1/rooms/!foo:example.com", `{"AcceptCommands": true, "WeatherAlerts": true}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rc, _ = p.GetRoomConfig("!foo:example.com")
+	assert.True(t, rc.AcceptCommands)
+	assert.True(t, rc.FormatText)
+	assert.True(t, rc.WeatherAlerts)
+	rec = api(http.MethodPatch, "/api/v1/rooms/!baz:example.com", `{"AcceptCommands": true}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = api(http.MethodDelete, "/api/v1/rooms/!bar:example.com", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = api(http.MethodDelete, "/api/v1/rooms/!bar:example.com", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, []string{"!foo:example.com"}, p.GetRoomIDs())
+}
+
+func TestAdminAPIFilters(t *testing.T) {
+	p := roomservice.NewPoster(nil, nil)
+	p.UpdateRoomConfig(&roomservice.RoomConfig{RoomID: "!foo:example.com"})
+	api := func(method, path, body string) *httptest.ResponseRecorder {
+		return serveAdminAPI(p, method, path, body, testAdminToken)
+	}
+	filters := "/api/v1/rooms/!foo:example.com/filters"
+
+	rec := api(http.MethodGet, filters, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String())
+
+	spawnFilter := `{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "ListWanted": true, "PokemonIDs": [147, 148]}`
+	rec = api(http.MethodPost, filters, spawnFilter)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = api(http.MethodPost, filters, `{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 500}, "ListRaids": true, "ListWanted": true, "RaidLevels": [5]}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	response := FilterResponse{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Index)
+	assert.True(t, response.Filter.ListRaids)
+
+	rec = api(http.MethodPost, "/api/v1/rooms/!bar:example.com/filters", spawnFilter)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = api(http.MethodPost, filters, `[]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api(http.MethodGet, filters+"/0", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []int{147, 148}, response.Filter.PokemonIDs)
+	assert.Equal(t, 1000.0, response.Filter.Area.RadiusM)
+	for _, index := range []string{"2", "-1", "foo"} {
+		rec = api(http.MethodGet, filters+"/"+index, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code, index)
+	}
+
+	// checked like the filter commands do
+	for _, invalid := range []string{
+		`{"Area": {"Latitude": 152.5, "Longitude": 13.4, "RadiusM": 1000}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": -1}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "RaidLevels": [5]}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "ListRaids": true, "RaidLevels": [7]}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "ListRaids": true, "Encounter": {}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "Encounter": {"IV": {"Min": 90, "Max": 110}}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "Encounter": {"MinAttack": 16}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "Encounter": {"CP": {"Min": 3000, "Max": 10}}}`,
+		`{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "Encounter": {"Level": {"Min": -1}}}`,
+	} {
+		rec = api(http.MethodPost, filters, invalid)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, invalid)
+		rec = api(http.MethodPut, filters+"/0", invalid)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, invalid)
+	}
+	rc, _ := p.GetRoomConfig("!foo:example.com")
+	assert.Equal(t, 2, len(rc.Filter))
+	assert.Equal(t, []int{147, 148}, rc.Filter[0].PokemonIDs)
+
+	rec = api(http.MethodPut, filters+"/0", `{"Area": {"Latitude": 52.5, "Longitude": 13.4, "RadiusM": 1000}, "ListWanted": false, "PokemonIDs": [16]}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rc, _ = p.GetRoomConfig("!foo:example.com")
+	assert.Equal(t, []int{16}, rc.Filter[0].PokemonIDs)
+	assert.False(t, rc.Filter[0].ListWanted)
+	rec = api(http.MethodPut, filters+"/2", spawnFilter)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = api(http.MethodDelete, filters+"/0", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rc, _ = p.GetRoomConfig("!foo:example.com")
+	if assert.Equal(t, 1, len(rc.Filter)) {
+		assert.True(t, rc.Filter[0].ListRaids)
+	}
+	rec = api(http.MethodDelete, filters+"/1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
+)
+
+const (
+	// DefaultTokenHeader carries the shared secret if it's not in the path
+	DefaultTokenHeader = "X-Webhook-Token"
+	// DefaultSignatureHeader carries the hex HMAC-SHA256 of the request body
+	DefaultSignatureHeader = "X-Webhook-Signature"
+)
+
+// reasons for rejected webhook requests
+const (
+	RejectReasonAddress   = "address"
+	RejectReasonToken     = "token"
+	RejectReasonSignature = "signature"
+)
+
+// WebhookAuth configures how webhook requests are authenticated. Checks with empty settings are skipped.
+type WebhookAuth struct {
+	// shared secret, either as last path element (/webhook/mad/<token>) or in TokenHeader
+	Token       string
+	TokenHeader string
+	// secret for the HMAC-SHA256 signature of the request body in SignatureHeader
+	HMACSecret      string
+	SignatureHeader string
+	// networks MAD hosts may connect from, checked against the TCP peer address
+	AllowedNets []*net.IPNet
+}
+
+// Auth is checked for every webhook request
+var Auth WebhookAuth
+
+// ParseAllowedNets parses CIDRs like "172.16.0.0/12". Single IPs are allowed too.
+func ParseAllowedNets(cidrs []string) (nets []*net.IPNet, err error) {
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", cidr)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+
+		_, ipNet, err2 := net.ParseCIDR(cidr)
+		if err2 != nil {
+			return nil, err2
+		}
+		nets = append(nets, ipNet)
+	}
+	return
+}
+
+func reject(c echo.Context, reason string) error {
+	metrics.WebhookRejections.WithLabelValues(reason).Inc()
+
+	log.Warnf("rejected webhook request from %s: bad %s", c.Request().RemoteAddr, reason)
+	return c.String(http.StatusUnauthorized, "FAIL\n")
+}
+
+// webhookAuth is the middleware checking Auth
+func webhookAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if len(Auth.AllowedNets) > 0 && !Auth.allowsAddress(c.Request().RemoteAddr) {
+			return reject(c, RejectReasonAddress)
+		}
+
+		if Auth.Token != "" && !Auth.hasToken(c) {
+			return reject(c, RejectReasonToken)
+		}
+
+		if Auth.HMACSecret != "" {
+			body, err := ioutil.ReadAll(c.Request().Body)
+			if err != nil || !Auth.hasSignature(c.Request().Header, body) {
+				return reject(c, RejectReasonSignature)
+			}
+			// let the handler read it again
+			c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		return next(c)
+	}
+}
+
+func (a *WebhookAuth) allowsAddress(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range a.AllowedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *WebhookAuth) hasToken(c echo.Context) bool {
+	header := a.TokenHeader
+	if header == "" {
+		header = DefaultTokenHeader
+	}
+
+	for _, token := range []string{c.Param("token"), c.Request().Header.Get(header)} {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *WebhookAuth) hasSignature(h http.Header, body []byte) bool {
+	header := a.SignatureHeader
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+
+	// GitHub style "sha256=<hex>" is fine too
+	signature, err := hex.DecodeString(strings.TrimPrefix(h.Get(header), "sha256="))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(a.HMACSecret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
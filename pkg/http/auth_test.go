@@ -0,0 +1,162 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWebhook(sink *ingest.Sink, path, data string, modify func(req *http.Request)) *httptest.ResponseRecorder {
+	Init()
+	AddWebhook(startTestSource(NewMADSource(), sink))
+	AddWebhook(startTestSource(NewRDMSource(), sink))
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if modify != nil {
+		modify(req)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func sign(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseAllowedNets(t *testing.T) {
+	nets, err := ParseAllowedNets([]string{"172.16.0.0/12", "192.0.2.1", "2001:db8::/32", "::1"})
+	if assert.Nil(t, err) && assert.Equal(t, 4, len(nets)) {
+		assert.Equal(t, "172.16.0.0/12", nets[0].String())
+		assert.Equal(t, "192.0.2.1/32", nets[1].String())
+		assert.Equal(t, "2001:db8::/32", nets[2].String())
+		assert.Equal(t, "::1/128", nets[3].String())
+	}
+
+	for _, invalid := range []string{"foo", "10.0.0.0/33", "10.0.0.256"} {
+		_, err = ParseAllowedNets([]string{invalid})
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestWebhookAuthDisabled(t *testing.T) {
+	Auth = WebhookAuth{}
+	sink := newTestSink()
+
+	rec := serveWebhook(sink, "/webhook/mad", `[]`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, responseOK, rec.Body.String())
+}
+
+func rejections(reason string) float64 {
+	return testutil.ToFloat64(metrics.WebhookRejections.WithLabelValues(reason))
+}
+
+func TestWebhookAuthToken(t *testing.T) {
+	Auth = WebhookAuth{Token: "s3cret"}
+	sink := newTestSink()
+	defer func() { Auth = WebhookAuth{} }()
+	before := rejections(RejectReasonToken)
+
+	rec := serveWebhook(sink, "/webhook/mad", `[]`, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serveWebhook(sink, "/webhook/mad/wrong", `[]`, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serveWebhook(sink, "/webhook/mad/s3cret", `[]`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+		req.Header.Set(DefaultTokenHeader, "s3cret")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// custom header
+	Auth.TokenHeader = "X-Mad-Token"
+	rec = serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+		req.Header.Set(DefaultTokenHeader, "s3cret")
+	})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+		req.Header.Set("X-Mad-Token", "s3cret")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, before+3, rejections(RejectReasonToken))
+}
+
+func TestWebhookAuthSignature(t *testing.T) {
+	Auth = WebhookAuth{HMACSecret: "s3cret"}
+	defer func() { Auth = WebhookAuth{} }()
+	before := rejections(RejectReasonSignature)
+
+	data := readTestFile("mad-webhook-all-types.json")
+	sink := newTestSink()
+
+	rec := serveWebhook(sink, "/webhook/mad", data, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serveWebhook(sink, "/webhook/mad", data, func(req *http.Request) {
+		req.Header.Set(DefaultSignatureHeader, sign("wrong", data))
+	})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serveWebhook(sink, "/webhook/mad", data, func(req *http.Request) {
+		req.Header.Set(DefaultSignatureHeader, "not hex")
+	})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// the handler still gets the whole body
+	rec = serveWebhook(sink, "/webhook/mad", data, func(req *http.Request) {
+		req.Header.Set(DefaultSignatureHeader, sign("s3cret", data))
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, len(sink.Gyms))
+
+	rec = serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+		req.Header.Set(DefaultSignatureHeader, "sha256="+sign("s3cret", `[]`))
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, before+3, rejections(RejectReasonSignature))
+}
+
+func TestWebhookAuthAllowedNets(t *testing.T) {
+	nets, _ := ParseAllowedNets([]string{"172.16.0.0/12", "::1"})
+	Auth = WebhookAuth{AllowedNets: nets}
+	sink := newTestSink()
+	defer func() { Auth = WebhookAuth{} }()
+	before := rejections(RejectReasonAddress)
+
+	for _, addr := range []string{"192.0.2.1:1234", "172.32.0.1:1234", "[::2]:1234", "garbage"} {
+		rec := serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+			req.RemoteAddr = addr
+			// only the TCP peer counts
+			req.Header.Set("X-Forwarded-For", "172.16.0.1")
+		})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, addr)
+	}
+
+	for _, addr := range []string{"172.16.0.1:1234", "172.31.255.255:1234", "[::1]:1234"} {
+		rec := serveWebhook(sink, "/webhook/mad", `[]`, func(req *http.Request) {
+			req.RemoteAddr = addr
+		})
+		assert.Equal(t, http.StatusOK, rec.Code, addr)
+	}
+
+	assert.Equal(t, before+4, rejections(RejectReasonAddress))
+}
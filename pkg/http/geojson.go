@@ -0,0 +1,197 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/spezifisch/silphtelescope/internal/helpers"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+// ActiveEventGetter returns the current events, implemented by roomservice.Poster
+type ActiveEventGetter interface {
+	GetActiveEvents(types []string, bbox *pogo.BoundingBox) roomservice.ActiveEventList
+}
+
+// FeatureCollection is a GeoJSON FeatureCollection, see RFC 7946
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature with a Point geometry
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Geometry   PointGeometry          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// PointGeometry is a GeoJSON Point, Coordinates are longitude and latitude in this order
+type PointGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// event types in the feed
+var geoJSONTypes = []string{ingest.EventSpawn, ingest.EventRaid, ingest.EventInvasion, ingest.EventGym}
+
+// AddGeoJSONFeed serves the active events at /geojson. Call Init() first.
+func AddGeoJSONFeed(events ActiveEventGetter) {
+	// community map pages are usually hosted elsewhere
+	cors := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{http.MethodGet},
+	})
+	e.GET("/geojson", func(c echo.Context) error {
+		return geoJSONFeed(c, events)
+	}, cors)
+}
+
+func geoJSONFeed(c echo.Context, events ActiveEventGetter) error {
+	types, err := parseGeoJSONTypes(c.QueryParam("type"))
+	if err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+	bbox, err := parseBBox(c.QueryParam("bbox"))
+	if err != nil {
+		return fail(c, http.StatusBadRequest, err.Error())
+	}
+
+	list := events.GetActiveEvents(types, bbox)
+	fc := FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []Feature{},
+	}
+	for _, s := range list.Spawns {
+		fc.Features = append(fc.Features, spawnFeature(&s))
+	}
+	for _, r := range list.Raids {
+		fc.Features = append(fc.Features, raidFeature(&r))
+	}
+	for _, i := range list.Invasions {
+		fc.Features = append(fc.Features, invasionFeature(&i))
+	}
+	for _, gs := range list.Gyms {
+		fc.Features = append(fc.Features, gymFeature(gs))
+	}
+
+	return c.JSON(http.StatusOK, fc)
+}
+
+// parseGeoJSONTypes parses "spawn,raid", all types are returned for an empty string
+func parseGeoJSONTypes(param string) (types []string, err error) {
+	if param == "" {
+		return geoJSONTypes, nil
+	}
+	for _, t := range strings.Split(param, ",") {
+		if !helpers.StringArrayContains(geoJSONTypes, t) {
+			return nil, errors.New("invalid type, use " + strings.Join(geoJSONTypes, ","))
+		}
+		types = append(types, t)
+	}
+	return
+}
+
+// parseBBox parses "<min lon>,<min lat>,<max lon>,<max lat>" like in RFC 7946, nil for an empty string
+func parseBBox(param string) (bbox *pogo.BoundingBox, err error) {
+	if param == "" {
+		return nil, nil
+	}
+	parts := strings.Split(param, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox needs 4 values: min lon, min lat, max lon, max lat")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, errors.New("invalid bbox value")
+		}
+	}
+	b := pogo.NewBoundingBox(values[1], values[0], values[3], values[2])
+	if b.SouthWest.Latitude > b.NorthEast.Latitude || b.SouthWest.Longitude > b.NorthEast.Longitude {
+		return nil, errors.New("bbox minimum is greater than maximum")
+	}
+	return &b, nil
+}
+
+func newPointFeature(id string, l pogo.Location, properties map[string]interface{}) Feature {
+	return Feature{
+		Type: "Feature",
+		ID:   id,
+		Geometry: PointGeometry{
+			Type:        "Point",
+			Coordinates: [2]float64{l.Longitude, l.Latitude},
+		},
+		Properties: properties,
+	}
+}
+
+func spawnFeature(s *pogo.Spawn) Feature {
+	properties := map[string]interface{}{
+		"type":            ingest.EventSpawn,
+		"pokemon_id":      s.Pokemon.ID,
+		"pokemon_name":    s.Pokemon.Name,
+		"gender":          int(s.Pokemon.Gender),
+		"end_time":        s.EndTime,
+		"verified":        s.VerifiedSpawnpoint,
+		"weather_boosted": s.BoostedWeather != pogo.WeatherNone,
+	}
+	if s.Encounter != nil {
+		properties["iv"] = s.Encounter.IVPercent()
+		properties["attack"] = s.Encounter.Attack
+		properties["defense"] = s.Encounter.Defense
+		properties["stamina"] = s.Encounter.Stamina
+		properties["cp"] = s.Encounter.CP
+		properties["level"] = s.Encounter.Level
+	}
+	return newPointFeature("spawn:"+s.EncounterID, s.Location, properties)
+}
+
+func raidFeature(r *roomservice.ActiveRaid) Feature {
+	properties := map[string]interface{}{
+		"type":       ingest.EventRaid,
+		"gym_id":     r.GymID,
+		"gym_name":   r.GymName,
+		"level":      r.Level,
+		"start_time": r.StartTime,
+		"end_time":   r.EndTime,
+		"egg":        r.IsEgg(),
+	}
+	if !r.IsEgg() {
+		properties["pokemon_id"] = r.Pokemon.ID
+		properties["pokemon_name"] = r.Pokemon.Name
+	}
+	return newPointFeature("raid:"+r.Hash, r.Location, properties)
+}
+
+func invasionFeature(i *pogo.Invasion) Feature {
+	return newPointFeature("invasion:"+i.Hash, i.Location, map[string]interface{}{
+		"type":          ingest.EventInvasion,
+		"pokestop_id":   i.PokestopID,
+		"pokestop_name": i.PokestopName,
+		"grunt_type":    int(i.GruntType),
+		"grunt_name":    i.GruntType.ToString(),
+		"end_time":      i.EndTime,
+	})
+}
+
+func gymFeature(gs *roomservice.GymState) Feature {
+	return newPointFeature("gym:"+gs.GUID, gs.Location, map[string]interface{}{
+		"type":             ingest.EventGym,
+		"gym_id":           gs.GUID,
+		"name":             gs.Name,
+		"team":             gs.TeamColor.ToString(),
+		"slots_available":  gs.SlotsAvailable,
+		"ex_raid_eligible": gs.ExRaidEligible,
+		"last_update":      gs.LastUpdate,
+	})
+}
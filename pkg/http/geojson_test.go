@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+type testActiveEvents struct {
+	types []string
+	bbox  *pogo.BoundingBox
+}
+
+func (t *testActiveEvents) GetActiveEvents(types []string, bbox *pogo.BoundingBox) roomservice.ActiveEventList {
+	t.types = types
+	t.bbox = bbox
+
+	location := pogo.Location{Latitude: 52.5, Longitude: 13.4}
+	return roomservice.ActiveEventList{
+		Spawns: []pogo.Spawn{{
+			EncounterID: "abc",
+			Pokemon:     pogo.Pokemon{ID: 16, Name: "Pidgey"},
+			Location:    location,
+			Encounter:   &pogo.Encounter{Attack: 15, Defense: 15, Stamina: 15, CP: 300},
+		}},
+		Raids: []roomservice.ActiveRaid{{
+			Raid:    pogo.Raid{Hash: "def", GymID: "gym1", Level: 5, Location: location},
+			GymName: "Town Hall",
+		}},
+		Gyms: []*roomservice.GymState{{GUID: "gym1", Name: "Town Hall", TeamColor: pogo.Red, Location: location}},
+	}
+}
+
+func serveGeoJSON(events ActiveEventGetter, query string) *httptest.ResponseRecorder {
+	Init()
+	AddGeoJSONFeed(events)
+
+	req := httptest.NewRequest(http.MethodGet, "/geojson"+query, nil)
+	req.Header.Set(echo.HeaderOrigin, "https://map.example.com")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGeoJSONFeed(t *testing.T) {
+	events := &testActiveEvents{}
+	rec := serveGeoJSON(events, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+	assert.Equal(t, geoJSONTypes, events.types)
+	assert.Nil(t, events.bbox)
+
+	fc := FeatureCollection{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &fc))
+	assert.Equal(t, "FeatureCollection", fc.Type)
+	if assert.Equal(t, 3, len(fc.Features)) {
+		spawn := fc.Features[0]
+		assert.Equal(t, "spawn:abc", spawn.ID)
+		assert.Equal(t, "Point", spawn.Geometry.Type)
+		assert.Equal(t, [2]float64{13.4, 52.5}, spawn.Geometry.Coordinates)
+		assert.Equal(t, "Pidgey", spawn.Properties["pokemon_name"])
+		assert.Equal(t, 100.0, spawn.Properties["iv"])
+
+		raid := fc.Features[1]
+		assert.Equal(t, "raid:def", raid.ID)
+		assert.Equal(t, true, raid.Properties["egg"])
+		assert.Equal(t, "Town Hall", raid.Properties["gym_name"])
+		assert.NotContains(t, raid.Properties, "pokemon_id")
+
+		assert.Equal(t, "red", fc.Features[2].Properties["team"])
+	}
+
+	rec = serveGeoJSON(events, "?type=spawn,raid&bbox=13.3,52.4,13.5,52.6")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"spawn", "raid"}, events.types)
+	if assert.NotNil(t, events.bbox) {
+		assert.Equal(t, pogo.NewBoundingBox(52.4, 13.3, 52.6, 13.5), *events.bbox)
+	}
+
+	for _, query := range []string{"?type=quest", "?bbox=1,2,3", "?bbox=a,b,c,d", "?bbox=13.5,52.4,13.3,52.6"} {
+		rec = serveGeoJSON(events, query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HealthCheck tests one component for /healthz and /readyz
+type HealthCheck struct {
+	Name string
+	// a failed liveness check means the process should be restarted, it fails /healthz.
+	// All checks count for /readyz.
+	Liveness bool
+	// Check returns a human-readable status, the check failed if err is set
+	Check func() (status string, err error)
+}
+
+// HealthResult is the outcome of a HealthCheck
+type HealthResult struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthResponse is the JSON body of /healthz and /readyz
+type HealthResponse struct {
+	OK     bool           `json:"ok"`
+	Checks []HealthResult `json:"checks"`
+}
+
+var (
+	healthMu     sync.Mutex
+	healthChecks []HealthCheck
+)
+
+// AddHealthCheck registers a check for /healthz and /readyz
+func AddHealthCheck(check HealthCheck) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	healthChecks = append(healthChecks, check)
+}
+
+// runHealthChecks runs the liveness checks or all checks
+func runHealthChecks(livenessOnly bool) (response HealthResponse) {
+	healthMu.Lock()
+	checks := append([]HealthCheck{}, healthChecks...)
+	healthMu.Unlock()
+
+	response.OK = true
+	response.Checks = []HealthResult{}
+	for _, check := range checks {
+		if livenessOnly && !check.Liveness {
+			continue
+		}
+
+		status, err := check.Check()
+		result := HealthResult{
+			Name:   check.Name,
+			OK:     err == nil,
+			Status: status,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			response.OK = false
+		}
+		response.Checks = append(response.Checks, result)
+	}
+	return
+}
+
+func serveHealth(c echo.Context, livenessOnly bool) error {
+	response := runHealthChecks(livenessOnly)
+	code := http.StatusOK
+	if !response.OK {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, response)
+}
+
+// healthz fails if the process is wedged
+func healthz(c echo.Context) error {
+	return serveHealth(c, true)
+}
+
+// readyz fails if any component isn't usable
+func readyz(c echo.Context) error {
+	return serveHealth(c, false)
+}
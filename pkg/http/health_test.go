@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getHealth(t *testing.T, path string) (code int, response HealthResponse) {
+	Init()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return rec.Code, response
+}
+
+func TestHealth(t *testing.T) {
+	defer func(saved []HealthCheck) {
+		healthChecks = saved
+	}(healthChecks)
+	healthChecks = nil
+
+	// nothing to check
+	code, response := getHealth(t, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.OK)
+	assert.Equal(t, []HealthResult{}, response.Checks)
+
+	syncErr := errors.New("no sync for 10m0s")
+	AddHealthCheck(HealthCheck{
+		Name:     "sync",
+		Liveness: true,
+		Check: func() (string, error) {
+			return "last sync 10m0s ago", syncErr
+		},
+	})
+	AddHealthCheck(HealthCheck{
+		Name: "db",
+		Check: func() (string, error) {
+			return "", errors.New("connection refused")
+		},
+	})
+
+	code, response = getHealth(t, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthResponse{
+		OK:     false,
+		Checks: []HealthResult{{Name: "sync", OK: false, Status: "last sync 10m0s ago", Error: "no sync for 10m0s"}},
+	}, response)
+
+	// the db is only relevant for readiness
+	syncErr = nil
+	code, response = getHealth(t, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.OK)
+
+	code, response = getHealth(t, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	if assert.Equal(t, 2, len(response.Checks)) {
+		assert.True(t, response.Checks[0].OK)
+		assert.Equal(t, HealthResult{Name: "db", OK: false, Error: "connection refused"}, response.Checks[1])
+	}
+}
@@ -1,26 +1,21 @@
 package http
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	log "github.com/sirupsen/logrus"
-	"github.com/spezifisch/silphtelescope/pkg/pogo"
+
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 )
 
 var (
 	// Bind specifies "<host>:<port>" to listen on
 	Bind string
-	// GymUpdates receiver
-	GymUpdates chan pogo.Gym
-	// SpawnUpdates receiver
-	SpawnUpdates chan pogo.Spawn
-	// RaidUpdates receiver
-	RaidUpdates chan pogo.Raid
 
-	e *echo.Echo
+	e        *echo.Echo
+	webhooks *echo.Group
 )
 
 // Init sets up the routes
@@ -31,12 +26,29 @@ func Init() {
 	e.HidePort = true
 
 	// Middleware
-	e.Use(middleware.Logger())
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Skipper: hasSecretInURL,
+	}))
 	e.Use(middleware.Recover())
 
 	// Routes
 	e.GET("/", hello)
-	e.POST("/webhook/mad", madWebhook)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	e.GET("/healthz", healthz)
+	e.GET("/readyz", readyz)
+	webhooks = e.Group("/webhook", webhookAuth)
+}
+
+// AddWebhook serves the source at /webhook/<name> and /webhook/<name>/<token>. Call Init() first.
+func AddWebhook(src *WebhookSource) {
+	webhooks.POST("/"+src.name, src.handle)
+	webhooks.POST("/"+src.name+"/:token", src.handle)
+}
+
+// hasSecretInURL returns true for requests that must not end up in the access log
+func hasSecretInURL(c echo.Context) bool {
+	// webhook token as last path element, stream token as query parameter
+	return c.Param("token") != "" || c.QueryParam("token") != ""
 }
 
 // Run starts the httpd
@@ -58,48 +70,3 @@ func Stop() {
 func hello(c echo.Context) error {
 	return c.String(http.StatusOK, "This is SilphTelescope.")
 }
-
-func madWebhook(c echo.Context) error {
-	var envelopes []Envelope
-	err := json.NewDecoder(c.Request().Body).Decode(&envelopes)
-	if err != nil {
-		log.Warnln("can't decode request to:", c.Request().URL, "error:", err)
-		return c.String(http.StatusBadRequest, "FAIL\n")
-	}
-
-	for _, msg := range envelopes {
-		var dst interface{}
-		switch msg.Type {
-		case "gym":
-			dst = new(GymMessage)
-		case "pokemon":
-			dst = new(PokemonMessage)
-		case "raid":
-			dst = new(RaidMessage)
-		case "pokestop":
-			continue
-		case "weather":
-			continue
-		default:
-			log.Debugln("unhandled type", msg.Type)
-			continue
-		}
-
-		err := json.Unmarshal(msg.Message, dst)
-		if err != nil {
-			log.Warn("can't decode message as type ", msg.Type, ": ", err)
-			continue
-		}
-
-		switch msg.Type {
-		case "gym":
-			sendGymUpdate(dst.(*GymMessage))
-		case "pokemon":
-			sendSpawnUpdate(dst.(*PokemonMessage))
-		case "raid":
-			sendRaidUpdate(dst.(*RaidMessage))
-		}
-	}
-
-	return c.String(http.StatusOK, "OK\n")
-}
@@ -3,12 +3,17 @@ package http
 import (
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,6 +42,27 @@ func testMadWebhookRequest(data string) (echo.Context, *httptest.ResponseRecorde
 	return c, rec
 }
 
+func newTestSink() *ingest.Sink {
+	return &ingest.Sink{
+		Gyms:      make(chan pogo.Gym, 50),
+		Spawns:    make(chan pogo.Spawn, 200),
+		Raids:     make(chan pogo.Raid, 50),
+		Weather:   make(chan pogo.Weather, 50),
+		Invasions: make(chan pogo.Invasion, 50),
+		Quests:    make(chan pogo.Quest, 50),
+		Lures:     make(chan pogo.Lure, 50),
+		Forts:     make(chan pogo.Fort, 200),
+	}
+}
+
+// startTestSource connects the source to the sink like Run() without blocking
+func startTestSource(src *WebhookSource, sink *ingest.Sink) *WebhookSource {
+	src.mu.Lock()
+	src.sink = sink
+	src.mu.Unlock()
+	return src
+}
+
 func TestSetup(t *testing.T) {
 	Init()
 
@@ -45,6 +71,19 @@ func TestSetup(t *testing.T) {
 	Stop()
 }
 
+func TestHasSecretInURL(t *testing.T) {
+	c, _ := testMadWebhookRequest(`[]`)
+	assert.False(t, hasSecretInURL(c))
+
+	c.SetParamNames("token")
+	c.SetParamValues("secret")
+	assert.True(t, hasSecretInURL(c))
+
+	c, _ = testMadWebhookRequest(`[]`)
+	c.Request().URL.RawQuery = "token=secret"
+	assert.True(t, hasSecretInURL(c))
+}
+
 func TestRoot(t *testing.T) {
 	data := `[]`
 	c, rec := testMadWebhookRequest(data)
@@ -55,20 +94,22 @@ func TestRoot(t *testing.T) {
 }
 
 func TestMadWebhookEmpty(t *testing.T) {
+	sink := newTestSink()
 	data := `[]`
 	c, rec := testMadWebhookRequest(data)
 
-	if assert.NoError(t, madWebhook(c)) {
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, responseOK, rec.Body.String())
 	}
 }
 
 func TestMadWebhookInvalid(t *testing.T) {
+	sink := newTestSink()
 	data := `foo`
 	c, rec := testMadWebhookRequest(data)
 
-	if assert.NoError(t, madWebhook(c)) {
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 		assert.Equal(t, responseFAIL, rec.Body.String())
 	}
@@ -78,39 +119,262 @@ func TestMadWebhookAllTypes(t *testing.T) {
 	data := readTestFile("mad-webhook-all-types.json")
 	c, rec := testMadWebhookRequest(data)
 
-	GymUpdates = make(chan pogo.Gym, 50)
-	RaidUpdates = make(chan pogo.Raid, 50)
-	SpawnUpdates = make(chan pogo.Spawn, 200)
+	sink := newTestSink()
 
-	if assert.NoError(t, madWebhook(c)) {
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, responseOK, rec.Body.String())
 	}
 }
 
+func TestMadWebhookGym(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Gyms)
+
+	var gyms []pogo.Gym
+	for g := range sink.Gyms {
+		gyms = append(gyms, g)
+	}
+	if assert.Equal(t, 1, len(gyms)) {
+		g := gyms[0]
+		assert.Equal(t, "ad009a3affaed08c1b6b91b1a5696ef4.16", g.GUID)
+		assert.Equal(t, "", g.Name)
+		assert.Equal(t, pogo.Yellow, g.TeamColor)
+		assert.Equal(t, 2, g.SlotsAvailable)
+		assert.True(t, g.ExRaidEligible)
+		assert.NotZero(t, g.UpdateTime)
+	}
+}
+
+func TestMadWebhookForts(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Forts)
+
+	forts := make(map[string]pogo.Fort)
+	count := 0
+	for f := range sink.Forts {
+		count++
+		forts[f.GUID] = f
+	}
+	// from raids, gyms and pokestops
+	assert.Equal(t, 5, count)
+
+	// "unknown" isn't a name
+	g := forts["ad009a3affaed08c1b6b91b1a5696ef4.16"]
+	assert.True(t, g.IsGym)
+	assert.Equal(t, "", g.Name)
+	assert.Equal(t, "http://lh3.googleusercontent.com/xyz", g.URL)
+	assert.Equal(t, 52.5016, math.Round(g.Location.Latitude*1e4)/1e4)
+
+	p := forts["ef27d3a22d760fd5741e166503d46854.16"]
+	assert.False(t, p.IsGym)
+	assert.Equal(t, "foo bar", p.Name)
+
+	assert.True(t, forts["23ec10cd2920aaa8e767898d0b4e44c3.16"].IsGym)
+}
+
+func TestMadWebhookEncounter(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Spawns)
+
+	var encountered []pogo.Spawn
+	spawnCount := 0
+	for s := range sink.Spawns {
+		spawnCount++
+		if s.Encounter != nil {
+			encountered = append(encountered, s)
+		}
+	}
+
+	// only one of the spawns has IVs
+	assert.Equal(t, 4, spawnCount)
+	if assert.Equal(t, 1, len(encountered)) {
+		s := encountered[0]
+		assert.Equal(t, 147, s.Pokemon.ID)
+		assert.Equal(t, &pogo.Encounter{
+			Attack:  15,
+			Defense: 14,
+			Stamina: 13,
+			CP:      812,
+			Level:   27,
+			Move1:   204,
+			Move2:   21,
+			Weight:  3.72,
+			Height:  1.86,
+		}, s.Encounter)
+	}
+}
+
+func TestMadWebhookWeather(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Weather)
+	close(sink.Spawns)
+
+	var weather []pogo.Weather
+	for w := range sink.Weather {
+		weather = append(weather, w)
+	}
+	if assert.Equal(t, 1, len(weather)) {
+		w := weather[0]
+		assert.Equal(t, pogo.WeatherOvercast, w.Condition)
+		assert.Equal(t, pogo.AlertNone, w.AlertSeverity)
+		assert.Equal(t, int64(1613492380), w.UpdateTime)
+		// the cell id in the test data is broken
+		assert.Equal(t, pogo.WeatherCellID(&w.Location), w.CellID)
+	}
+
+	boosted := 0
+	for s := range sink.Spawns {
+		if s.BoostedWeather == pogo.WeatherOvercast {
+			boosted++
+		}
+	}
+	assert.Equal(t, 2, boosted)
+}
+
+func TestMadWebhookInvasion(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Invasions)
+
+	var invasions []pogo.Invasion
+	for i := range sink.Invasions {
+		invasions = append(invasions, i)
+	}
+	if assert.Equal(t, 1, len(invasions)) {
+		i := invasions[0]
+		assert.Equal(t, "ef27d3a22d760fd5741e166503d46854.16:1613491985", i.Hash)
+		assert.Equal(t, "ef27d3a22d760fd5741e166503d46854.16", i.PokestopID)
+		assert.Equal(t, "foo bar", i.PokestopName)
+		assert.Equal(t, pogo.GruntType(37), i.GruntType)
+		assert.Equal(t, int64(1613491985), i.StartTime)
+		assert.Equal(t, int64(1613493785), i.EndTime)
+	}
+}
+
+func TestMadWebhookLure(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Lures)
+
+	var lures []pogo.Lure
+	for l := range sink.Lures {
+		lures = append(lures, l)
+	}
+	if assert.Equal(t, 1, len(lures)) {
+		l := lures[0]
+		assert.Equal(t, "d35a02eb49149450fb2fffc6e467eb37.16:1613493600", l.Hash)
+		assert.Equal(t, "d35a02eb49149450fb2fffc6e467eb37.16", l.PokestopID)
+		// MAD's "unknown" isn't a name
+		assert.Equal(t, "", l.PokestopName)
+		assert.Equal(t, pogo.LureTypeGlacial, l.LureType)
+		assert.Equal(t, int64(1613491800), l.StartTime)
+		assert.Equal(t, int64(1613493600), l.EndTime)
+	}
+}
+
+func TestMadWebhookQuest(t *testing.T) {
+	data := readTestFile("mad-webhook-all-types.json")
+	c, rec := testMadWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Quests)
+
+	quests := map[pogo.QuestRewardType]pogo.Quest{}
+	for q := range sink.Quests {
+		quests[q.RewardType] = q
+	}
+	assert.Equal(t, 3, len(quests))
+
+	q := quests[pogo.QuestRewardPokemonEncounter]
+	assert.Equal(t, "a7f0d1c7a8d44c3e9b2bd7b0e4e03f4b.16", q.PokestopID)
+	assert.Equal(t, "Oberbaumbruecke", q.PokestopName)
+	assert.Equal(t, "Catch 5 Dragon-type Pokemon", q.Task)
+	assert.Equal(t, 147, q.PokemonID)
+	assert.Equal(t, 0, q.ItemID)
+	assert.Equal(t, int64(1613491500), q.StartTime)
+	assert.Equal(t, pogo.QuestExpiry(time.Unix(1613491500, 0)).Unix(), q.EndTime)
+	assert.Contains(t, q.Hash, "a7f0d1c7a8d44c3e9b2bd7b0e4e03f4b.16:7:147:")
+
+	q = quests[pogo.QuestRewardItem]
+	assert.Equal(t, 706, q.ItemID)
+	assert.Equal(t, 0, q.PokemonID)
+	assert.Equal(t, 3, q.Amount)
+
+	q = quests[pogo.QuestRewardMegaEnergy]
+	assert.Equal(t, 6, q.PokemonID)
+	assert.Equal(t, 20, q.Amount)
+	// MAD's "unknown" isn't a name
+	assert.Equal(t, "", q.PokestopName)
+}
+
 func TestMadWebhookUnhandledType(t *testing.T) {
 	data := readTestFile("mad-webhook-unhandled-type.json")
 	c, rec := testMadWebhookRequest(data)
 
-	GymUpdates = make(chan pogo.Gym, 50)
-	RaidUpdates = make(chan pogo.Raid, 50)
-	SpawnUpdates = make(chan pogo.Spawn, 200)
+	sink := newTestSink()
+	other := metrics.WebhookEnvelopes.WithLabelValues("mad", otherType, metrics.OutcomeIgnored)
+	before := testutil.ToFloat64(other)
 
-	if assert.NoError(t, madWebhook(c)) {
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, responseOK, rec.Body.String())
 	}
+	// types from the request body don't become label values
+	assert.Equal(t, before+2, testutil.ToFloat64(other))
 }
 
 func TestMadWebhookBrokenMessages(t *testing.T) {
 	data := readTestFile("mad-webhook-broken.json")
 	c, rec := testMadWebhookRequest(data)
 
-	GymUpdates = make(chan pogo.Gym, 50)
-	RaidUpdates = make(chan pogo.Raid, 50)
-	SpawnUpdates = make(chan pogo.Spawn, 200)
+	sink := newTestSink()
 
-	if assert.NoError(t, madWebhook(c)) {
+	if assert.NoError(t, startTestSource(NewMADSource(), sink).handle(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, responseOK, rec.Body.String())
 	}
@@ -39,6 +39,22 @@ type PokemonMessage struct {
 	DisappearTimestamp int64   `json:"disappear_time"`
 	KnownDisappearTime bool    `json:"verified"`
 	Rarity             int     `json:"rarity"`
+	BoostedWeather     int     `json:"boosted_weather,omitempty"`
+
+	EncounterDetails
+}
+
+// EncounterDetails are only set when the scanner encountered the pokemon, MAD and RDM use the same names
+type EncounterDetails struct {
+	IndividualAttack  *int     `json:"individual_attack,omitempty"`
+	IndividualDefense *int     `json:"individual_defense,omitempty"`
+	IndividualStamina *int     `json:"individual_stamina,omitempty"`
+	CP                *int     `json:"cp,omitempty"`
+	PokemonLevel      *int     `json:"pokemon_level,omitempty"`
+	Move1             *int     `json:"move_1,omitempty"`
+	Move2             *int     `json:"move_2,omitempty"`
+	Weight            *float64 `json:"weight,omitempty"`
+	Height            *float64 `json:"height,omitempty"`
 }
 
 // RaidPokemon describes an egg or spawned raid boss
@@ -68,7 +84,54 @@ type RaidMessage struct {
 	BasePokemon
 }
 
-// Envelope is the thing that MAD posts to the configures webhooks
+// PokestopMessage contains pokestop info with an optional invasion and lure
+type PokestopMessage struct {
+	Location
+	PokestopID         string `json:"pokestop_id"`
+	Name               string `json:"name"`
+	URL                string `json:"url,omitempty"`
+	Updated            int64  `json:"updated"`
+	LastModified       int64  `json:"last_modified"`
+	LureExpiration     int64  `json:"lure_expiration"`
+	ActiveFortModifier int    `json:"active_fort_modifier"` // lure item id, null without lure
+	IncidentStart      int64  `json:"incident_start"`
+	IncidentExpiration int64  `json:"incident_expiration"`
+	IncidentGruntType  int    `json:"incident_grunt_type"`
+}
+
+// QuestMessage contains a field research quest at a pokestop
+type QuestMessage struct {
+	Location
+	PokestopID         string `json:"pokestop_id"`
+	Name               string `json:"name"`
+	URL                string `json:"url,omitempty"`
+	Timestamp          int64  `json:"timestamp"`
+	QuestType          string `json:"quest_type"`
+	QuestTypeRaw       int    `json:"quest_type_raw"`
+	QuestTarget        int    `json:"quest_target"`
+	QuestTask          string `json:"quest_task"`
+	QuestTemplate      string `json:"quest_template"`
+	QuestRewardType    string `json:"quest_reward_type"`
+	QuestRewardTypeRaw int    `json:"quest_reward_type_raw"`
+	ItemType           string `json:"item_type"`
+	ItemID             int    `json:"item_id"`
+	ItemAmount         int    `json:"item_amount"`
+	PokemonID          int    `json:"pokemon_id"`
+	PokemonForm        int    `json:"pokemon_form,omitempty"`
+	PokemonCostume     int    `json:"pokemon_costume,omitempty"`
+}
+
+// WeatherMessage contains the weather of a level 10 S2 cell
+type WeatherMessage struct {
+	Location
+	S2CellID      string `json:"s2_cell_id"`
+	Condition     int    `json:"condition"`
+	AlertSeverity int    `json:"alert_severity"`
+	Day           int    `json:"day"`
+	TimeChanged   int64  `json:"time_changed"`
+}
+
+// Envelope is the thing that MAD and RDM post to the configured webhooks
 type Envelope struct {
 	Type    string          `json:"type"` // gym, pokemon, raid, pokestop, weather
 	Message json.RawMessage `json:"message"`
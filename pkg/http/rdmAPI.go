@@ -0,0 +1,75 @@
+package http
+
+// RDMPokemonMessage describes a spawned pokemon as sent by RealDeviceMap
+type RDMPokemonMessage struct {
+	Location
+	BasePokemon
+	EncounterID           string `json:"encounter_id"`
+	SpawnpointID          string `json:"spawnpoint_id"` // hex or "None" for nearby/lure spawns
+	PokestopID            string `json:"pokestop_id"`   // "None" for wild spawns
+	DisappearTimestamp    int64  `json:"disappear_time"`
+	DisappearTimeVerified bool   `json:"disappear_time_verified"`
+	FirstSeen             int64  `json:"first_seen"`
+	BoostedWeather        int    `json:"weather,omitempty"`
+
+	EncounterDetails
+
+	// PVP rankings by league ("great", "ultra", ...), not used yet
+	PVP map[string][]RDMPVPRanking `json:"pvp,omitempty"`
+}
+
+// RDMPVPRanking is one entry of a pvp block, for the pokemon itself or one of its evolutions
+type RDMPVPRanking struct {
+	Pokemon    int      `json:"pokemon"`
+	Form       int      `json:"form,omitempty"`
+	Rank       *int     `json:"rank,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+	CP         int      `json:"cp"`
+	Level      float64  `json:"level"`
+	Cap        int      `json:"cap,omitempty"`
+}
+
+// RDMRaidMessage contains raid info for a gym as sent by RealDeviceMap
+type RDMRaidMessage struct {
+	Location
+	GymID          string `json:"gym_id"`
+	GymName        string `json:"gym_name"`
+	GymURL         string `json:"gym_url,omitempty"`
+	TeamID         int    `json:"team_id"`
+	Level          int    `json:"level"`
+	SpawnTimestamp int64  `json:"spawn"`
+	StartTimestamp int64  `json:"start"`
+	EndTimestamp   int64  `json:"end"`
+	CP             int    `json:"cp"`
+	Evolution      int    `json:"evolution"`
+	Move1          int    `json:"move_1"`
+	Move2          int    `json:"move_2"`
+	ExRaidEligible bool   `json:"ex_raid_eligible"`
+	IsExclusive    bool   `json:"is_exclusive"`
+
+	BasePokemon
+}
+
+// RDMGymMessage contains gym info as sent by RealDeviceMap for the "gym" type
+type RDMGymMessage struct {
+	Location
+	GymID          string `json:"gym_id"`
+	GymName        string `json:"gym_name"`
+	URL            string `json:"url,omitempty"`
+	TeamID         int    `json:"team_id"`
+	SlotsAvailable int    `json:"slots_available"`
+	ExRaidEligible bool   `json:"ex_raid_eligible"`
+	LastModified   int64  `json:"last_modified"`
+}
+
+// RDMGymDetailsMessage contains gym info as sent by RealDeviceMap for the "gym_details" type
+type RDMGymDetailsMessage struct {
+	Location
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url,omitempty"`
+	Team           int    `json:"team"`
+	SlotsAvailable int    `json:"slots_available"`
+	ExRaidEligible bool   `json:"ex_raid_eligible"`
+	InBattle       bool   `json:"in_battle"`
+}
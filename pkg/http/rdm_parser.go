@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// parseRDMMessage is the messageParser for RealDeviceMap webhooks
+func parseRDMMessage(msgType string, raw json.RawMessage) (events []interface{}, err error) {
+	var dst interface{}
+	switch msgType {
+	case "gym":
+		dst = new(RDMGymMessage)
+	case "gym_details":
+		dst = new(RDMGymDetailsMessage)
+	case "pokemon":
+		dst = new(RDMPokemonMessage)
+	case "raid":
+		dst = new(RDMRaidMessage)
+	default:
+		return nil, errUnhandledType
+	}
+
+	err = json.Unmarshal(raw, dst)
+	if err != nil {
+		return
+	}
+
+	switch msgType {
+	case "gym":
+		msg := dst.(*RDMGymMessage)
+		events = append(events, convertRDMGym(msg),
+			newFort(msg.GymID, msg.GymName, msg.URL, msg.Location, true))
+	case "gym_details":
+		msg := dst.(*RDMGymDetailsMessage)
+		events = append(events, convertRDMGymDetails(msg),
+			newFort(msg.ID, msg.Name, msg.URL, msg.Location, true))
+	case "pokemon":
+		events = append(events, convertRDMSpawn(dst.(*RDMPokemonMessage)))
+	case "raid":
+		msg := dst.(*RDMRaidMessage)
+		events = append(events, convertRDMRaid(msg),
+			newFort(msg.GymID, msg.GymName, msg.GymURL, msg.Location, true))
+	}
+	return
+}
+
+func convertRDMSpawn(msg *RDMPokemonMessage) pogo.Spawn {
+	m := pogo.Spawn{
+		EncounterID:        msg.EncounterID,
+		VerifiedSpawnpoint: msg.DisappearTimeVerified,
+		Pokemon: pogo.Pokemon{
+			ID:     msg.PokemonID,
+			Name:   "", // too expensive to look up right now, it's probaby ignored anyway
+			Gender: pogo.ToGender(msg.Gender),
+		},
+		TimestampRange: pogo.TimestampRange{
+			StartTime: 0, // first_seen isn't the spawn time
+			EndTime:   msg.DisappearTimestamp,
+		},
+		Location: pogo.Location{
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+		},
+		Encounter:      getEncounter(&msg.EncounterDetails),
+		BoostedWeather: pogo.ToWeatherCondition(msg.BoostedWeather),
+	}
+	return m
+}
+
+func convertRDMRaid(msg *RDMRaidMessage) pogo.Raid {
+	var mon *pogo.Pokemon = nil
+	if msg.PokemonID != 0 {
+		mon = &pogo.Pokemon{
+			ID:     msg.PokemonID,
+			Name:   "", // too expensive to look up right now, it's probaby ignored anyway
+			Gender: pogo.ToGender(msg.Gender),
+		}
+	}
+
+	m := pogo.Raid{
+		Hash:  pogo.RaidHash(msg.GymID, msg.StartTimestamp),
+		GymID: msg.GymID,
+		Location: pogo.Location{
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+		},
+		Pokemon: mon,
+		Level:   msg.Level,
+		TimestampRange: pogo.TimestampRange{
+			StartTime: msg.StartTimestamp,
+			EndTime:   msg.EndTimestamp,
+		},
+	}
+	return m
+}
+
+func convertRDMGym(msg *RDMGymMessage) pogo.Gym {
+	m := pogo.Gym{
+		GUID:      msg.GymID,
+		Name:      fortName(msg.GymName),
+		TeamColor: pogo.ToTeamColor(msg.TeamID),
+		Location: pogo.Location{
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+		},
+		SlotsAvailable: msg.SlotsAvailable,
+		ExRaidEligible: msg.ExRaidEligible,
+		UpdateTime:     time.Now().Unix(), // last_modified is when the gym changed in game
+	}
+	return m
+}
+
+func convertRDMGymDetails(msg *RDMGymDetailsMessage) pogo.Gym {
+	m := pogo.Gym{
+		GUID:      msg.ID,
+		Name:      fortName(msg.Name),
+		TeamColor: pogo.ToTeamColor(msg.Team),
+		Location: pogo.Location{
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+		},
+		SlotsAvailable: msg.SlotsAvailable,
+		ExRaidEligible: msg.ExRaidEligible,
+		UpdateTime:     time.Now().Unix(), // RDM doesn't supply that info
+	}
+	return m
+}
@@ -0,0 +1,221 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/stretchr/testify/assert"
+)
+
+func testRdmWebhookRequest(data string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/rdm", strings.NewReader(data))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	return c, rec
+}
+
+func postRdmTestFile(t *testing.T) *ingest.Sink {
+	data := readTestFile("rdm-webhook-all-types.json")
+	c, rec := testRdmWebhookRequest(data)
+
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewRDMSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, responseOK, rec.Body.String())
+	}
+	close(sink.Gyms)
+	close(sink.Raids)
+	close(sink.Spawns)
+	close(sink.Forts)
+	return sink
+}
+
+func TestRdmWebhookEmpty(t *testing.T) {
+	sink := newTestSink()
+	c, rec := testRdmWebhookRequest(`[]`)
+
+	if assert.NoError(t, startTestSource(NewRDMSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, responseOK, rec.Body.String())
+	}
+}
+
+func TestRdmWebhookInvalid(t *testing.T) {
+	sink := newTestSink()
+	c, rec := testRdmWebhookRequest(`foo`)
+
+	if assert.NoError(t, startTestSource(NewRDMSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, responseFAIL, rec.Body.String())
+	}
+}
+
+func TestRdmWebhookBrokenMessage(t *testing.T) {
+	c, rec := testRdmWebhookRequest(`[{"type": "pokemon", "message": {"encounter_id": 42}}]`)
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewRDMSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, 0, len(sink.Spawns))
+}
+
+func TestRdmWebhookRoute(t *testing.T) {
+	Auth = WebhookAuth{}
+	sink := newTestSink()
+
+	rec := serveWebhook(sink, "/webhook/rdm", `[]`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRdmWebhookSpawns(t *testing.T) {
+	sink := postRdmTestFile(t)
+
+	var spawns []pogo.Spawn
+	for s := range sink.Spawns {
+		spawns = append(spawns, s)
+	}
+	if !assert.Equal(t, 2, len(spawns)) {
+		return
+	}
+
+	s := spawns[0]
+	assert.Equal(t, "13940498398425584193", s.EncounterID)
+	assert.Equal(t, 147, s.Pokemon.ID)
+	assert.Equal(t, pogo.ToGender(1), s.Pokemon.Gender)
+	assert.True(t, s.VerifiedSpawnpoint)
+	assert.Equal(t, int64(1613493017), s.EndTime)
+	assert.Equal(t, pogo.WeatherPartlyCloudy, s.BoostedWeather)
+	if assert.NotNil(t, s.Encounter) {
+		assert.Equal(t, pogo.Encounter{
+			Attack:  15,
+			Defense: 14,
+			Stamina: 13,
+			CP:      532,
+			Level:   20,
+			Move1:   204,
+			Move2:   57,
+			Weight:  3.12,
+			Height:  1.74,
+		}, *s.Encounter)
+	}
+
+	s = spawns[1]
+	assert.Equal(t, "877085851519025965", s.EncounterID)
+	assert.Equal(t, 595, s.Pokemon.ID)
+	assert.False(t, s.VerifiedSpawnpoint)
+	assert.Nil(t, s.Encounter)
+	assert.Equal(t, pogo.WeatherNone, s.BoostedWeather)
+}
+
+func TestRdmWebhookRaids(t *testing.T) {
+	sink := postRdmTestFile(t)
+
+	var raids []pogo.Raid
+	for r := range sink.Raids {
+		raids = append(raids, r)
+	}
+	if !assert.Equal(t, 2, len(raids)) {
+		return
+	}
+
+	egg := raids[0]
+	assert.True(t, egg.IsEgg())
+	assert.Equal(t, "ad009a3affaed08c1b6b91b1a5696ef4.16:1613491985", egg.Hash)
+	assert.Equal(t, "ad009a3affaed08c1b6b91b1a5696ef4.16", egg.GymID)
+	assert.Equal(t, 5, egg.Level)
+	assert.Equal(t, int64(1613491985), egg.StartTime)
+	assert.Equal(t, int64(1613494685), egg.EndTime)
+
+	boss := raids[1]
+	assert.False(t, boss.IsEgg())
+	if assert.NotNil(t, boss.Pokemon) {
+		assert.Equal(t, 68, boss.Pokemon.ID)
+	}
+	assert.Equal(t, 3, boss.Level)
+}
+
+func TestRdmWebhookGyms(t *testing.T) {
+	sink := postRdmTestFile(t)
+
+	var gyms []pogo.Gym
+	for g := range sink.Gyms {
+		gyms = append(gyms, g)
+	}
+	if !assert.Equal(t, 2, len(gyms)) {
+		return
+	}
+
+	g := gyms[0]
+	assert.Equal(t, "ad009a3affaed08c1b6b91b1a5696ef4.16", g.GUID)
+	assert.Equal(t, "Spree Gym", g.Name)
+	assert.Equal(t, pogo.Yellow, g.TeamColor)
+	assert.Equal(t, 1, g.SlotsAvailable)
+	assert.True(t, g.ExRaidEligible)
+	assert.NotZero(t, g.UpdateTime)
+
+	g = gyms[1]
+	assert.Equal(t, "42cafef00d010101010101010101023.16", g.GUID)
+	assert.Equal(t, "Women Graffiti", g.Name)
+	assert.Equal(t, pogo.Red, g.TeamColor)
+	assert.Equal(t, 0, g.SlotsAvailable)
+	assert.False(t, g.ExRaidEligible)
+}
+
+func TestRdmWebhookForts(t *testing.T) {
+	sink := postRdmTestFile(t)
+
+	var forts []pogo.Fort
+	for f := range sink.Forts {
+		forts = append(forts, f)
+	}
+	// from raids, gyms and gym details
+	if !assert.Equal(t, 4, len(forts)) {
+		return
+	}
+	for _, f := range forts {
+		assert.True(t, f.IsGym)
+		assert.NotEmpty(t, f.Name)
+	}
+	assert.Equal(t, "ad009a3affaed08c1b6b91b1a5696ef4.16", forts[0].GUID)
+	assert.Equal(t, "Spree Gym", forts[0].Name)
+	assert.Equal(t, "http://lh3.googleusercontent.com/xyz", forts[0].URL)
+}
+
+func TestRdmWebhookUnknownGym(t *testing.T) {
+	c, rec := testRdmWebhookRequest(readTestFile("rdm-webhook-unknown-gym.json"))
+	sink := newTestSink()
+
+	if assert.NoError(t, startTestSource(NewRDMSource(), sink).handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	close(sink.Gyms)
+	close(sink.Forts)
+
+	var gyms []pogo.Gym
+	for g := range sink.Gyms {
+		gyms = append(gyms, g)
+	}
+	var forts []pogo.Fort
+	for f := range sink.Forts {
+		forts = append(forts, f)
+	}
+	if !assert.Equal(t, 2, len(gyms)) || !assert.Equal(t, 2, len(forts)) {
+		return
+	}
+
+	// "Unknown" isn't a name, so the real one can take its place later
+	assert.Equal(t, "", gyms[0].Name)
+	assert.Equal(t, "", forts[0].Name)
+	assert.Equal(t, "Spree Gym", gyms[1].Name)
+	assert.Equal(t, "Spree Gym", forts[1].Name)
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+)
+
+// replayParsers maps the source names in recordings to their parsers
+var replayParsers = map[string]messageParser{
+	"mad": parseMADMessage,
+	"rdm": parseRDMMessage,
+}
+
+// ReplaySource is an ingest.Source feeding a recording through the webhook parsers
+type ReplaySource struct {
+	ingest.Counter
+
+	path string
+	// Speed 1 replays in real time, 10 ten times faster, 0 as fast as possible
+	speed float64
+
+	done     chan bool
+	stopOnce sync.Once
+}
+
+// NewReplaySource creates a source for the recording at path
+func NewReplaySource(path string, speed float64) *ReplaySource {
+	return &ReplaySource{
+		path:  path,
+		speed: speed,
+		done:  make(chan bool),
+	}
+}
+
+// Name returns "replay"
+func (s *ReplaySource) Name() string {
+	return "replay"
+}
+
+// Run emits the recorded events until the end of the recording or Stop.
+// Event timestamps are shifted so that each batch happens when it's emitted, at any speed.
+func (s *ReplaySource) Run(sink *ingest.Sink) (err error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	reader := ingest.NewRecordReader(f)
+	replayStart := time.Now()
+	var firstRecord int64
+	for n := 0; ; n++ {
+		rec, err2 := reader.Next()
+		if err2 == io.EOF {
+			log.Infof("replay: %d batches done", n)
+			return
+		} else if err2 != nil {
+			return fmt.Errorf("record %d: %w", n+1, err2)
+		}
+
+		if n == 0 {
+			firstRecord = rec.Time
+		}
+		if !s.wait(replayStart, time.Duration(rec.Time-firstRecord)*time.Millisecond) {
+			return
+		}
+
+		parse, ok := replayParsers[rec.Source]
+		if !ok {
+			log.Warnf("replay: unknown source %s in record %d", rec.Source, n+1)
+			s.CountError()
+			continue
+		}
+		var envelopes []Envelope
+		if err2 = json.Unmarshal(rec.Batch, &envelopes); err2 != nil {
+			log.WithError(err2).Warnf("replay: can't decode record %d", n+1)
+			s.CountError()
+			continue
+		}
+		offset := time.Now().Unix() - rec.Time/1000 // seconds
+		emitEnvelopes(&s.Counter, rec.Source, parse, sink, envelopes, offset)
+	}
+}
+
+// wait sleeps until the recorded time since the first record has passed, scaled by speed.
+// It returns false if stopped in the meantime.
+func (s *ReplaySource) wait(replayStart time.Time, sinceFirst time.Duration) bool {
+	if s.speed <= 0 {
+		select {
+		case <-s.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	due := replayStart.Add(time.Duration(float64(sinceFirst) / s.speed))
+	select {
+	case <-s.done:
+		return false
+	case <-time.After(time.Until(due)):
+		return true
+	}
+}
+
+// Stop makes Run return
+func (s *ReplaySource) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-replay")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	recorder, err := ingest.NewRecorder(path, 0, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// record both scanners, broken requests aren't recorded
+	mad := startTestSource(NewMADSource(), newTestSink())
+	mad.Recorder = recorder
+	rdm := startTestSource(NewRDMSource(), newTestSink())
+	rdm.Recorder = recorder
+	c, _ := testMadWebhookRequest(readTestFile("mad-webhook-all-types.json"))
+	assert.Nil(t, mad.handle(c))
+	c, _ = testMadWebhookRequest(`foo`)
+	assert.Nil(t, mad.handle(c))
+	c, _ = testRdmWebhookRequest(readTestFile("rdm-webhook-all-types.json"))
+	assert.Nil(t, rdm.handle(c))
+	recorder.Close()
+
+	sink := newTestSink()
+	src := NewReplaySource(path, 0)
+	assert.Equal(t, "replay", src.Name())
+	assert.Nil(t, src.Run(sink))
+
+	counters := src.Counters()
+	assert.Equal(t, mad.Counters().Events[ingest.EventSpawn]+rdm.Counters().Events[ingest.EventSpawn], counters.Events[ingest.EventSpawn])
+	assert.Equal(t, uint64(0), counters.Errors)
+}
+
+func TestReplayShift(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-replay")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	// recorded when the weather changed
+	recorder, err := ingest.NewRecorder(path, 0, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	recorder.Write("mad", []byte(readTestFile("mad-webhook-all-types.json")), time.Unix(1613492380, 0))
+	recorder.Close()
+
+	sink := newTestSink()
+	assert.Nil(t, NewReplaySource(path, 0).Run(sink))
+
+	// so the weather changed just now
+	w := <-sink.Weather
+	assert.InDelta(t, time.Now().Unix(), w.UpdateTime, 5)
+
+	// and everything else moved with it
+	live := newTestSink()
+	c, _ := testMadWebhookRequest(readTestFile("mad-webhook-all-types.json"))
+	startTestSource(NewMADSource(), live).handle(c)
+	offset := w.UpdateTime - 1613492380
+	assert.Equal(t, (<-live.Raids).StartTime+offset, (<-sink.Raids).StartTime)
+	assert.Equal(t, (<-live.Spawns).EndTime+offset, (<-sink.Spawns).EndTime)
+}
+
+func TestReplayShiftFast(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-replay")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	// weather changes recorded when they happened, an hour apart
+	recorder, err := ingest.NewRecorder(path, 0, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	weather := `[{"type": "weather", "message": {"s2_cell_id": "5118835684653216542", "condition": 4, "time_changed": %d, "latitude": 52.49892577039597, "longitude": 13.474062019460323}}]`
+	for _, changed := range []int64{1613492380, 1613492380 + 3600} {
+		recorder.Write("mad", []byte(fmt.Sprintf(weather, changed)), time.Unix(changed, 0))
+	}
+	recorder.Close()
+
+	for _, speed := range []float64{0, 3600} {
+		sink := newTestSink()
+		assert.Nil(t, NewReplaySource(path, speed).Run(sink))
+
+		// each batch happens when it's replayed, not an hour after the first one
+		for i := 0; i < 2; i++ {
+			w := <-sink.Weather
+			assert.InDelta(t, time.Now().Unix(), w.UpdateTime, 5, "speed %v batch %d", speed, i)
+		}
+	}
+}
+
+func TestReplayStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-replay")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	// the second batch is due in an hour
+	content := `{"time":1613491985000,"source":"mad","batch":[]}
+{"time":1613495585000,"source":"foo","batch":[]}
+`
+	ioutil.WriteFile(path, []byte(content), 0640)
+
+	src := NewReplaySource(path, 1)
+	done := make(chan error)
+	go func() {
+		done <- src.Run(newTestSink())
+	}()
+	src.Stop()
+	src.Stop()
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Error("replay didn't stop")
+	}
+
+	// missing file
+	assert.NotNil(t, NewReplaySource(filepath.Join(dir, "missing.jsonl"), 0).Run(newTestSink()))
+
+	// unknown source and broken lines
+	ioutil.WriteFile(path, []byte(`{"time":1,"source":"foo","batch":[]}
+{"time":2,"source":"mad","batch":{}}
+`), 0640)
+	src = NewReplaySource(path, 0)
+	assert.Nil(t, src.Run(newTestSink()))
+	assert.Equal(t, uint64(2), src.Counters().Errors)
+
+	ioutil.WriteFile(path, []byte("foo\n"), 0640)
+	assert.NotNil(t, NewReplaySource(path, 0).Run(newTestSink()))
+}
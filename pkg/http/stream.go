@@ -0,0 +1,159 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+const (
+	// events for slow subscribers are dropped when this many are waiting
+	streamBufferSize = 50
+	// comment lines keep proxies from closing idle streams
+	streamKeepAlive = 30 * time.Second
+)
+
+// RoomConfigGetter looks up RoomConfigs, implemented by roomservice.Poster
+type RoomConfigGetter interface {
+	GetRoomConfig(roomID string) (*roomservice.RoomConfig, bool)
+}
+
+// StreamHub sends the spawns and raids posted to a room to the subscribers of /stream/<room>
+type StreamHub struct {
+	// BaseURL is where users reach the http server, e.g. "https://silpht.example.com"
+	BaseURL string
+
+	rooms     RoomConfigGetter
+	keepAlive time.Duration
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan roomservice.PostEvent]bool // room ID -> subscriber channels
+}
+
+// AddEventStream serves the rooms' posts as Server-Sent Events at /stream/<room>. Call Init() first.
+func AddEventStream(rooms RoomConfigGetter, baseURL string) (h *StreamHub) {
+	h = &StreamHub{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		rooms:       rooms,
+		keepAlive:   streamKeepAlive,
+		subscribers: make(map[string]map[chan roomservice.PostEvent]bool),
+	}
+	e.GET("/stream/:room", h.stream)
+	return
+}
+
+// Publish sends the event to the room's subscribers without blocking
+func (h *StreamHub) Publish(event roomservice.PostEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.RoomID] {
+		select {
+		case ch <- event:
+		default:
+			log.Debugf("stream subscriber of %s is too slow, dropped %s", event.RoomID, event.Kind)
+		}
+	}
+}
+
+// StreamURL returns the stream's address, clients send the token in the Authorization header
+func (h *StreamHub) StreamURL(roomID string) string {
+	return h.BaseURL + "/stream/" + url.PathEscape(roomID)
+}
+
+// Subscribers returns the number of open streams of the room
+func (h *StreamHub) Subscribers(roomID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers[roomID])
+}
+
+func (h *StreamHub) subscribe(roomID string) chan roomservice.PostEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan roomservice.PostEvent, streamBufferSize)
+	if h.subscribers[roomID] == nil {
+		h.subscribers[roomID] = make(map[chan roomservice.PostEvent]bool)
+	}
+	h.subscribers[roomID][ch] = true
+	return ch
+}
+
+func (h *StreamHub) unsubscribe(roomID string, ch chan roomservice.PostEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[roomID], ch)
+	if len(h.subscribers[roomID]) == 0 {
+		delete(h.subscribers, roomID)
+	}
+}
+
+// checkToken returns true if the room's stream is on and the token matches
+func (h *StreamHub) checkToken(roomID, token string) bool {
+	rc, ok := h.rooms.GetRoomConfig(roomID)
+	if !ok || rc.StreamToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(rc.StreamToken)) == 1
+}
+
+func (h *StreamHub) stream(c echo.Context) error {
+	roomID := getRoomParam(c)
+	token := c.QueryParam("token")
+	if auth := c.Request().Header.Get(echo.HeaderAuthorization); auth != "" {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if !h.checkToken(roomID, token) {
+		log.Warnf("rejected stream request for %s from %s", roomID, c.Request().RemoteAddr)
+		return fail(c, http.StatusUnauthorized, "invalid token")
+	}
+
+	ch := h.subscribe(roomID)
+	defer h.unsubscribe(roomID, ch)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses by default
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	fmt.Fprint(res, ": connected\n\n")
+	res.Flush()
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-ch:
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.WithError(err).Error("can't encode stream event")
+				continue
+			}
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Kind, data)
+			res.Flush()
+		case <-keepAlive.C:
+			// the token was replaced or the stream turned off
+			if !h.checkToken(roomID, token) {
+				return nil
+			}
+			fmt.Fprint(res, ": keep-alive\n\n")
+			res.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+func readStreamLine(t *testing.T, r *bufio.Reader) string {
+	line, err := r.ReadString('\n')
+	assert.Nil(t, err)
+	return strings.TrimSuffix(line, "\n")
+}
+
+func TestEventStream(t *testing.T) {
+	Init()
+	p := roomservice.NewPoster(nil, nil)
+	roomID := "!foo:example.com"
+	p.UpdateRoomConfig(&roomservice.RoomConfig{RoomID: roomID, StreamToken: "t0ken"})
+	p.UpdateRoomConfig(&roomservice.RoomConfig{RoomID: "!bar:example.com"})
+	h := AddEventStream(p, "https://silpht.example.com/")
+	h.keepAlive = 20 * time.Millisecond
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	streamURL := h.StreamURL(roomID)
+	assert.Equal(t, "https://silpht.example.com/stream/%21foo:example.com", streamURL)
+	streamURL = strings.Replace(streamURL, h.BaseURL, server.URL, 1)
+
+	for _, url := range []string{
+		server.URL + "/stream/%21foo:example.com",
+		server.URL + "/stream/%21foo:example.com?token=wrong",
+		server.URL + "/stream/%21bar:example.com?token=",
+	} {
+		resp, err := http.Get(url)
+		if assert.Nil(t, err) {
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, url)
+			resp.Body.Close()
+		}
+	}
+
+	// for clients that can't set headers
+	resp, err := http.Get(streamURL + "?token=t0ken")
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+	assert.Eventually(t, func() bool {
+		return h.Subscribers(roomID) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	req, _ := http.NewRequest(http.MethodGet, streamURL, nil)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	resp, err = http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+	assert.Equal(t, ": connected", readStreamLine(t, r))
+	assert.Equal(t, 1, h.Subscribers(roomID))
+
+	// only the room's own posts are sent
+	h.Publish(roomservice.PostEvent{RoomID: "!bar:example.com", Kind: roomservice.PostKindRaid})
+	h.Publish(roomservice.PostEvent{
+		RoomID: roomID,
+		Kind:   roomservice.PostKindSpawn,
+		Text:   "Pidgey until 06:58:02",
+		Spawn:  &roomservice.SpawnPost{EncounterID: "abc", PokemonID: 16},
+	})
+	line := readStreamLine(t, r)
+	for strings.HasPrefix(line, ": keep-alive") || line == "" {
+		line = readStreamLine(t, r)
+	}
+	assert.Equal(t, "event: spawn", line)
+	line = readStreamLine(t, r)
+	if assert.True(t, strings.HasPrefix(line, "data: "), line) {
+		event := roomservice.PostEvent{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		assert.Equal(t, "Pidgey until 06:58:02", event.Text)
+		if assert.NotNil(t, event.Spawn) {
+			assert.Equal(t, 16, event.Spawn.PokemonID)
+		}
+		assert.Nil(t, event.Raid)
+	}
+
+	// turning the stream off closes it
+	p.ChangeRoomConfig(roomID, &roomservice.RoomConfigChange{ChangeStreamToken: true}, &roomservice.RoomConfig{})
+	for {
+		if _, err := r.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return h.Subscribers(roomID) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
+)
+
+// messageParser converts a webhook message of the given type to pogo events.
+// Unhandled types return errUnhandledType.
+type messageParser func(msgType string, raw json.RawMessage) (events []interface{}, err error)
+
+// errUnhandledType is returned by messageParsers for message types they don't know
+var errUnhandledType = errors.New("unhandled type")
+
+// otherType is the metrics label for unhandled message types, they come from the request body
+const otherType = "other"
+
+// WebhookSource is an ingest.Source fed by a scanner's webhook
+type WebhookSource struct {
+	ingest.Counter
+
+	name  string
+	parse messageParser
+
+	// Recorder gets every accepted batch if set, see silpht replay
+	Recorder *ingest.Recorder
+
+	mu       sync.Mutex
+	sink     *ingest.Sink // only set while running
+	done     chan bool
+	stopOnce sync.Once
+}
+
+// NewMADSource creates the source for MAD's webhook at /webhook/mad
+func NewMADSource() *WebhookSource {
+	return newWebhookSource("mad", parseMADMessage)
+}
+
+// NewRDMSource creates the source for RealDeviceMap's webhook at /webhook/rdm
+func NewRDMSource() *WebhookSource {
+	return newWebhookSource("rdm", parseRDMMessage)
+}
+
+func newWebhookSource(name string, parse messageParser) *WebhookSource {
+	return &WebhookSource{
+		name:  name,
+		parse: parse,
+		done:  make(chan bool),
+	}
+}
+
+// Name returns the path element of the webhook
+func (s *WebhookSource) Name() string {
+	return s.name
+}
+
+// Run accepts webhook requests until Stop is called. Requests are rejected while not running.
+func (s *WebhookSource) Run(sink *ingest.Sink) error {
+	s.mu.Lock()
+	s.sink = sink
+	s.mu.Unlock()
+
+	<-s.done
+
+	s.mu.Lock()
+	s.sink = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// Stop makes Run return
+func (s *WebhookSource) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *WebhookSource) getSink() *ingest.Sink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sink
+}
+
+func (s *WebhookSource) handle(c echo.Context) error {
+	sink := s.getSink()
+	if sink == nil {
+		return c.String(http.StatusServiceUnavailable, "FAIL\n")
+	}
+
+	body, err := ioutil.ReadAll(c.Request().Body)
+	var envelopes []Envelope
+	if err == nil {
+		err = json.Unmarshal(body, &envelopes)
+	}
+	if err != nil {
+		log.Warnln("can't decode request to:", c.Request().URL, "error:", err)
+		s.CountError()
+		return c.String(http.StatusBadRequest, "FAIL\n")
+	}
+
+	metrics.WebhookLastData.WithLabelValues(s.name).SetToCurrentTime()
+
+	if s.Recorder != nil {
+		if err := s.Recorder.Write(s.name, body, time.Now()); err != nil {
+			log.WithError(err).Warnf("%s: recording failed", s.name)
+		}
+	}
+
+	emitEnvelopes(&s.Counter, s.name, s.parse, sink, envelopes, 0)
+	return c.String(http.StatusOK, "OK\n")
+}
+
+// emitEnvelopes parses the messages of a batch and emits their events, shifted by offset seconds
+func emitEnvelopes(c *ingest.Counter, name string, parse messageParser, sink *ingest.Sink, envelopes []Envelope, offset int64) {
+	for _, msg := range envelopes {
+		events, err := parse(msg.Type, msg.Message)
+		if err == errUnhandledType {
+			log.Debugln("unhandled type", msg.Type)
+			metrics.WebhookEnvelopes.WithLabelValues(name, otherType, metrics.OutcomeIgnored).Inc()
+			continue
+		} else if err != nil {
+			log.Warn("can't decode message as type ", msg.Type, ": ", err)
+			c.CountError()
+			metrics.WebhookEnvelopes.WithLabelValues(name, msg.Type, metrics.OutcomeError).Inc()
+			continue
+		}
+
+		outcome := metrics.OutcomeIgnored
+		for _, event := range events {
+			if offset != 0 {
+				event = ingest.ShiftEvent(event, offset)
+			}
+			if err := c.Emit(sink, event); err != nil {
+				log.WithError(err).Debugf("%s: dropped %T", name, event)
+				outcome = metrics.OutcomeDropped
+			} else if outcome == metrics.OutcomeIgnored {
+				outcome = metrics.OutcomeOK
+			}
+		}
+		metrics.WebhookEnvelopes.WithLabelValues(name, msg.Type, outcome).Inc()
+	}
+}
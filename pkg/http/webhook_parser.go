@@ -1,24 +1,102 @@
 package http
 
 import (
-	"fmt"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 )
 
-func sendGymUpdate(msg *GymMessage) {
+// parseMADMessage is the messageParser for MAD webhooks
+func parseMADMessage(msgType string, raw json.RawMessage) (events []interface{}, err error) {
+	var dst interface{}
+	switch msgType {
+	case "gym":
+		dst = new(GymMessage)
+	case "pokemon":
+		dst = new(PokemonMessage)
+	case "raid":
+		dst = new(RaidMessage)
+	case "pokestop":
+		dst = new(PokestopMessage)
+	case "quest":
+		dst = new(QuestMessage)
+	case "weather":
+		dst = new(WeatherMessage)
+	default:
+		return nil, errUnhandledType
+	}
+
+	err = json.Unmarshal(raw, dst)
+	if err != nil {
+		return
+	}
+
+	switch msgType {
+	case "gym":
+		msg := dst.(*GymMessage)
+		events = append(events, convertGym(msg),
+			newFort(msg.GymID, msg.Name, msg.URL, msg.Location, true))
+	case "pokemon":
+		events = append(events, convertSpawn(dst.(*PokemonMessage)))
+	case "raid":
+		msg := dst.(*RaidMessage)
+		events = append(events, convertRaid(msg),
+			newFort(msg.GymID, msg.Name, msg.URL, msg.Location, true))
+	case "pokestop":
+		msg := dst.(*PokestopMessage)
+		events = append(convertPokestop(msg),
+			newFort(msg.PokestopID, msg.Name, msg.URL, msg.Location, false))
+	case "quest":
+		events = append(events, convertQuest(dst.(*QuestMessage)))
+	case "weather":
+		events = append(events, convertWeather(dst.(*WeatherMessage)))
+	}
+	return
+}
+
+// fortName strips the placeholder scanners send for forts they haven't seen up close,
+// MAD's "unknown" and RDM's "Unknown". An empty name never replaces a real one in the GeoDex.
+func fortName(name string) string {
+	if strings.EqualFold(name, "unknown") {
+		return ""
+	}
+	return name
+}
+
+// newFort returns the fort info that comes with gym, raid and pokestop messages
+func newFort(guid, name, url string, l Location, isGym bool) pogo.Fort {
+	return pogo.Fort{
+		GUID: guid,
+		Name: fortName(name),
+		URL:  url,
+		Location: pogo.Location{
+			Latitude:  float64(l.Latitude),
+			Longitude: float64(l.Longitude),
+		},
+		IsGym: isGym,
+	}
+}
+
+func convertGym(msg *GymMessage) pogo.Gym {
 	m := pogo.Gym{
 		GUID:      msg.GymID,
+		Name:      fortName(msg.Name),
 		TeamColor: pogo.ToTeamColor(msg.TeamID),
 		Location: pogo.Location{
 			Latitude:  float64(msg.Latitude),
 			Longitude: float64(msg.Longitude),
 		},
+		SlotsAvailable: msg.SlotsAvailable,
+		ExRaidEligible: msg.IsExRaidEligible != 0,
+		UpdateTime:     time.Now().Unix(), // MAD doesn't supply that info
 	}
-	GymUpdates <- m
+	return m
 }
 
-func sendSpawnUpdate(msg *PokemonMessage) {
+func convertSpawn(msg *PokemonMessage) pogo.Spawn {
 	m := pogo.Spawn{
 		EncounterID:        msg.EncounterID.String(),
 		VerifiedSpawnpoint: msg.KnownDisappearTime,
@@ -35,11 +113,45 @@ func sendSpawnUpdate(msg *PokemonMessage) {
 			Latitude:  float64(msg.Latitude),
 			Longitude: float64(msg.Longitude),
 		},
+		Encounter:      getEncounter(&msg.EncounterDetails),
+		BoostedWeather: pogo.ToWeatherCondition(msg.BoostedWeather),
 	}
-	SpawnUpdates <- m
+	return m
 }
 
-func sendRaidUpdate(msg *RaidMessage) {
+// getEncounter returns the encounter details if the message has IVs, otherwise nil
+func getEncounter(msg *EncounterDetails) *pogo.Encounter {
+	if msg.IndividualAttack == nil || msg.IndividualDefense == nil || msg.IndividualStamina == nil {
+		return nil
+	}
+
+	intOrZero := func(v *int) int {
+		if v == nil {
+			return 0
+		}
+		return *v
+	}
+	floatOrZero := func(v *float64) float64 {
+		if v == nil {
+			return 0
+		}
+		return *v
+	}
+
+	return &pogo.Encounter{
+		Attack:  *msg.IndividualAttack,
+		Defense: *msg.IndividualDefense,
+		Stamina: *msg.IndividualStamina,
+		CP:      intOrZero(msg.CP),
+		Level:   intOrZero(msg.PokemonLevel),
+		Move1:   intOrZero(msg.Move1),
+		Move2:   intOrZero(msg.Move2),
+		Weight:  floatOrZero(msg.Weight),
+		Height:  floatOrZero(msg.Height),
+	}
+}
+
+func convertRaid(msg *RaidMessage) pogo.Raid {
 	var mon *pogo.Pokemon = nil
 	if msg.PokemonID != 0 {
 		mon = &pogo.Pokemon{
@@ -50,7 +162,7 @@ func sendRaidUpdate(msg *RaidMessage) {
 	}
 
 	m := pogo.Raid{
-		Hash:  fmt.Sprintf("%s:%d", msg.GymID, msg.StartTimestamp),
+		Hash:  pogo.RaidHash(msg.GymID, int64(msg.StartTimestamp)),
 		GymID: msg.GymID,
 		Location: pogo.Location{
 			Latitude:  float64(msg.Latitude),
@@ -63,5 +175,97 @@ func sendRaidUpdate(msg *RaidMessage) {
 			EndTime:   int64(msg.EndTimestamp),
 		},
 	}
-	RaidUpdates <- m
+	return m
+}
+
+func convertWeather(msg *WeatherMessage) pogo.Weather {
+	location := pogo.Location{
+		Latitude:  float64(msg.Latitude),
+		Longitude: float64(msg.Longitude),
+	}
+
+	// the cell id might have been mangled by a JSON library using float64 numbers,
+	// the cell center is good enough to get it back
+	cellID, err := strconv.ParseUint(msg.S2CellID, 10, 64)
+	if err != nil || !pogo.IsWeatherCellID(cellID) {
+		cellID = pogo.WeatherCellID(&location)
+	}
+
+	m := pogo.Weather{
+		CellID:        cellID,
+		Condition:     pogo.ToWeatherCondition(msg.Condition),
+		AlertSeverity: pogo.ToAlertSeverity(msg.AlertSeverity),
+		Location:      location,
+		UpdateTime:    msg.TimeChanged,
+	}
+	return m
+}
+
+// convertPokestop returns the invasion and lure at the pokestop, if any
+func convertPokestop(msg *PokestopMessage) (events []interface{}) {
+	location := pogo.Location{
+		Latitude:  float64(msg.Latitude),
+		Longitude: float64(msg.Longitude),
+	}
+	name := fortName(msg.Name)
+
+	if msg.IncidentExpiration != 0 && msg.IncidentGruntType != 0 {
+		m := pogo.Invasion{
+			Hash:         pogo.InvasionHash(msg.PokestopID, msg.IncidentStart),
+			PokestopID:   msg.PokestopID,
+			PokestopName: name,
+			Location:     location,
+			GruntType:    pogo.GruntType(msg.IncidentGruntType),
+			TimestampRange: pogo.TimestampRange{
+				StartTime: msg.IncidentStart,
+				EndTime:   msg.IncidentExpiration,
+			},
+		}
+		events = append(events, m)
+	}
+
+	lureType := pogo.LureType(msg.ActiveFortModifier)
+	if msg.LureExpiration != 0 && lureType.IsValid() {
+		m := pogo.Lure{
+			Hash:         pogo.LureHash(msg.PokestopID, msg.LureExpiration),
+			PokestopID:   msg.PokestopID,
+			PokestopName: name,
+			Location:     location,
+			LureType:     lureType,
+			TimestampRange: pogo.TimestampRange{
+				StartTime: msg.LureExpiration - pogo.LureDuration,
+				EndTime:   msg.LureExpiration,
+			},
+		}
+		events = append(events, m)
+	}
+	return
+}
+
+func convertQuest(msg *QuestMessage) pogo.Quest {
+	rewardType := pogo.QuestRewardType(msg.QuestRewardTypeRaw)
+	expiry := pogo.QuestExpiry(time.Unix(msg.Timestamp, 0))
+
+	m := pogo.Quest{
+		PokestopID:   msg.PokestopID,
+		PokestopName: fortName(msg.Name),
+		Location: pogo.Location{
+			Latitude:  float64(msg.Latitude),
+			Longitude: float64(msg.Longitude),
+		},
+		Task:       msg.QuestTask,
+		RewardType: rewardType,
+		Amount:     msg.ItemAmount,
+		TimestampRange: pogo.TimestampRange{
+			StartTime: msg.Timestamp,
+			EndTime:   expiry.Unix(),
+		},
+	}
+	if rewardType.HasPokemon() {
+		m.PokemonID = msg.PokemonID
+	} else if rewardType == pogo.QuestRewardItem {
+		m.ItemID = msg.ItemID
+	}
+	m.Hash = pogo.QuestHash(msg.PokestopID, rewardType, m.RewardID(), expiry)
+	return m
 }
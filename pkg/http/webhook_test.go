@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSourceLifecycle(t *testing.T) {
+	src := NewMADSource()
+	assert.Equal(t, "mad", src.Name())
+
+	// not running yet
+	c, rec := testMadWebhookRequest(`[]`)
+	if assert.NoError(t, src.handle(c)) {
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+
+	sink := newTestSink()
+	done := make(chan bool)
+	go func() {
+		assert.NoError(t, src.Run(sink))
+		done <- true
+	}()
+	for src.getSink() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	c, rec = testMadWebhookRequest(readTestFile("mad-webhook-all-types.json"))
+	if assert.NoError(t, src.handle(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	src.Stop()
+	<-done
+	src.Stop() // twice is fine
+
+	c, rec = testMadWebhookRequest(`[]`)
+	if assert.NoError(t, src.handle(c)) {
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestWebhookSourceCounters(t *testing.T) {
+	sink := newTestSink()
+	src := startTestSource(NewMADSource(), sink)
+
+	c, _ := testMadWebhookRequest(readTestFile("mad-webhook-all-types.json"))
+	assert.NoError(t, src.handle(c))
+	c, _ = testMadWebhookRequest(`foo`)
+	assert.NoError(t, src.handle(c))
+
+	counters := src.Counters()
+	assert.Equal(t, uint64(len(sink.Spawns)), counters.Events[ingest.EventSpawn])
+	assert.Equal(t, uint64(1), counters.Events[ingest.EventGym])
+	assert.Equal(t, uint64(3), counters.Events[ingest.EventQuest])
+	assert.Equal(t, uint64(1), counters.Errors)
+
+	// events without receiver are counted as errors
+	sink.Lures = nil
+	c, _ = testMadWebhookRequest(readTestFile("mad-webhook-all-types.json"))
+	assert.NoError(t, src.handle(c))
+	assert.Equal(t, uint64(2), src.Counters().Errors)
+}
+
+func TestMetricsRoute(t *testing.T) {
+	Auth = WebhookAuth{}
+	sink := newTestSink()
+
+	rec := serveWebhook(sink, "/webhook/mad", readTestFile("mad-webhook-all-types.json"), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `silpht_webhook_envelopes_total{outcome="ok",source="mad",type="gym"}`)
+	assert.Contains(t, rec.Body.String(), `silpht_webhook_last_data_timestamp_seconds{source="mad"}`)
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+//go:embed webui
+var webUIFiles embed.FS
+
+const (
+	// login links can be used once within this time
+	webLoginTTL = 10 * time.Minute
+	// sessions end after this time, the user needs a new link then
+	webSessionTTL = 8 * time.Hour
+)
+
+// WebSession is returned after logging in with a login link, it's scoped to a single room
+type WebSession struct {
+	Token   string
+	RoomID  string
+	UserID  string
+	Expires time.Time
+}
+
+type webLoginRequest struct {
+	Token string
+}
+
+// WebUI serves the filter editor at /ui and hands out one-time login links for it
+type WebUI struct {
+	// BaseURL is where users reach the http server, e.g. "https://silpht.example.com"
+	BaseURL string
+
+	api *adminAPI
+	dex *pogo.Pokedex
+
+	mu       sync.Mutex
+	logins   map[string]WebSession // one-time login tokens
+	sessions map[string]WebSession
+	now      func() time.Time
+}
+
+// AddWebUI serves the filter editor and its room-scoped API at /ui. Call Init() first.
+func AddWebUI(rooms RoomConfigEditor, dex *pogo.Pokedex, baseURL string) (w *WebUI) {
+	w = &WebUI{
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		api:      &adminAPI{rooms: rooms},
+		dex:      dex,
+		logins:   make(map[string]WebSession),
+		sessions: make(map[string]WebSession),
+		now:      time.Now,
+	}
+
+	files, err := fs.Sub(webUIFiles, "webui")
+	if err != nil {
+		log.WithError(err).Error("web UI files missing")
+		return
+	}
+	e.GET("/ui", func(c echo.Context) error {
+		return c.Redirect(http.StatusMovedPermanently, "/ui/")
+	})
+	e.GET("/ui/*", echo.WrapHandler(http.StripPrefix("/ui/", http.FileServer(http.FS(files)))))
+	e.POST("/ui/api/login", w.login)
+
+	// the same handlers as the admin API, limited to the session's room
+	g := e.Group("/ui/api", w.auth)
+	g.GET("/pokedex", w.pokedex)
+	g.GET("/rooms/:room", w.api.getRoom)
+	g.GET("/rooms/:room/filters", w.api.listFilters)
+	g.POST("/rooms/:room/filters", w.api.appendFilter)
+	g.GET("/rooms/:room/filters/:index", w.api.getFilter)
+	g.PUT("/rooms/:room/filters/:index", w.api.replaceFilter)
+	g.DELETE("/rooms/:room/filters/:index", w.api.removeFilter)
+	return
+}
+
+// CreateLoginLink returns a link that logs the user in to edit the room's filters.
+// It's valid once and only for a few minutes because it's posted in the room.
+func (w *WebUI) CreateLoginLink(roomID, userID string) (link string, err error) {
+	token, err := newWebToken()
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+	w.logins[token] = WebSession{
+		RoomID:  roomID,
+		UserID:  userID,
+		Expires: w.now().Add(webLoginTTL),
+	}
+
+	// the fragment isn't sent to the server, so it doesn't end up in access logs
+	link = w.BaseURL + "/ui/#login=" + token
+	return
+}
+
+// expire removes expired logins and sessions, needs w.mu
+func (w *WebUI) expire() {
+	now := w.now()
+	for token, login := range w.logins {
+		if now.After(login.Expires) {
+			delete(w.logins, token)
+		}
+	}
+	for token, session := range w.sessions {
+		if now.After(session.Expires) {
+			delete(w.sessions, token)
+		}
+	}
+}
+
+func newWebToken() (token string, err error) {
+	buf := make([]byte, 16)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	token = hex.EncodeToString(buf)
+	return
+}
+
+// login exchanges a one-time login token for a session
+func (w *WebUI) login(c echo.Context) error {
+	req := &webLoginRequest{}
+	if err := c.Bind(req); err != nil {
+		return fail(c, http.StatusBadRequest, "invalid login request")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire()
+
+	login, ok := w.logins[req.Token]
+	if !ok || req.Token == "" {
+		log.Warnf("rejected web UI login from %s", c.Request().RemoteAddr)
+		return fail(c, http.StatusUnauthorized, "invalid or expired login link")
+	}
+	delete(w.logins, req.Token)
+
+	token, err := newWebToken()
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, "can't create session")
+	}
+	session := WebSession{
+		Token:   token,
+		RoomID:  login.RoomID,
+		UserID:  login.UserID,
+		Expires: w.now().Add(webSessionTTL),
+	}
+	w.sessions[token] = session
+	log.Infof("web UI: %s logged in for %s", session.UserID, session.RoomID)
+
+	return c.JSON(http.StatusOK, session)
+}
+
+// auth checks the session token and that only the session's room is accessed
+func (w *WebUI) auth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := strings.TrimPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+
+		w.mu.Lock()
+		w.expire()
+		session, ok := w.sessions[token]
+		w.mu.Unlock()
+
+		if !ok || token == "" {
+			return fail(c, http.StatusUnauthorized, "invalid or expired session")
+		}
+		if c.Param("room") != "" && getRoomParam(c) != session.RoomID {
+			log.Warnf("web UI: %s tried to access %s", session.UserID, getRoomParam(c))
+			return fail(c, http.StatusForbidden, "session is for another room")
+		}
+		return next(c)
+	}
+}
+
+// pokedex lists all species for the name picker
+func (w *WebUI) pokedex(c echo.Context) error {
+	if w.dex == nil {
+		return c.JSON(http.StatusOK, []pogo.PokedexEntry{})
+	}
+	return c.JSON(http.StatusOK, w.dex.Entries())
+}
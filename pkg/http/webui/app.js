@@ -0,0 +1,380 @@
+"use strict";
+
+// raid levels as used by the scanner, 6 is mega
+const raidLevels = [1, 2, 3, 4, 5, 6];
+const defaultRadiusM = 500;
+
+const state = {
+    session: null,
+    filters: [],
+    selected: -1,
+    names: {}, // pokedex ID -> name
+    ids: {}, // lowercase name or ID -> pokedex ID
+    layers: null,
+};
+
+const $ = (id) => document.getElementById(id);
+
+function setStatus(text, isError) {
+    $("status").textContent = text;
+    $("status").className = isError ? "error" : "";
+}
+
+function showLogin(text) {
+    sessionStorage.removeItem("session");
+    state.session = null;
+    if (text) {
+        $("login-message").textContent = text;
+    }
+    $("editor").hidden = true;
+    $("login").hidden = false;
+}
+
+async function request(method, path, body) {
+    const headers = { "Content-Type": "application/json" };
+    if (state.session) {
+        headers["Authorization"] = "Bearer " + state.session.Token;
+    }
+    const resp = await fetch("api/" + path, {
+        method: method,
+        headers: headers,
+        body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    if (resp.status === 401) {
+        showLogin("Your session expired. Send web in your room to get a new login link.");
+        throw new Error("session expired");
+    }
+    if (resp.status === 204) {
+        return null;
+    }
+    const data = await resp.json();
+    if (!resp.ok) {
+        throw new Error(data.Error || resp.statusText);
+    }
+    return data;
+}
+
+function roomPath(suffix) {
+    return "rooms/" + encodeURIComponent(state.session.RoomID) + suffix;
+}
+
+// login with the token from a login link or resume the session of this tab
+async function login() {
+    const match = location.hash.match(/login=([0-9a-f]+)/);
+    if (match) {
+        history.replaceState(null, "", location.pathname);
+        try {
+            state.session = await request("POST", "login", { Token: match[1] });
+        } catch (err) {
+            showLogin("This login link is invalid or was already used. Send web in your room to get a new one.");
+            return false;
+        }
+        sessionStorage.setItem("session", JSON.stringify(state.session));
+        return true;
+    }
+
+    const stored = sessionStorage.getItem("session");
+    if (stored) {
+        state.session = JSON.parse(stored);
+        if (new Date(state.session.Expires) > new Date()) {
+            return true;
+        }
+    }
+    showLogin();
+    return false;
+}
+
+async function loadPokedex() {
+    const entries = await request("GET", "pokedex");
+    const options = document.createDocumentFragment();
+    for (const entry of entries) {
+        state.names[entry.ID] = entry.NameEN;
+        state.ids[String(entry.ID)] = entry.ID;
+        for (const name of [entry.NameEN, entry.NameDE]) {
+            if (!name) {
+                continue;
+            }
+            state.ids[name.toLowerCase()] = entry.ID;
+            const option = document.createElement("option");
+            option.value = name;
+            options.appendChild(option);
+        }
+    }
+    $("species").appendChild(options);
+}
+
+function speciesName(id) {
+    return state.names[id] || "#" + id;
+}
+
+function filterTitle(index) {
+    const f = state.filters[index];
+    return "#" + index + " " + (f.ListRaids ? "Raids" : "Spawns");
+}
+
+function filterSummary(f) {
+    const species = (f.PokemonIDs || []).map(speciesName);
+    let list = species.slice(0, 5).join(", ");
+    if (species.length > 5) {
+        list += " and " + (species.length - 5) + " more";
+    }
+    let text;
+    if (f.ListWanted) {
+        text = species.length ? "only " + list : "no species selected";
+    } else {
+        text = species.length ? "all except " + list : "all species";
+    }
+    if (f.ListRaids && f.RaidLevels && f.RaidLevels.length) {
+        text += ", eggs " + f.RaidLevels.map((l) => (l === 6 ? "mega" : l)).join(",");
+    }
+    return text + ", " + Math.round(f.Area.RadiusM) + " m";
+}
+
+async function loadFilters() {
+    state.filters = (await request("GET", roomPath("/filters"))).map((r) => r.Filter);
+    if (state.selected >= state.filters.length) {
+        state.selected = -1;
+    }
+    render();
+}
+
+async function saveFilter(index) {
+    try {
+        await request("PUT", roomPath("/filters/" + index), state.filters[index]);
+        setStatus("Saved " + filterTitle(index) + ".");
+    } catch (err) {
+        setStatus(err.message, true);
+    }
+    await loadFilters();
+}
+
+async function addFilter(listRaids) {
+    const center = map.getCenter();
+    const f = {
+        Area: { Latitude: center.lat, Longitude: center.lng, RadiusM: defaultRadiusM },
+        ListRaids: listRaids,
+        ListWanted: listRaids,
+        PokemonIDs: [],
+        RaidLevels: listRaids ? [5, 6] : [],
+    };
+    try {
+        const created = await request("POST", roomPath("/filters"), f);
+        state.selected = created.Index;
+        setStatus("Added " + (listRaids ? "raid" : "spawn") + " filter.");
+    } catch (err) {
+        setStatus(err.message, true);
+    }
+    await loadFilters();
+}
+
+async function deleteFilter(index) {
+    if (!confirm("Delete " + filterTitle(index) + "?")) {
+        return;
+    }
+    try {
+        await request("DELETE", roomPath("/filters/" + index));
+        state.selected = -1;
+        setStatus("Deleted filter, the numbers of the following filters changed.");
+    } catch (err) {
+        setStatus(err.message, true);
+    }
+    await loadFilters();
+}
+
+// point on the circle's edge east of the center
+function edgeOf(center, radiusM) {
+    const metersPerDegree = 111320 * Math.cos((center.lat * Math.PI) / 180);
+    return L.latLng(center.lat, center.lng + radiusM / metersPerDegree);
+}
+
+function drawFilter(index) {
+    const f = state.filters[index];
+    const center = L.latLng(f.Area.Latitude, f.Area.Longitude);
+    const selected = index === state.selected;
+    const circle = L.circle(center, {
+        radius: f.Area.RadiusM,
+        color: f.ListRaids ? "#e0303a" : "#3388ff",
+        weight: selected ? 4 : 2,
+    }).addTo(state.layers);
+    circle.bindTooltip(filterTitle(index));
+    circle.on("click", () => select(index));
+
+    if (!selected) {
+        return;
+    }
+    const centerMarker = L.marker(center, { draggable: true }).addTo(state.layers);
+    const edgeHandle = L.marker(edgeOf(center, f.Area.RadiusM), { draggable: true, opacity: 0.6 }).addTo(state.layers);
+
+    centerMarker.on("drag", (ev) => {
+        circle.setLatLng(ev.latlng);
+        edgeHandle.setLatLng(edgeOf(ev.latlng, circle.getRadius()));
+    });
+    centerMarker.on("dragend", () => {
+        const pos = centerMarker.getLatLng();
+        f.Area.Latitude = pos.lat;
+        f.Area.Longitude = pos.lng;
+        saveFilter(index);
+    });
+    edgeHandle.on("drag", (ev) => {
+        circle.setRadius(circle.getLatLng().distanceTo(ev.latlng));
+    });
+    edgeHandle.on("dragend", () => {
+        f.Area.RadiusM = Math.round(circle.getRadius());
+        saveFilter(index);
+    });
+}
+
+function renderList() {
+    const list = $("filters");
+    list.textContent = "";
+    state.filters.forEach((f, index) => {
+        const item = document.createElement("li");
+        item.className = (f.ListRaids ? "raid" : "spawn") + (index === state.selected ? " selected" : "");
+        const title = document.createElement("strong");
+        title.textContent = filterTitle(index);
+        item.appendChild(title);
+        item.appendChild(document.createElement("br"));
+        item.appendChild(document.createTextNode(filterSummary(f)));
+        item.addEventListener("click", () => select(index, true));
+        list.appendChild(item);
+    });
+    if (!state.filters.length) {
+        const item = document.createElement("li");
+        item.textContent = "This room has no spawn or raid filters yet.";
+        list.appendChild(item);
+    }
+}
+
+function renderEditor() {
+    const form = $("filter");
+    const f = state.filters[state.selected];
+    form.hidden = !f;
+    if (!f) {
+        return;
+    }
+
+    $("filter-title").textContent = filterTitle(state.selected);
+    $("filter-wanted").checked = f.ListWanted;
+
+    const species = $("filter-species");
+    species.textContent = "";
+    for (const id of f.PokemonIDs || []) {
+        const tag = document.createElement("li");
+        tag.textContent = speciesName(id);
+        const remove = document.createElement("button");
+        remove.type = "button";
+        remove.textContent = "×";
+        remove.title = "Remove";
+        remove.addEventListener("click", () => {
+            f.PokemonIDs = f.PokemonIDs.filter((other) => other !== id);
+            renderEditor();
+        });
+        tag.appendChild(remove);
+        species.appendChild(tag);
+    }
+
+    const levels = $("filter-levels");
+    levels.hidden = !f.ListRaids;
+    levels.querySelectorAll("label").forEach((label) => label.remove());
+    for (const level of raidLevels) {
+        const label = document.createElement("label");
+        const box = document.createElement("input");
+        box.type = "checkbox";
+        box.checked = (f.RaidLevels || []).includes(level);
+        box.addEventListener("change", () => {
+            const other = (f.RaidLevels || []).filter((l) => l !== level);
+            f.RaidLevels = box.checked ? other.concat(level).sort((a, b) => a - b) : other;
+        });
+        label.appendChild(box);
+        label.appendChild(document.createTextNode(level === 6 ? " mega " : " " + level + " "));
+        levels.appendChild(label);
+    }
+}
+
+function render() {
+    state.layers.clearLayers();
+    state.filters.forEach((_, index) => drawFilter(index));
+    renderList();
+    renderEditor();
+}
+
+function select(index, pan) {
+    state.selected = index;
+    render();
+    const f = state.filters[index];
+    if (pan && f) {
+        map.fitBounds(L.latLng(f.Area.Latitude, f.Area.Longitude).toBounds(f.Area.RadiusM * 2.5));
+    }
+}
+
+function addSpecies() {
+    const f = state.filters[state.selected];
+    const input = $("species-name");
+    const id = state.ids[input.value.trim().toLowerCase()];
+    if (!id) {
+        setStatus("Unknown species: " + input.value, true);
+        return;
+    }
+    f.PokemonIDs = f.PokemonIDs || [];
+    if (!f.PokemonIDs.includes(id)) {
+        f.PokemonIDs.push(id);
+    }
+    input.value = "";
+    setStatus("");
+    renderEditor();
+}
+
+function fitAll() {
+    if (!state.filters.length) {
+        map.setView([51.1657, 10.4515], 6);
+        return;
+    }
+    let bounds = null;
+    for (const f of state.filters) {
+        const b = L.latLng(f.Area.Latitude, f.Area.Longitude).toBounds(f.Area.RadiusM * 2);
+        bounds = bounds ? bounds.extend(b) : b;
+    }
+    map.fitBounds(bounds);
+}
+
+const map = L.map("map");
+L.tileLayer("https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png", {
+    maxZoom: 19,
+    attribution: '&copy; <a href="https://www.openstreetmap.org/copyright">OpenStreetMap</a> contributors',
+}).addTo(map);
+state.layers = L.layerGroup().addTo(map);
+
+$("add-spawn").addEventListener("click", () => addFilter(false));
+$("add-raid").addEventListener("click", () => addFilter(true));
+$("species-add").addEventListener("click", addSpecies);
+$("species-name").addEventListener("keydown", (ev) => {
+    if (ev.key === "Enter") {
+        ev.preventDefault();
+        addSpecies();
+    }
+});
+$("filter-wanted").addEventListener("change", (ev) => {
+    state.filters[state.selected].ListWanted = ev.target.checked;
+});
+$("filter-delete").addEventListener("click", () => deleteFilter(state.selected));
+$("filter").addEventListener("submit", (ev) => {
+    ev.preventDefault();
+    saveFilter(state.selected);
+});
+
+(async () => {
+    if (!(await login())) {
+        return;
+    }
+    $("room").textContent = state.session.RoomID;
+    $("login").hidden = true;
+    $("editor").hidden = false;
+    map.invalidateSize();
+    try {
+        await loadPokedex();
+        await loadFilters();
+        fitAll();
+    } catch (err) {
+        setStatus(err.message, true);
+    }
+})();
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/spezifisch/silphtelescope/pkg/roomservice"
+)
+
+func serveWebUI(method, path, body, session string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if session != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+session)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func webLogin(t *testing.T, link string) (rec *httptest.ResponseRecorder, session *WebSession) {
+	parts := strings.SplitN(link, "#login=", 2)
+	if !assert.Equal(t, 2, len(parts)) {
+		return
+	}
+	rec = serveWebUI(http.MethodPost, "/ui/api/login", `{"Token": "`+parts[1]+`"}`, "")
+	if rec.Code == http.StatusOK {
+		session = &WebSession{}
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), session))
+	}
+	return
+}
+
+func TestWebUIFiles(t *testing.T) {
+	Init()
+	AddWebUI(roomservice.NewPoster(nil, nil), nil, "https://silpht.example.com")
+
+	rec := serveWebUI(http.MethodGet, "/ui", "", "")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	rec = serveWebUI(http.MethodGet, "/ui/", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<title>SilphTelescope Filters</title>")
+	rec = serveWebUI(http.MethodGet, "/ui/app.js", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serveWebUI(http.MethodGet, "/ui/missing.js", "", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWebUILogin(t *testing.T) {
+	Init()
+	now := time.Unix(1613492380, 0)
+	w := AddWebUI(roomservice.NewPoster(nil, nil), nil, "https://silpht.example.com/")
+	w.now = func() time.Time { return now }
+
+	link, err := w.CreateLoginLink("!foo:example.com", "@mod:example.com")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(link, "https://silpht.example.com/ui/#login="), link)
+
+	rec, session := webLogin(t, link)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, session) {
+		assert.Equal(t, "!foo:example.com", session.RoomID)
+		assert.Equal(t, "@mod:example.com", session.UserID)
+		assert.Equal(t, now.Add(webSessionTTL).Unix(), session.Expires.Unix())
+		assert.NotEmpty(t, session.Token)
+	}
+
+	// links work only once
+	rec, _ = webLogin(t, link)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serveWebUI(http.MethodPost, "/ui/api/login", `{"Token": ""}`, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// and only for a few minutes
+	link, _ = w.CreateLoginLink("!foo:example.com", "@mod:example.com")
+	now = now.Add(webLoginTTL + time.Second)
+	rec, _ = webLogin(t, link)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// sessions expire too
+	now = now.Add(webSessionTTL)
+	rec = serveWebUI(http.MethodGet, "/ui/api/pokedex", "", session.Token)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestWebUIRoomAccess(t *testing.T) {
+	Init()
+	p := roomservice.NewPoster(nil, nil)
+	for _, roomID := range []string{"!foo:example.com", "!bar:example.com"} {
+		p.UpdateRoomConfig(&roomservice.RoomConfig{
+			RoomID: roomID,
+			Filter: []roomservice.PokemonFilter{{Area: pogo.NewLocationRadius(52.5, 13.4, 1000)}},
+		})
+	}
+	dex, err := pogo.NewPokedex("../../data/pokedex.json")
+	assert.Nil(t, err)
+	w := AddWebUI(p, dex, "https://silpht.example.com")
+
+	link, _ := w.CreateLoginLink("!foo:example.com", "@mod:example.com")
+	_, session := webLogin(t, link)
+	if !assert.NotNil(t, session) {
+		return
+	}
+
+	rec := serveWebUI(http.MethodGet, "/ui/api/rooms/%21foo:example.com/filters", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = serveWebUI(http.MethodGet, "/ui/api/rooms/%21foo:example.com/filters", "", session.Token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serveWebUI(http.MethodGet, "/ui/api/rooms/%21bar:example.com/filters", "", session.Token)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = serveWebUI(http.MethodDelete, "/ui/api/rooms/%21bar:example.com/filters/0", "", session.Token)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// moving the circle replaces the filter
+	rec = serveWebUI(http.MethodPut, "/ui/api/rooms/%21foo:example.com/filters/0",
+		`{"Area": {"Latitude": 52.6, "Longitude": 13.5, "RadiusM": 800}, "ListWanted": true, "PokemonIDs": [25]}`, session.Token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rc, _ := p.GetRoomConfig("!foo:example.com")
+	assert.Equal(t, pogo.NewLocationRadius(52.6, 13.5, 800), rc.Filter[0].Area)
+	assert.Equal(t, []int{25}, rc.Filter[0].PokemonIDs)
+	rc, _ = p.GetRoomConfig("!bar:example.com")
+	assert.Equal(t, 1000.0, rc.Filter[0].Area.RadiusM)
+
+	// species picker
+	rec = serveWebUI(http.MethodGet, "/ui/api/pokedex", "", session.Token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	entries := []pogo.PokedexEntry{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Equal(t, dex.Len(), len(entries))
+}
@@ -0,0 +1,255 @@
+// Package ingest connects data sources like scanner webhooks to the Poster.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// event types, used as counter keys
+const (
+	EventGym      = "gym"
+	EventSpawn    = "spawn"
+	EventRaid     = "raid"
+	EventWeather  = "weather"
+	EventInvasion = "invasion"
+	EventQuest    = "quest"
+	EventLure     = "lure"
+	EventFort     = "fort"
+)
+
+// eventTypes in the order used for status output
+var eventTypes = []string{EventSpawn, EventRaid, EventGym, EventWeather, EventInvasion, EventQuest, EventLure, EventFort}
+
+// ErrNoReceiver is returned by Sink.Send if nobody takes that event type
+var ErrNoReceiver = errors.New("no receiver for event type")
+
+// errStopped is returned by Sink.send if it gave up waiting for the receiver
+var errStopped = errors.New("stopped")
+
+// Source produces events, e.g. a scanner webhook or a recording
+type Source interface {
+	// Name identifies the source in logs and status output
+	Name() string
+	// Run emits events into the sink until Stop is called or the source is exhausted
+	Run(sink *Sink) error
+	// Stop makes Run return
+	Stop()
+	// Counters returns a snapshot of the source's statistics
+	Counters() Counters
+}
+
+// Sink receives events from all sources, usually these are the Poster's update channels.
+// Channels for optional event types may be nil.
+type Sink struct {
+	Gyms      chan pogo.Gym
+	Spawns    chan pogo.Spawn
+	Raids     chan pogo.Raid
+	Weather   chan pogo.Weather
+	Invasions chan pogo.Invasion
+	Quests    chan pogo.Quest
+	Lures     chan pogo.Lure
+	Forts     chan pogo.Fort
+
+	// set by Queue.Sink(), events only go into the queue then
+	queue *Queue
+}
+
+// Send puts the event into the matching channel and returns its type.
+// It blocks until the receiver takes the event, unless the sink belongs to a Queue.
+func (s *Sink) Send(event interface{}) (eventType string, err error) {
+	return s.send(event, nil)
+}
+
+// send is Send giving up with errStopped when done is closed
+func (s *Sink) send(event interface{}, done <-chan bool) (eventType string, err error) {
+	if s.queue != nil {
+		eventType, err = EventType(event)
+		if err == nil {
+			s.queue.push(eventType, event)
+		}
+		return
+	}
+
+	switch ev := event.(type) {
+	case pogo.Gym:
+		eventType = EventGym
+		if s.Gyms != nil {
+			select {
+			case s.Gyms <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Spawn:
+		eventType = EventSpawn
+		if s.Spawns != nil {
+			select {
+			case s.Spawns <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Raid:
+		eventType = EventRaid
+		if s.Raids != nil {
+			select {
+			case s.Raids <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Weather:
+		eventType = EventWeather
+		if s.Weather != nil {
+			select {
+			case s.Weather <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Invasion:
+		eventType = EventInvasion
+		if s.Invasions != nil {
+			select {
+			case s.Invasions <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Quest:
+		eventType = EventQuest
+		if s.Quests != nil {
+			select {
+			case s.Quests <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Lure:
+		eventType = EventLure
+		if s.Lures != nil {
+			select {
+			case s.Lures <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	case pogo.Fort:
+		eventType = EventFort
+		if s.Forts != nil {
+			select {
+			case s.Forts <- ev:
+			case <-done:
+				err = errStopped
+			}
+			return
+		}
+	default:
+		return "", fmt.Errorf("unknown event %T", event)
+	}
+	return eventType, ErrNoReceiver
+}
+
+// EventType returns the type of the event for counters
+func EventType(event interface{}) (eventType string, err error) {
+	switch event.(type) {
+	case pogo.Gym:
+		eventType = EventGym
+	case pogo.Spawn:
+		eventType = EventSpawn
+	case pogo.Raid:
+		eventType = EventRaid
+	case pogo.Weather:
+		eventType = EventWeather
+	case pogo.Invasion:
+		eventType = EventInvasion
+	case pogo.Quest:
+		eventType = EventQuest
+	case pogo.Lure:
+		eventType = EventLure
+	case pogo.Fort:
+		eventType = EventFort
+	default:
+		err = fmt.Errorf("unknown event %T", event)
+	}
+	return
+}
+
+// Counters are statistics of a source
+type Counters struct {
+	Events map[string]uint64 // sent events by type
+	Errors uint64            // messages that couldn't be decoded or sent
+}
+
+// ToString converts Counters into a human-readable string
+func (c Counters) ToString() string {
+	s := ""
+	for _, eventType := range eventTypes {
+		if n := c.Events[eventType]; n > 0 {
+			s += fmt.Sprintf("%d %s, ", n, eventType)
+		}
+	}
+	return fmt.Sprintf("%s%d errors", s, c.Errors)
+}
+
+// Counter is a thread-safe Counters, sources can embed it
+type Counter struct {
+	mu       sync.Mutex
+	counters Counters
+}
+
+// CountEvent counts a sent event
+func (c *Counter) CountEvent(eventType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counters.Events == nil {
+		c.counters.Events = make(map[string]uint64)
+	}
+	c.counters.Events[eventType]++
+}
+
+// CountError counts a message that couldn't be decoded or sent
+func (c *Counter) CountError() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters.Errors++
+}
+
+// Counters returns a snapshot
+func (c *Counter) Counters() Counters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := Counters{
+		Events: make(map[string]uint64, len(c.counters.Events)),
+		Errors: c.counters.Errors,
+	}
+	for eventType, n := range c.counters.Events {
+		snapshot.Events[eventType] = n
+	}
+	return snapshot
+}
+
+// Emit sends the event to the sink and counts it
+func (c *Counter) Emit(sink *Sink, event interface{}) error {
+	eventType, err := sink.Send(event)
+	if err != nil {
+		c.CountError()
+		return err
+	}
+	c.CountEvent(eventType)
+	return nil
+}
@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSinkSend(t *testing.T) {
+	sink := &Sink{
+		Gyms:   make(chan pogo.Gym, 1),
+		Spawns: make(chan pogo.Spawn, 1),
+	}
+
+	eventType, err := sink.Send(pogo.Gym{GUID: "foo"})
+	assert.Nil(t, err)
+	assert.Equal(t, EventGym, eventType)
+	assert.Equal(t, "foo", (<-sink.Gyms).GUID)
+
+	eventType, err = sink.Send(pogo.Spawn{EncounterID: "bar"})
+	assert.Nil(t, err)
+	assert.Equal(t, EventSpawn, eventType)
+	assert.Equal(t, "bar", (<-sink.Spawns).EncounterID)
+
+	// optional channel
+	eventType, err = sink.Send(pogo.Lure{})
+	assert.Equal(t, ErrNoReceiver, err)
+	assert.Equal(t, EventLure, eventType)
+
+	eventType, err = sink.Send(pogo.Fort{})
+	assert.Equal(t, ErrNoReceiver, err)
+	assert.Equal(t, EventFort, eventType)
+
+	_, err = sink.Send("foo")
+	assert.NotNil(t, err)
+}
+
+func TestCounter(t *testing.T) {
+	sink := &Sink{Raids: make(chan pogo.Raid, 2)}
+	c := &Counter{}
+
+	assert.Nil(t, c.Emit(sink, pogo.Raid{}))
+	assert.Nil(t, c.Emit(sink, pogo.Raid{}))
+	assert.NotNil(t, c.Emit(sink, pogo.Quest{}))
+	c.CountError()
+
+	counters := c.Counters()
+	assert.Equal(t, map[string]uint64{EventRaid: 2}, counters.Events)
+	assert.Equal(t, uint64(2), counters.Errors)
+	assert.Equal(t, "2 raid, 2 errors", counters.ToString())
+
+	// snapshots don't change
+	c.CountEvent(EventRaid)
+	assert.Equal(t, uint64(2), counters.Events[EventRaid])
+}
+
+type testSource struct {
+	Counter
+	name   string
+	events []interface{}
+	err    error
+	stop   chan bool
+}
+
+func (s *testSource) Name() string {
+	return s.name
+}
+
+func (s *testSource) Run(sink *Sink) error {
+	for _, event := range s.events {
+		s.Emit(sink, event)
+	}
+	if s.stop != nil {
+		<-s.stop
+	}
+	return s.err
+}
+
+func (s *testSource) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+	}
+}
+
+func TestManager(t *testing.T) {
+	sink := &Sink{Gyms: make(chan pogo.Gym, 10)}
+	m := NewManager(sink)
+
+	first := &testSource{name: "first", events: []interface{}{pogo.Gym{}, pogo.Gym{}}}
+	second := &testSource{name: "second", events: []interface{}{pogo.Gym{}}, stop: make(chan bool)}
+	failing := &testSource{name: "failing", err: errors.New("broken")}
+	m.Add(first)
+	m.Add(second)
+	m.Add(failing)
+
+	for i := 0; i < 3; i++ {
+		<-sink.Gyms
+	}
+
+	m.Stop()
+	status := m.Status()
+	if assert.Equal(t, 3, len(status)) {
+		assert.Equal(t, "first", status[0].Name)
+		assert.Equal(t, uint64(2), status[0].Counters.Events[EventGym])
+		assert.Equal(t, "second", status[1].Name)
+		assert.Equal(t, uint64(1), status[1].Counters.Events[EventGym])
+		assert.Equal(t, "failing", status[2].Name)
+		for _, s := range status {
+			assert.False(t, s.Running)
+		}
+	}
+}
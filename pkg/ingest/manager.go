@@ -0,0 +1,92 @@
+package ingest
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Manager runs several sources side by side, all emitting into the same sink
+type Manager struct {
+	sink *Sink
+
+	mu      sync.Mutex
+	sources []Source
+	running map[Source]bool
+	wg      sync.WaitGroup
+}
+
+// SourceStatus describes a source for status output
+type SourceStatus struct {
+	Name     string
+	Running  bool
+	Counters Counters
+}
+
+// NewManager creates a Manager for the given sink
+func NewManager(sink *Sink) *Manager {
+	return &Manager{
+		sink:    sink,
+		running: make(map[Source]bool),
+	}
+}
+
+// Add starts the source in the background
+func (m *Manager) Add(src Source) {
+	m.mu.Lock()
+	m.sources = append(m.sources, src)
+	m.running[src] = true
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		log.Infof("ingest source %s started", src.Name())
+		err := src.Run(m.sink)
+		if err != nil {
+			log.WithError(err).Errorf("ingest source %s failed", src.Name())
+		} else {
+			log.Infof("ingest source %s stopped", src.Name())
+		}
+
+		m.mu.Lock()
+		m.running[src] = false
+		m.mu.Unlock()
+	}()
+}
+
+// Status returns name, state and counters of all sources in the order they were added
+func (m *Manager) Status() (status []SourceStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, src := range m.sources {
+		status = append(status, SourceStatus{
+			Name:     src.Name(),
+			Running:  m.running[src],
+			Counters: src.Counters(),
+		})
+	}
+	return
+}
+
+// QueueStatus returns the state of the queue if the sink belongs to one
+func (m *Manager) QueueStatus() (status QueueStatus, ok bool) {
+	if m.sink.queue == nil {
+		return
+	}
+	return m.sink.queue.Status(), true
+}
+
+// Stop stops all sources and waits for them
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	sources := append([]Source{}, m.sources...)
+	m.mu.Unlock()
+
+	for _, src := range sources {
+		src.Stop()
+	}
+	m.wg.Wait()
+}
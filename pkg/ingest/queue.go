@@ -0,0 +1,206 @@
+package ingest
+
+import (
+	"fmt"
+	"sync"
+)
+
+// OverflowPolicy decides which event is dropped when the queue is full
+type OverflowPolicy int
+
+// overflow policies
+const (
+	// DropOldest makes room for the new event
+	DropOldest OverflowPolicy = iota
+	// DropNewest keeps the queue as it is
+	DropNewest
+	// PreferRaids drops the oldest event that isn't a raid or egg, then works like DropNewest for
+	// other events and like DropOldest for raids
+	PreferRaids
+)
+
+var overflowPolicyNames = []string{"drop-oldest", "drop-newest", "prefer-raids"}
+
+// ToString returns the name used in the config
+func (p OverflowPolicy) ToString() string {
+	if p < 0 || int(p) >= len(overflowPolicyNames) {
+		return fmt.Sprintf("policy #%d", p)
+	}
+	return overflowPolicyNames[p]
+}
+
+// ParseOverflowPolicy parses a name from ToString()
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	for i, name := range overflowPolicyNames {
+		if name == s {
+			return OverflowPolicy(i), nil
+		}
+	}
+	return DropOldest, fmt.Errorf("unknown overflow policy: %s", s)
+}
+
+type queuedEvent struct {
+	eventType string
+	event     interface{}
+}
+
+// Queue is a bounded event queue between the sources and a possibly slow sink.
+// Sources never block on it, events are dropped according to the policy when it's full.
+type Queue struct {
+	capacity int
+	policy   OverflowPolicy
+
+	mu      sync.Mutex
+	events  []queuedEvent
+	dropped map[string]uint64
+
+	notify   chan bool
+	done     chan bool
+	stopOnce sync.Once
+}
+
+// QueueStatus describes the queue for status output
+type QueueStatus struct {
+	Length   int
+	Capacity int
+	Policy   OverflowPolicy
+	Dropped  map[string]uint64 // by event type
+}
+
+// NewQueue creates a queue holding at most capacity events
+func NewQueue(capacity int, policy OverflowPolicy) *Queue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Queue{
+		capacity: capacity,
+		policy:   policy,
+		dropped:  make(map[string]uint64),
+		notify:   make(chan bool, 1),
+		done:     make(chan bool),
+	}
+}
+
+// Sink returns a sink for sources that puts all events into the queue
+func (q *Queue) Sink() *Sink {
+	return &Sink{queue: q}
+}
+
+func (q *Queue) push(eventType string, event interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.wakeUp()
+
+	e := queuedEvent{eventType, event}
+	if len(q.events) < q.capacity {
+		q.events = append(q.events, e)
+		return
+	}
+
+	switch q.policy {
+	case DropNewest:
+		q.dropped[eventType]++
+	case PreferRaids:
+		for i, queued := range q.events {
+			if queued.eventType != EventRaid {
+				q.drop(i)
+				q.events = append(q.events, e)
+				return
+			}
+		}
+		if eventType != EventRaid {
+			q.dropped[eventType]++
+			return
+		}
+		fallthrough
+	default:
+		q.drop(0)
+		q.events = append(q.events, e)
+	}
+}
+
+// drop removes the event at index i. q.mu must be held.
+func (q *Queue) drop(i int) {
+	q.dropped[q.events[i].eventType]++
+	q.events = append(q.events[:i], q.events[i+1:]...)
+}
+
+func (q *Queue) wakeUp() {
+	select {
+	case q.notify <- true:
+	default:
+	}
+}
+
+func (q *Queue) pop() (e queuedEvent, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.events) == 0 {
+		return
+	}
+	e = q.events[0]
+	q.events = q.events[1:]
+	return e, true
+}
+
+// Run forwards queued events to the sink until Stop is called
+func (q *Queue) Run(out *Sink) {
+	for {
+		e, ok := q.pop()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-q.done:
+				return
+			}
+		}
+
+		// this blocks while the receiver is busy, the queue fills up in the meantime
+		if _, err := out.send(e.event, q.done); err == errStopped {
+			return
+		} else if err != nil {
+			q.mu.Lock()
+			q.dropped[e.eventType]++
+			q.mu.Unlock()
+		}
+	}
+}
+
+// Stop makes Run return
+func (q *Queue) Stop() {
+	q.stopOnce.Do(func() {
+		close(q.done)
+	})
+}
+
+// Status returns a snapshot of the queue's state
+func (q *Queue) Status() QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := QueueStatus{
+		Length:   len(q.events),
+		Capacity: q.capacity,
+		Policy:   q.policy,
+		Dropped:  make(map[string]uint64, len(q.dropped)),
+	}
+	for eventType, n := range q.dropped {
+		status.Dropped[eventType] = n
+	}
+	return status
+}
+
+// ToString converts QueueStatus into a human-readable string
+func (s QueueStatus) ToString() string {
+	var total uint64
+	dropped := ""
+	for _, eventType := range eventTypes {
+		if n := s.Dropped[eventType]; n > 0 {
+			total += n
+			dropped += fmt.Sprintf(", %d %s", n, eventType)
+		}
+	}
+	return fmt.Sprintf("%d/%d events (%s), %d dropped%s", s.Length, s.Capacity, s.Policy.ToString(), total, dropped)
+}
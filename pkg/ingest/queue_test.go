@@ -0,0 +1,167 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/stretchr/testify/assert"
+)
+
+// queuedIDs returns the spawn encounter IDs and raid hashes in the queue
+func queuedIDs(q *Queue) (ids []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, e := range q.events {
+		switch ev := e.event.(type) {
+		case pogo.Spawn:
+			ids = append(ids, ev.EncounterID)
+		case pogo.Raid:
+			ids = append(ids, ev.Hash)
+		}
+	}
+	return
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, policy := range []OverflowPolicy{DropOldest, DropNewest, PreferRaids} {
+		parsed, err := ParseOverflowPolicy(policy.ToString())
+		assert.Nil(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+
+	_, err := ParseOverflowPolicy("drop-everything")
+	assert.NotNil(t, err)
+	assert.Equal(t, "policy #42", OverflowPolicy(42).ToString())
+}
+
+func TestQueueDropOldest(t *testing.T) {
+	q := NewQueue(2, DropOldest)
+	sink := q.Sink()
+
+	for _, id := range []string{"a", "b", "c"} {
+		eventType, err := sink.Send(pogo.Spawn{EncounterID: id})
+		assert.Nil(t, err)
+		assert.Equal(t, EventSpawn, eventType)
+	}
+	assert.Equal(t, []string{"b", "c"}, queuedIDs(q))
+
+	status := q.Status()
+	assert.Equal(t, 2, status.Length)
+	assert.Equal(t, map[string]uint64{EventSpawn: 1}, status.Dropped)
+	assert.Equal(t, "2/2 events (drop-oldest), 1 dropped, 1 spawn", status.ToString())
+
+	_, err := sink.Send("foo")
+	assert.NotNil(t, err)
+}
+
+func TestQueueDropNewest(t *testing.T) {
+	q := NewQueue(2, DropNewest)
+	sink := q.Sink()
+
+	for _, id := range []string{"a", "b", "c"} {
+		sink.Send(pogo.Spawn{EncounterID: id})
+	}
+	sink.Send(pogo.Raid{Hash: "r"})
+	assert.Equal(t, []string{"a", "b"}, queuedIDs(q))
+	assert.Equal(t, map[string]uint64{EventSpawn: 1, EventRaid: 1}, q.Status().Dropped)
+}
+
+func TestQueuePreferRaids(t *testing.T) {
+	q := NewQueue(3, PreferRaids)
+	sink := q.Sink()
+
+	sink.Send(pogo.Spawn{EncounterID: "a"})
+	sink.Send(pogo.Raid{Hash: "r1"})
+	sink.Send(pogo.Spawn{EncounterID: "b"})
+
+	// spawns make room for raids and new spawns
+	sink.Send(pogo.Raid{Hash: "r2"})
+	assert.Equal(t, []string{"r1", "b", "r2"}, queuedIDs(q))
+	sink.Send(pogo.Spawn{EncounterID: "c"})
+	assert.Equal(t, []string{"r1", "r2", "c"}, queuedIDs(q))
+	sink.Send(pogo.Raid{Hash: "r3"})
+	assert.Equal(t, []string{"r1", "r2", "r3"}, queuedIDs(q))
+
+	// only raids left
+	sink.Send(pogo.Spawn{EncounterID: "d"})
+	assert.Equal(t, []string{"r1", "r2", "r3"}, queuedIDs(q))
+	sink.Send(pogo.Raid{Hash: "r4"})
+	assert.Equal(t, []string{"r2", "r3", "r4"}, queuedIDs(q))
+
+	assert.Equal(t, map[string]uint64{EventSpawn: 4, EventRaid: 1}, q.Status().Dropped)
+}
+
+func TestQueueRun(t *testing.T) {
+	q := NewQueue(10, DropOldest)
+	out := &Sink{
+		Spawns: make(chan pogo.Spawn),
+		Raids:  make(chan pogo.Raid),
+	}
+
+	done := make(chan bool)
+	go func() {
+		q.Run(out)
+		done <- true
+	}()
+
+	// the receiver is slow, sending doesn't block anyway
+	sink := q.Sink()
+	sink.Send(pogo.Spawn{EncounterID: "a"})
+	sink.Send(pogo.Raid{Hash: "r"})
+	assert.Equal(t, "a", (<-out.Spawns).EncounterID)
+	assert.Equal(t, "r", (<-out.Raids).Hash)
+
+	// events arriving after the queue was drained
+	sink.Send(pogo.Spawn{EncounterID: "b"})
+	select {
+	case s := <-out.Spawns:
+		assert.Equal(t, "b", s.EncounterID)
+	case <-time.After(time.Second):
+		t.Error("queue didn't forward event")
+	}
+
+	q.Stop()
+	q.Stop()
+	<-done
+	assert.Equal(t, 0, q.Status().Length)
+}
+
+func TestQueueRunDrops(t *testing.T) {
+	q := NewQueue(10, DropOldest)
+	out := &Sink{Spawns: make(chan pogo.Spawn)}
+
+	done := make(chan bool)
+	go func() {
+		q.Run(out)
+		done <- true
+	}()
+
+	// nobody takes raids
+	sink := q.Sink()
+	sink.Send(pogo.Raid{Hash: "r"})
+	sink.Send(pogo.Spawn{EncounterID: "a"})
+	assert.Equal(t, "a", (<-out.Spawns).EncounterID)
+	assert.Equal(t, map[string]uint64{EventRaid: 1}, q.Status().Dropped)
+
+	// Stop ends Run even if the receiver doesn't take the event
+	sink.Send(pogo.Spawn{EncounterID: "b"})
+	q.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Run didn't return")
+	}
+}
+
+func TestManagerQueueStatus(t *testing.T) {
+	_, ok := NewManager(&Sink{}).QueueStatus()
+	assert.False(t, ok)
+
+	q := NewQueue(5, PreferRaids)
+	status, ok := NewManager(q.Sink()).QueueStatus()
+	assert.True(t, ok)
+	assert.Equal(t, 5, status.Capacity)
+	assert.Equal(t, PreferRaids, status.Policy)
+}
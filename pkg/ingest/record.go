@@ -0,0 +1,206 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// Record is one line of a recording: a raw webhook batch and when it was received
+type Record struct {
+	Time   int64           `json:"time"` // unix milliseconds
+	Source string          `json:"source"`
+	Batch  json.RawMessage `json:"batch"`
+}
+
+// Recorder appends records to a JSONL file. The file is rotated to path.1, path.2, ... when it gets bigger than MaxSize.
+type Recorder struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRecorder opens path for appending. maxSize <= 0 disables rotation, keep is the number of rotated files kept.
+func NewRecorder(path string, maxSize int64, keep int) (r *Recorder, err error) {
+	r = &Recorder{
+		path:    path,
+		maxSize: maxSize,
+		keep:    keep,
+	}
+	err = r.open()
+	return
+}
+
+func (r *Recorder) open() (err error) {
+	r.file, err = os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return
+	}
+
+	info, err := r.file.Stat()
+	if err != nil {
+		r.file.Close()
+		return
+	}
+	r.size = info.Size()
+	return
+}
+
+// rotate moves the current file to path.1 and older ones further. r.mu must be held.
+func (r *Recorder) rotate() (err error) {
+	r.file.Close()
+
+	if r.keep < 1 {
+		os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep))
+		for i := r.keep - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		err = os.Rename(r.path, r.path+".1")
+		if err != nil {
+			return
+		}
+	}
+	return r.open()
+}
+
+// Write records a batch received at t. The batch must be valid JSON.
+func (r *Recorder) Write(source string, batch []byte, t time.Time) (err error) {
+	compact := new(bytes.Buffer)
+	err = json.Compact(compact, batch)
+	if err != nil {
+		return
+	}
+
+	line, err := json.Marshal(Record{
+		Time:   t.UnixNano() / int64(time.Millisecond),
+		Source: source,
+		Batch:  compact.Bytes(),
+	})
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		err = r.rotate()
+		if err != nil {
+			return
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return
+}
+
+// Close closes the file, further writes fail
+func (r *Recorder) Close() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	err = r.file.Close()
+	r.file = nil
+	return
+}
+
+// RecordReader reads records written by a Recorder
+type RecordReader struct {
+	r *bufio.Reader
+}
+
+// NewRecordReader reads records from r
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next record or io.EOF at the end. Empty lines are skipped.
+func (rr *RecordReader) Next() (rec Record, err error) {
+	for {
+		var line []byte
+		line, err = rr.r.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			// a last line without newline is fine
+			return rec, json.Unmarshal(line, &rec)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ShiftEvent moves the event's timestamps by offset seconds, e.g. to replay a recording as if it happened now.
+// Hashes containing a timestamp are recomputed, so replayed events don't collide with the recorded ones.
+// Quests still expire at local midnight. Gym update times are set by the parsers when receiving the message,
+// they aren't shifted.
+func ShiftEvent(event interface{}, offset int64) interface{} {
+	switch ev := event.(type) {
+	case pogo.Spawn:
+		shiftRange(&ev.TimestampRange, offset)
+		return ev
+	case pogo.Raid:
+		shiftRange(&ev.TimestampRange, offset)
+		if ev.StartTime != 0 {
+			ev.Hash = pogo.RaidHash(ev.GymID, ev.StartTime)
+		}
+		return ev
+	case pogo.Weather:
+		if ev.UpdateTime != 0 {
+			ev.UpdateTime += offset
+		}
+		return ev
+	case pogo.Invasion:
+		shiftRange(&ev.TimestampRange, offset)
+		if ev.StartTime != 0 {
+			ev.Hash = pogo.InvasionHash(ev.PokestopID, ev.StartTime)
+		}
+		return ev
+	case pogo.Quest:
+		if ev.StartTime != 0 {
+			ev.StartTime += offset
+			expiry := pogo.QuestExpiry(time.Unix(ev.StartTime, 0))
+			ev.EndTime = expiry.Unix()
+			ev.Hash = pogo.QuestHash(ev.PokestopID, ev.RewardType, ev.RewardID(), expiry)
+		}
+		return ev
+	case pogo.Lure:
+		shiftRange(&ev.TimestampRange, offset)
+		if ev.EndTime != 0 {
+			ev.Hash = pogo.LureHash(ev.PokestopID, ev.EndTime)
+		}
+		return ev
+	}
+	return event
+}
+
+// shiftRange shifts the timestamps that are set
+func shiftRange(r *pogo.TimestampRange, offset int64) {
+	if r.StartTime != 0 {
+		r.StartTime += offset
+	}
+	if r.EndTime != 0 {
+		r.EndTime += offset
+	}
+}
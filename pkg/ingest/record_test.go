@@ -0,0 +1,138 @@
+package ingest
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-record")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	r, err := NewRecorder(path, 0, 0)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, r.Write("mad", []byte("[\n  {\"type\": \"gym\"}\n]"), time.Unix(1613491985, 500*int64(time.Millisecond))))
+	assert.NotNil(t, r.Write("mad", []byte("not json"), time.Now()))
+	assert.Nil(t, r.Close())
+	assert.NotNil(t, r.Write("mad", []byte("[]"), time.Now()))
+
+	content, _ := ioutil.ReadFile(path)
+	assert.Equal(t, `{"time":1613491985500,"source":"mad","batch":[{"type":"gym"}]}`+"\n", string(content))
+
+	// appends
+	r, _ = NewRecorder(path, 0, 0)
+	assert.Nil(t, r.Write("rdm", []byte("[]"), time.Now()))
+	r.Close()
+
+	f, _ := os.Open(path)
+	defer f.Close()
+	reader := NewRecordReader(f)
+	rec, err := reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1613491985500), rec.Time)
+	assert.Equal(t, "mad", rec.Source)
+	assert.Equal(t, `[{"type":"gym"}]`, string(rec.Batch))
+	rec, err = reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "rdm", rec.Source)
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRecorderRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-record")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "webhooks.jsonl")
+
+	// every record gets its own file
+	r, err := NewRecorder(path, 10, 2)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer r.Close()
+	for _, source := range []string{"a", "b", "c", "d"} {
+		assert.Nil(t, r.Write(source, []byte("[]"), time.Now()))
+	}
+
+	for suffix, source := range map[string]string{"": "d", ".1": "c", ".2": "b"} {
+		content, err := ioutil.ReadFile(path + suffix)
+		if assert.Nil(t, err, suffix) {
+			assert.Contains(t, string(content), `"source":"`+source+`"`)
+		}
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRecordReader(t *testing.T) {
+	reader := NewRecordReader(strings.NewReader("\n" + `{"time":1,"source":"mad","batch":[]}` + "\n\nfoo\n" + `{"time":2}`))
+
+	rec, err := reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), rec.Time)
+	_, err = reader.Next()
+	assert.NotNil(t, err)
+	rec, err = reader.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), rec.Time)
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestShiftEvent(t *testing.T) {
+	spawn := ShiftEvent(pogo.Spawn{TimestampRange: pogo.TimestampRange{EndTime: 100}}, 10).(pogo.Spawn)
+	assert.Equal(t, pogo.TimestampRange{StartTime: 0, EndTime: 110}, spawn.TimestampRange)
+
+	raid := ShiftEvent(pogo.Raid{TimestampRange: pogo.TimestampRange{StartTime: 50, EndTime: 100}}, -10).(pogo.Raid)
+	assert.Equal(t, pogo.TimestampRange{StartTime: 40, EndTime: 90}, raid.TimestampRange)
+
+	raid = ShiftEvent(pogo.Raid{Hash: "gym:50", GymID: "gym", TimestampRange: pogo.TimestampRange{StartTime: 50}}, 10).(pogo.Raid)
+	assert.Equal(t, "gym:60", raid.Hash)
+
+	invasion := ShiftEvent(pogo.Invasion{Hash: "stop:50", PokestopID: "stop", TimestampRange: pogo.TimestampRange{StartTime: 50, EndTime: 100}}, 10).(pogo.Invasion)
+	assert.Equal(t, pogo.TimestampRange{StartTime: 60, EndTime: 110}, invasion.TimestampRange)
+	assert.Equal(t, "stop:60", invasion.Hash)
+
+	lure := ShiftEvent(pogo.Lure{Hash: "stop:100", PokestopID: "stop", TimestampRange: pogo.TimestampRange{StartTime: 50, EndTime: 100}}, 10).(pogo.Lure)
+	assert.Equal(t, pogo.TimestampRange{StartTime: 60, EndTime: 110}, lure.TimestampRange)
+	assert.Equal(t, "stop:110", lure.Hash)
+
+	// quests still expire at midnight after the start time, even if the offset isn't whole days
+	start := time.Date(2021, 5, 1, 23, 0, 0, 0, time.Local)
+	quest := pogo.Quest{PokestopID: "stop", RewardType: pogo.QuestRewardPokemonEncounter, PokemonID: 25,
+		TimestampRange: pogo.TimestampRange{StartTime: start.Unix(), EndTime: pogo.QuestExpiry(start).Unix()}}
+	quest.Hash = pogo.QuestHash(quest.PokestopID, quest.RewardType, quest.RewardID(), pogo.QuestExpiry(start))
+	shifted := ShiftEvent(quest, 2*3600).(pogo.Quest)
+	expiry := time.Date(2021, 5, 3, 0, 0, 0, 0, time.Local)
+	assert.Equal(t, start.Unix()+2*3600, shifted.StartTime)
+	assert.Equal(t, expiry.Unix(), shifted.EndTime)
+	assert.Equal(t, pogo.QuestHash("stop", pogo.QuestRewardPokemonEncounter, 25, expiry), shifted.Hash)
+	assert.NotEqual(t, quest.Hash, shifted.Hash)
+
+	weather := ShiftEvent(pogo.Weather{UpdateTime: 100}, 10).(pogo.Weather)
+	assert.Equal(t, int64(110), weather.UpdateTime)
+
+	gym := ShiftEvent(pogo.Gym{UpdateTime: 100}, 10).(pogo.Gym)
+	assert.Equal(t, int64(100), gym.UpdateTime)
+
+	for _, event := range []interface{}{pogo.Invasion{}, pogo.Quest{}, pogo.Lure{}, pogo.Fort{}} {
+		assert.Equal(t, event, ShiftEvent(event, 10))
+	}
+}
@@ -11,10 +11,12 @@ type Chatter struct {
 
 // SendText wraps gomatrix.SendText
 func (c *Chatter) SendText(roomID, text string) {
-	c.cli.SendText(roomID, text)
+	_, err := c.cli.SendText(roomID, text)
+	countSend(roomID, err)
 }
 
 // SendFormattedText wraps gomatrix.SendFormattedText
 func (c *Chatter) SendFormattedText(roomID, text, formattedText string) {
-	c.cli.SendFormattedText(roomID, text, formattedText)
+	_, err := c.cli.SendFormattedText(roomID, text, formattedText)
+	countSend(roomID, err)
 }
@@ -1,6 +1,8 @@
 package matrix
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/matrix-org/gomatrix"
@@ -22,6 +24,27 @@ type Matrix struct {
 
 	// set to true when we're should shut down
 	stopping bool
+
+	// time of the last processed sync response, for health checks
+	syncMu   sync.Mutex
+	lastSync time.Time
+	// set by Init, the first sync gets as long as any other
+	initTime time.Time
+}
+
+// progressSyncer notes the time of every sync response
+type progressSyncer struct {
+	*gomatrix.DefaultSyncer
+	m *Matrix
+}
+
+// ProcessResponse wraps gomatrix.DefaultSyncer.ProcessResponse
+func (s *progressSyncer) ProcessResponse(res *gomatrix.RespSync, since string) (err error) {
+	s.m.syncMu.Lock()
+	s.m.lastSync = time.Now()
+	s.m.syncMu.Unlock()
+
+	return s.DefaultSyncer.ProcessResponse(res, since)
 }
 
 // New Matrix API
@@ -47,15 +70,51 @@ func (m *Matrix) Init() {
 
 	// we apparently need an own syncer to add the callbacks
 	customSyncer := gomatrix.NewDefaultSyncer(m.UserID, m.cli.Store)
-	m.cli.Syncer = customSyncer
+	m.cli.Syncer = &progressSyncer{customSyncer, m}
 
 	// add message callbacks
 	customSyncer.OnEventType("m.room.message", m.onRoomMessage)
 	customSyncer.OnEventType("m.room.member", m.onRoomMember)
 
+	m.syncMu.Lock()
+	m.initTime = time.Now()
+	m.syncMu.Unlock()
+
 	log.Print("Using Homeserver: ", m.Homeserver, ", UserID: ", m.UserID)
 }
 
+// LastSync returns when the last sync response came in, zero if none did yet
+func (m *Matrix) LastSync() time.Time {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+
+	return m.lastSync
+}
+
+// CheckSync is a health check that fails if the sync loop didn't get a response within maxAge.
+// Before the first response the age counts from Init, so the check doesn't fail during startup.
+func (m *Matrix) CheckSync(maxAge time.Duration) (status string, err error) {
+	m.syncMu.Lock()
+	lastSync, initTime := m.lastSync, m.initTime
+	m.syncMu.Unlock()
+
+	if lastSync.IsZero() {
+		age := time.Since(initTime).Round(time.Second)
+		status = fmt.Sprintf("no sync yet, started %s ago", age)
+		if age > maxAge {
+			err = fmt.Errorf("no sync for %s", age)
+		}
+		return
+	}
+
+	age := time.Since(lastSync).Round(time.Second)
+	status = fmt.Sprintf("last sync %s ago", age)
+	if age > maxAge {
+		err = fmt.Errorf("no sync for %s", age)
+	}
+	return
+}
+
 // SetPoster connects the matrix callbacks to a poster that gets configured by room commands
 func (m *Matrix) SetPoster(p *roomservice.Poster) {
 	m.poster = p
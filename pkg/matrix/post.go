@@ -1,11 +1,29 @@
 package matrix
 
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
+)
+
 // SendText sends a message to a room
 func (m *Matrix) SendText(roomID, text string) {
-	m.cli.SendText(roomID, text)
+	_, err := m.cli.SendText(roomID, text)
+	countSend(roomID, err)
 }
 
 // SendFormattedText sends a message to a room
 func (m *Matrix) SendFormattedText(roomID, text, formattedText string) {
-	m.cli.SendFormattedText(roomID, text, formattedText)
+	_, err := m.cli.SendFormattedText(roomID, text, formattedText)
+	countSend(roomID, err)
+}
+
+// countSend updates the metrics after sending a message
+func countSend(roomID string, err error) {
+	if err != nil {
+		log.WithError(err).Warnf("failed sending message to %s", roomID)
+		metrics.MatrixSendErrors.Inc()
+		return
+	}
+	metrics.PostsSent.WithLabelValues(roomID).Inc()
 }
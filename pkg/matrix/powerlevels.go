@@ -0,0 +1,22 @@
+package matrix
+
+// powerLevelsContent is the part of m.room.power_levels we need for permission checks
+type powerLevelsContent struct {
+	Users        map[string]int `json:"users"`
+	UsersDefault int            `json:"users_default"`
+}
+
+// GetUserPowerLevel returns the user's power level from the room's m.room.power_levels state
+func (m *Matrix) GetUserPowerLevel(roomID, userID string) (level int, err error) {
+	content := powerLevelsContent{}
+	err = m.cli.StateEvent(roomID, "m.room.power_levels", "", &content)
+	if err != nil {
+		return
+	}
+
+	level, ok := content.Users[userID]
+	if !ok {
+		level = content.UsersDefault
+	}
+	return
+}
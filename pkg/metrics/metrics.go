@@ -0,0 +1,112 @@
+// Package metrics collects Prometheus metrics, served by pkg/http at /metrics.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// outcomes of webhook envelopes
+const (
+	// OutcomeOK means all events of the envelope were passed on
+	OutcomeOK = "ok"
+	// OutcomeIgnored means the envelope has an unhandled type or contains nothing of interest
+	OutcomeIgnored = "ignored"
+	// OutcomeDropped means nobody takes these events
+	OutcomeDropped = "dropped"
+	// OutcomeError means the message couldn't be decoded
+	OutcomeError = "error"
+)
+
+var (
+	// WebhookEnvelopes counts webhook messages by source, message type and outcome
+	WebhookEnvelopes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "silpht_webhook_envelopes_total",
+		Help: "Webhook messages by source, type and outcome.",
+	}, []string{"source", "type", "outcome"})
+	// WebhookLastData is when a source last sent a valid request, alert on this if the scanner stops
+	WebhookLastData = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "silpht_webhook_last_data_timestamp_seconds",
+		Help: "Unix time of the last valid webhook request by source.",
+	}, []string{"source"})
+	// WebhookRejections counts webhook requests that failed authentication by reason (address, token, signature)
+	WebhookRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "silpht_webhook_rejections_total",
+		Help: "Webhook requests rejected by reason.",
+	}, []string{"reason"})
+
+	// EventsProcessed counts spawns and raids the Poster checked against the filters
+	EventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "silpht_events_processed_total",
+		Help: "Events checked against room filters by type.",
+	}, []string{"type"})
+	// EventsMatched counts spawns and raids that were posted to at least one room
+	EventsMatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "silpht_events_matched_total",
+		Help: "Events posted to at least one room by type.",
+	}, []string{"type"})
+
+	// PostsSent counts messages sent to Matrix rooms
+	PostsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "silpht_posts_sent_total",
+		Help: "Messages sent by room.",
+	}, []string{"room"})
+	// MatrixSendErrors counts messages the homeserver didn't accept
+	MatrixSendErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "silpht_matrix_send_errors_total",
+		Help: "Messages that couldn't be sent.",
+	})
+
+	// LookupDuration measures GeoDex lookups by backend (tile38, memory, bolt)
+	LookupDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "silpht_geodex_lookup_duration_seconds",
+		Help:    "GeoDex lookup latency by backend.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8), // 100µs to 1.6s
+	}, []string{"backend"})
+
+	// QueueLength is the number of waiting events by queue, updated on scrape
+	QueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "silpht_queue_length",
+		Help: "Events waiting in the ingest queue and the Poster's update channels.",
+	}, []string{"queue"})
+	// RoomStateEntries is the number of tracked posts by room and kind, updated on scrape
+	RoomStateEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "silpht_room_state_entries",
+		Help: "Posted events remembered in RoomState by room and kind.",
+	}, []string{"room", "kind"})
+
+	updatersMu sync.Mutex
+	updaters   []func()
+)
+
+// ObserveLookup records the duration of a lookup started at start, use with defer
+func ObserveLookup(backend string, start time.Time) {
+	LookupDuration.WithLabelValues(backend).Observe(time.Since(start).Seconds())
+}
+
+// OnScrape registers a function that updates gauges before they are collected
+func OnScrape(update func()) {
+	updatersMu.Lock()
+	defer updatersMu.Unlock()
+
+	updaters = append(updaters, update)
+}
+
+// Handler serves all metrics in the Prometheus text format
+func Handler() http.Handler {
+	h := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		updatersMu.Lock()
+		for _, update := range updaters {
+			update()
+		}
+		updatersMu.Unlock()
+
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	OnScrape(func() {
+		QueueLength.WithLabelValues("test").Set(42)
+	})
+	ObserveLookup("test", time.Now())
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body, _ := ioutil.ReadAll(rec.Body)
+	assert.Contains(t, string(body), `silpht_queue_length{queue="test"} 42`)
+	assert.Contains(t, string(body), `silpht_geodex_lookup_duration_seconds_count{backend="test"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+}
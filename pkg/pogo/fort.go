@@ -0,0 +1,10 @@
+package pogo
+
+// Fort is a gym or pokestop as described by the scanner, it keeps the GeoDex up to date
+type Fort struct {
+	GUID     string
+	Name     string // empty if the scanner doesn't know it
+	URL      string // image, might be empty
+	Location Location
+	IsGym    bool
+}
@@ -40,11 +40,24 @@ func (t TeamColor) ToString() (s string) {
 	return
 }
 
+// TeamColorFromString converts a color name from ToString() to a TeamColor
+func TeamColorFromString(s string) (t TeamColor, ok bool) {
+	for _, t = range []TeamColor{Neutral, Blue, Red, Yellow} {
+		if t.ToString() == s {
+			return t, true
+		}
+	}
+	return Neutral, false
+}
+
 // Gym describes a gym, optionally with a raid
 type Gym struct {
-	TeamColor TeamColor
-	GUID      string // Ingress GUID, also used in Pogo
-	Name      string // gym name as shown in game
-	Location  Location
-	Raid      *Raid
+	TeamColor      TeamColor
+	GUID           string // Ingress GUID, also used in Pogo
+	Name           string // gym name as shown in game
+	Location       Location
+	Raid           *Raid
+	SlotsAvailable int   // free defender slots
+	ExRaidEligible bool  // EX raid passes can be given out here
+	UpdateTime     int64 // when the gym info was received
 }
@@ -0,0 +1,103 @@
+package pogo
+
+import "fmt"
+
+// GruntType is the Team GO Rocket character of an invasion
+type GruntType int
+
+// special characters, the others are grunts with or without a Pokemon type
+const (
+	GruntTypeUnset    GruntType = 0
+	GruntTypeCliff    GruntType = 41
+	GruntTypeArlo     GruntType = 42
+	GruntTypeSierra   GruntType = 43
+	GruntTypeGiovanni GruntType = 44
+)
+
+// gruntTypeNames maps the InvasionCharacter enum from protos to names
+var gruntTypeNames = []string{
+	"Unset",
+	"Blanche",
+	"Candela",
+	"Spark",
+	"Grunt (male)",
+	"Grunt (female)",
+	"Bug Grunt (female)",
+	"Bug Grunt (male)",
+	"Shadow Grunt (female)",
+	"Shadow Grunt (male)",
+	"Dark Grunt (female)",
+	"Dark Grunt (male)",
+	"Dragon Grunt (female)",
+	"Dragon Grunt (male)",
+	"Fairy Grunt (female)",
+	"Fairy Grunt (male)",
+	"Fighting Grunt (female)",
+	"Fighting Grunt (male)",
+	"Fire Grunt (female)",
+	"Fire Grunt (male)",
+	"Flying Grunt (female)",
+	"Flying Grunt (male)",
+	"Grass Grunt (female)",
+	"Grass Grunt (male)",
+	"Ground Grunt (female)",
+	"Ground Grunt (male)",
+	"Ice Grunt (female)",
+	"Ice Grunt (male)",
+	"Steel Grunt (female)",
+	"Steel Grunt (male)",
+	"Normal Grunt (female)",
+	"Normal Grunt (male)",
+	"Poison Grunt (female)",
+	"Poison Grunt (male)",
+	"Psychic Grunt (female)",
+	"Psychic Grunt (male)",
+	"Rock Grunt (female)",
+	"Rock Grunt (male)",
+	"Water Grunt (female)",
+	"Water Grunt (male)",
+	"Team Leader",
+	"Cliff",
+	"Arlo",
+	"Sierra",
+	"Giovanni",
+	"Decoy Grunt (male)",
+	"Decoy Grunt (female)",
+	"Ghost Grunt (female)",
+	"Ghost Grunt (male)",
+	"Electric Grunt (female)",
+	"Electric Grunt (male)",
+}
+
+// ToString returns the name of the character
+func (g GruntType) ToString() string {
+	if g < 0 || int(g) >= len(gruntTypeNames) {
+		return fmt.Sprintf("Grunt #%d", g)
+	}
+	return gruntTypeNames[g]
+}
+
+// IsLeader returns true for the Team GO Rocket leaders Cliff, Arlo and Sierra
+func (g GruntType) IsLeader() bool {
+	return g == GruntTypeCliff || g == GruntTypeArlo || g == GruntTypeSierra
+}
+
+// LeaderGruntTypes returns the Team GO Rocket leaders
+func LeaderGruntTypes() []GruntType {
+	return []GruntType{GruntTypeCliff, GruntTypeArlo, GruntTypeSierra}
+}
+
+// InvasionHash identifies an invasion at a pokestop
+func InvasionHash(pokestopID string, startTime int64) string {
+	return fmt.Sprintf("%s:%d", pokestopID, startTime)
+}
+
+// Invasion describes a Team GO Rocket invasion at a pokestop
+type Invasion struct {
+	Hash         string
+	PokestopID   string
+	PokestopName string // from the webhook, might be empty
+	Location     Location
+	GruntType    GruntType
+	TimestampRange
+}
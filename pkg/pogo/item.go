@@ -0,0 +1,60 @@
+package pogo
+
+import "fmt"
+
+// itemNames maps the Item enum from protos to names, only items that can be quest rewards or fort modifiers
+var itemNames = map[int]string{
+	1:    "Poke Ball",
+	2:    "Great Ball",
+	3:    "Ultra Ball",
+	4:    "Master Ball",
+	5:    "Premier Ball",
+	101:  "Potion",
+	102:  "Super Potion",
+	103:  "Hyper Potion",
+	104:  "Max Potion",
+	201:  "Revive",
+	202:  "Max Revive",
+	301:  "Lucky Egg",
+	401:  "Incense",
+	501:  "Lure Module",
+	502:  "Glacial Lure Module",
+	503:  "Mossy Lure Module",
+	504:  "Magnetic Lure Module",
+	505:  "Rainy Lure Module",
+	506:  "Sparkly Lure Module",
+	701:  "Razz Berry",
+	703:  "Nanab Berry",
+	705:  "Pinap Berry",
+	706:  "Golden Razz Berry",
+	708:  "Silver Pinap Berry",
+	902:  "Egg Incubator",
+	903:  "Super Incubator",
+	1101: "Sun Stone",
+	1102: "King's Rock",
+	1103: "Metal Coat",
+	1104: "Dragon Scale",
+	1105: "Up-Grade",
+	1106: "Sinnoh Stone",
+	1107: "Unova Stone",
+	1201: "Fast TM",
+	1202: "Charged TM",
+	1203: "Elite Fast TM",
+	1204: "Elite Charged TM",
+	1301: "Rare Candy",
+	1302: "XL Rare Candy",
+	1401: "Raid Pass",
+	1402: "Premium Raid Pass",
+	1404: "Star Piece",
+	1501: "Mysterious Component",
+	1502: "Rocket Radar",
+	1503: "Super Rocket Radar",
+}
+
+// ItemName returns the name of the item with the id from protos
+func ItemName(id int) string {
+	if name, ok := itemNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("Item #%d", id)
+}
@@ -89,3 +89,23 @@ func (lr LocationRadius) Contains(o *Location) bool {
 	distance := lr.Location.DistanceTo(o)
 	return distance <= lr.RadiusM
 }
+
+// BoundingBox is the rectangle between two corners, it can't span the antimeridian
+type BoundingBox struct {
+	SouthWest Location
+	NorthEast Location
+}
+
+// NewBoundingBox returns the box between the given corners
+func NewBoundingBox(minLat, minLon, maxLat, maxLon float64) BoundingBox {
+	return BoundingBox{
+		SouthWest: Location{Latitude: minLat, Longitude: minLon},
+		NorthEast: Location{Latitude: maxLat, Longitude: maxLon},
+	}
+}
+
+// Contains returns true if the location is inside the box or on its edge
+func (b BoundingBox) Contains(o *Location) bool {
+	return o.Latitude >= b.SouthWest.Latitude && o.Latitude <= b.NorthEast.Latitude &&
+		o.Longitude >= b.SouthWest.Longitude && o.Longitude <= b.NorthEast.Longitude
+}
@@ -162,3 +162,14 @@ func TestLocationRadius(t *testing.T) {
 	res = lr.Contains(&farLoc)
 	assert.Equal(t, false, res)
 }
+
+func TestBoundingBox(t *testing.T) {
+	b := NewBoundingBox(52.4, 13.3, 52.6, 13.5)
+	assert.Equal(t, Location{52.4, 13.3}, b.SouthWest)
+	assert.Equal(t, Location{52.6, 13.5}, b.NorthEast)
+
+	assert.True(t, b.Contains(&Location{52.5, 13.4}))
+	assert.True(t, b.Contains(&Location{52.4, 13.5}))
+	assert.False(t, b.Contains(&Location{52.7, 13.4}))
+	assert.False(t, b.Contains(&Location{52.5, 13.2}))
+}
@@ -0,0 +1,62 @@
+package pogo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LureType is the item id of the lure module installed at a pokestop
+type LureType int
+
+// mapping from protos
+const (
+	LureTypeUnset    LureType = 0
+	LureTypeNormal   LureType = 501
+	LureTypeGlacial  LureType = 502
+	LureTypeMossy    LureType = 503
+	LureTypeMagnetic LureType = 504
+	LureTypeRainy    LureType = 505
+	LureTypeSparkly  LureType = 506
+)
+
+// LureDuration is how long a lure module lasts in seconds
+const LureDuration = 30 * 60
+
+// ToString returns the item name of the lure module
+func (l LureType) ToString() string {
+	return ItemName(int(l))
+}
+
+// ShortName returns the lowercase name used in commands, e.g. "glacial"
+func (l LureType) ShortName() string {
+	if l == LureTypeNormal {
+		return "normal"
+	}
+	return strings.ToLower(strings.TrimSuffix(l.ToString(), " Lure Module"))
+}
+
+// IsValid returns true for known lure modules
+func (l LureType) IsValid() bool {
+	return l >= LureTypeNormal && l <= LureTypeSparkly
+}
+
+// LureTypes returns all known lure modules
+func LureTypes() []LureType {
+	return []LureType{LureTypeNormal, LureTypeGlacial, LureTypeMossy,
+		LureTypeMagnetic, LureTypeRainy, LureTypeSparkly}
+}
+
+// LureHash identifies a lure module at a pokestop
+func LureHash(pokestopID string, expiration int64) string {
+	return fmt.Sprintf("%s:%d", pokestopID, expiration)
+}
+
+// Lure describes an active lure module at a pokestop
+type Lure struct {
+	Hash         string
+	PokestopID   string
+	PokestopName string // from the webhook, might be empty
+	Location     Location
+	LureType     LureType
+	TimestampRange
+}
@@ -3,6 +3,7 @@ package pogo
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -91,4 +92,133 @@ func TestPokedex(t *testing.T) {
 	assert.Error(t, err)
 	_, _, err = dex.GetNamesByID(-1)
 	assert.Error(t, err)
+
+	entries := dex.Entries()
+	if assert.Equal(t, dex.Len(), len(entries)) {
+		assert.Equal(t, PokedexEntry{1, "Bulbasaur", "Bisasam"}, entries[0])
+	}
+}
+
+func TestRaid(t *testing.T) {
+	r := Raid{Level: 5}
+	assert.Equal(t, true, r.IsEgg())
+	assert.Equal(t, "Level 5", r.LevelToString())
+
+	r.Pokemon = &Pokemon{ID: 0}
+	assert.Equal(t, true, r.IsEgg())
+
+	r.Pokemon.ID = 150
+	assert.Equal(t, false, r.IsEgg())
+
+	r.Level = RaidLevelMega
+	assert.Equal(t, "Mega", r.LevelToString())
+}
+
+func TestEncounter(t *testing.T) {
+	e := Encounter{
+		Attack:  15,
+		Defense: 15,
+		Stamina: 15,
+		CP:      2345,
+		Level:   30,
+		Move1:   216,
+		Move2:   90,
+		Weight:  4.5,
+		Height:  0.75,
+	}
+	assert.Equal(t, 100.0, e.IVPercent())
+	assert.Equal(t, "100% (15/15/15) CP 2345 L30, moves 216/90, 4.50kg 0.75m", e.ToString())
+
+	e.Attack = 0
+	e.Defense = 0
+	e.Stamina = 0
+	assert.Equal(t, 0.0, e.IVPercent())
+
+	e.Attack = 10
+	e.Defense = 12
+	e.Stamina = 14
+	assert.Equal(t, 80.0, e.IVPercent())
+}
+
+func TestWeather(t *testing.T) {
+	assert.Equal(t, WeatherRainy, ToWeatherCondition(2))
+	assert.Equal(t, WeatherNone, ToWeatherCondition(8))
+	assert.Equal(t, WeatherNone, ToWeatherCondition(-1))
+	assert.Equal(t, "Rainy", WeatherRainy.ToString())
+	assert.Equal(t, "None", WeatherNone.ToString())
+	assert.Equal(t, []string{"Ice", "Steel"}, WeatherSnow.BoostedTypes())
+	assert.Nil(t, WeatherNone.BoostedTypes())
+	assert.Equal(t, "Fog (boosts Dark, Ghost)", WeatherFog.BoostToString())
+	assert.Equal(t, "no weather boost", WeatherNone.BoostToString())
+
+	assert.Equal(t, AlertExtreme, ToAlertSeverity(2))
+	assert.Equal(t, AlertNone, ToAlertSeverity(3))
+	assert.Equal(t, "moderate", AlertModerate.ToString())
+	assert.Equal(t, "none", AlertNone.ToString())
+
+	// cell center from the MAD webhook test data
+	l := Location{Latitude: 52.4989257703959698, Longitude: 13.47406201946032334}
+	assert.Equal(t, uint64(5163463834198867968), WeatherCellID(&l))
+	assert.True(t, IsWeatherCellID(WeatherCellID(&l)))
+	assert.False(t, IsWeatherCellID(5118835684653216542))
+	near := Location{Latitude: 52.499, Longitude: 13.474}
+	assert.Equal(t, WeatherCellID(&l), WeatherCellID(&near))
+}
+
+func TestGruntType(t *testing.T) {
+	assert.Equal(t, "Unset", GruntTypeUnset.ToString())
+	assert.Equal(t, "Rock Grunt (male)", GruntType(37).ToString())
+	assert.Equal(t, "Giovanni", GruntTypeGiovanni.ToString())
+	assert.Equal(t, "Electric Grunt (male)", GruntType(50).ToString())
+	assert.Equal(t, "Grunt #51", GruntType(51).ToString())
+	assert.Equal(t, "Grunt #-1", GruntType(-1).ToString())
+
+	assert.True(t, GruntTypeArlo.IsLeader())
+	assert.False(t, GruntTypeGiovanni.IsLeader())
+	assert.Equal(t, 3, len(LeaderGruntTypes()))
+}
+
+func TestTeamColorFromString(t *testing.T) {
+	for _, team := range []TeamColor{Neutral, Blue, Red, Yellow} {
+		parsed, ok := TeamColorFromString(team.ToString())
+		assert.True(t, ok)
+		assert.Equal(t, team, parsed)
+	}
+	_, ok := TeamColorFromString("green")
+	assert.False(t, ok)
+}
+
+func TestItem(t *testing.T) {
+	assert.Equal(t, "Golden Razz Berry", ItemName(706))
+	assert.Equal(t, "Item #9999", ItemName(9999))
+}
+
+func TestLure(t *testing.T) {
+	assert.Equal(t, "Glacial Lure Module", LureTypeGlacial.ToString())
+	assert.Equal(t, "glacial", LureTypeGlacial.ShortName())
+	assert.Equal(t, "normal", LureTypeNormal.ShortName())
+	assert.True(t, LureTypeSparkly.IsValid())
+	assert.False(t, LureTypeUnset.IsValid())
+	assert.False(t, LureType(507).IsValid())
+	assert.Equal(t, 6, len(LureTypes()))
+}
+
+func TestQuest(t *testing.T) {
+	assert.Equal(t, "Encounter", QuestRewardPokemonEncounter.ToString())
+	assert.Equal(t, "Mega Energy", QuestRewardMegaEnergy.ToString())
+	assert.Equal(t, "Unset", QuestRewardType(99).ToString())
+
+	q := Quest{RewardType: QuestRewardPokemonEncounter, PokemonID: 147, ItemID: 1}
+	assert.Equal(t, 147, q.RewardID())
+	q.RewardType = QuestRewardItem
+	assert.Equal(t, 1, q.RewardID())
+	q.RewardType = QuestRewardStardust
+	assert.Equal(t, 0, q.RewardID())
+
+	scanTime := time.Date(2021, 2, 16, 23, 59, 0, 0, time.Local)
+	expiry := QuestExpiry(scanTime)
+	assert.Equal(t, time.Date(2021, 2, 17, 0, 0, 0, 0, time.Local), expiry)
+	assert.Equal(t, expiry, QuestExpiry(expiry.Add(-24*time.Hour)))
+
+	assert.Equal(t, fmt.Sprintf("stop:7:147:%d", expiry.Unix()), QuestHash("stop", QuestRewardPokemonEncounter, 147, expiry))
 }
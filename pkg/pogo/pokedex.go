@@ -48,6 +48,20 @@ func (p *Pokedex) ReadFile() (err error) {
 	return
 }
 
+// Len returns the number of entries
+func (p *Pokedex) Len() int {
+	return len(p.entries)
+}
+
+// Entries returns a copy of all entries ordered by ID
+func (p *Pokedex) Entries() (entries []PokedexEntry) {
+	entries = make([]PokedexEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, *entry)
+	}
+	return
+}
+
 // GetNamesByID returns the english and german name of the pokemon with its id from 1-898
 func (p *Pokedex) GetNamesByID(id int) (nameEN, nameDE string, err error) {
 	arrayIdx := id - 1
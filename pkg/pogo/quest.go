@@ -0,0 +1,101 @@
+package pogo
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuestRewardType is the kind of reward for a field research quest
+type QuestRewardType int
+
+// mapping from protos
+const (
+	QuestRewardUnset QuestRewardType = iota
+	QuestRewardExperience
+	QuestRewardItem
+	QuestRewardStardust
+	QuestRewardCandy
+	QuestRewardAvatarClothing
+	QuestRewardQuest
+	QuestRewardPokemonEncounter
+	QuestRewardPokecoin
+	QuestRewardXLCandy
+	QuestRewardLevelCap
+	QuestRewardSticker
+	QuestRewardMegaEnergy
+)
+
+// ToString converts the QuestRewardType to a string
+func (q QuestRewardType) ToString() (s string) {
+	switch q {
+	case QuestRewardExperience:
+		s = "Experience"
+	case QuestRewardItem:
+		s = "Item"
+	case QuestRewardStardust:
+		s = "Stardust"
+	case QuestRewardCandy:
+		s = "Candy"
+	case QuestRewardAvatarClothing:
+		s = "Avatar Clothing"
+	case QuestRewardQuest:
+		s = "Quest"
+	case QuestRewardPokemonEncounter:
+		s = "Encounter"
+	case QuestRewardPokecoin:
+		s = "Pokecoins"
+	case QuestRewardXLCandy:
+		s = "XL Candy"
+	case QuestRewardLevelCap:
+		s = "Level Cap"
+	case QuestRewardSticker:
+		s = "Sticker"
+	case QuestRewardMegaEnergy:
+		s = "Mega Energy"
+	default:
+		s = "Unset"
+	}
+	return
+}
+
+// HasPokemon returns true if the reward is for a specific Pokemon
+func (q QuestRewardType) HasPokemon() bool {
+	return q == QuestRewardPokemonEncounter || q == QuestRewardCandy ||
+		q == QuestRewardXLCandy || q == QuestRewardMegaEnergy
+}
+
+// Quest describes a field research quest at a pokestop
+type Quest struct {
+	Hash         string
+	PokestopID   string
+	PokestopName string // from the webhook, might be empty
+	Location     Location
+	Task         string // quest text
+	RewardType   QuestRewardType
+	ItemID       int // only for item rewards
+	PokemonID    int // only for rewards with HasPokemon()
+	Amount       int // number of items, stardust, candy or energy
+	TimestampRange
+}
+
+// RewardID returns the pokemon or item id of the reward, 0 if there is none
+func (q *Quest) RewardID() int {
+	if q.RewardType.HasPokemon() {
+		return q.PokemonID
+	}
+	if q.RewardType == QuestRewardItem {
+		return q.ItemID
+	}
+	return 0
+}
+
+// QuestExpiry returns the local midnight after the given time when field research quests are replaced
+func QuestExpiry(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.Local)
+}
+
+// QuestHash identifies a quest at a pokestop on a day
+func QuestHash(pokestopID string, rewardType QuestRewardType, rewardID int, expiry time.Time) string {
+	return fmt.Sprintf("%s:%d:%d:%d", pokestopID, rewardType, rewardID, expiry.Unix())
+}
@@ -1,5 +1,10 @@
 package pogo
 
+import "fmt"
+
+// RaidLevelMega is the raid level MAD uses for mega raids
+const RaidLevelMega = 6
+
 // Raid contains raid info
 type Raid struct {
 	Hash     string
@@ -9,3 +14,21 @@ type Raid struct {
 	Level    int
 	TimestampRange
 }
+
+// RaidHash identifies a raid at a gym, the egg and the hatched boss have the same hash
+func RaidHash(gymID string, startTime int64) string {
+	return fmt.Sprintf("%s:%d", gymID, startTime)
+}
+
+// IsEgg returns true if the raid boss didn't hatch yet
+func (r *Raid) IsEgg() bool {
+	return r.Pokemon == nil || r.Pokemon.ID == 0
+}
+
+// LevelToString returns the raid level in human-readable form
+func (r *Raid) LevelToString() string {
+	if r.Level == RaidLevelMega {
+		return "Mega"
+	}
+	return fmt.Sprintf("Level %d", r.Level)
+}
@@ -1,5 +1,7 @@
 package pogo
 
+import "fmt"
+
 // Spawn describes a spawned Pokemon
 type Spawn struct {
 	EncounterID        string
@@ -7,4 +9,30 @@ type Spawn struct {
 	Pokemon
 	Location
 	TimestampRange
+	Encounter      *Encounter       // nil if the scanner didn't encounter the Pokemon
+	BoostedWeather WeatherCondition // WeatherNone if not boosted
+}
+
+// Encounter contains the stats that are only known after encountering a Pokemon
+type Encounter struct {
+	Attack  int // individual values, 0-15
+	Defense int
+	Stamina int
+	CP      int
+	Level   int
+	Move1   int     // quick move id
+	Move2   int     // charge move id
+	Weight  float64 // kg
+	Height  float64 // m
+}
+
+// IVPercent returns the sum of the individual values as percentage of the perfect value
+func (e *Encounter) IVPercent() float64 {
+	return float64(e.Attack+e.Defense+e.Stamina) * 100.0 / 45.0
+}
+
+// ToString returns the IV, CP, level and moves in human-readable form
+func (e *Encounter) ToString() string {
+	return fmt.Sprintf("%.0f%% (%d/%d/%d) CP %d L%d, moves %d/%d, %.2fkg %.2fm",
+		e.IVPercent(), e.Attack, e.Defense, e.Stamina, e.CP, e.Level, e.Move1, e.Move2, e.Weight, e.Height)
 }
@@ -0,0 +1,141 @@
+package pogo
+
+import (
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// WeatherCellLevel is the S2 cell level the game uses for weather
+const WeatherCellLevel = 10
+
+// WeatherCondition is the gameplay weather in a cell
+type WeatherCondition int
+
+// mapping from protos
+const (
+	WeatherNone WeatherCondition = iota
+	WeatherClear
+	WeatherRainy
+	WeatherPartlyCloudy
+	WeatherOvercast
+	WeatherWindy
+	WeatherSnow
+	WeatherFog
+)
+
+// ToWeatherCondition converts an int from a proto to a WeatherCondition
+func ToWeatherCondition(val int) WeatherCondition {
+	if val < int(WeatherNone) || val > int(WeatherFog) {
+		return WeatherNone
+	}
+	return WeatherCondition(val)
+}
+
+// ToString converts the WeatherCondition to a string with the name of the weather
+func (w WeatherCondition) ToString() (s string) {
+	switch w {
+	case WeatherClear:
+		s = "Sunny/Clear"
+	case WeatherRainy:
+		s = "Rainy"
+	case WeatherPartlyCloudy:
+		s = "Partly Cloudy"
+	case WeatherOvercast:
+		s = "Cloudy"
+	case WeatherWindy:
+		s = "Windy"
+	case WeatherSnow:
+		s = "Snow"
+	case WeatherFog:
+		s = "Fog"
+	default:
+		s = "None"
+	}
+	return
+}
+
+// BoostedTypes returns the Pokemon types that are boosted by the weather
+func (w WeatherCondition) BoostedTypes() (types []string) {
+	switch w {
+	case WeatherClear:
+		types = []string{"Grass", "Ground", "Fire"}
+	case WeatherRainy:
+		types = []string{"Water", "Electric", "Bug"}
+	case WeatherPartlyCloudy:
+		types = []string{"Normal", "Rock"}
+	case WeatherOvercast:
+		types = []string{"Fairy", "Fighting", "Poison"}
+	case WeatherWindy:
+		types = []string{"Dragon", "Flying", "Psychic"}
+	case WeatherSnow:
+		types = []string{"Ice", "Steel"}
+	case WeatherFog:
+		types = []string{"Dark", "Ghost"}
+	}
+	return
+}
+
+// BoostToString returns the weather with the types it boosts in human-readable form
+func (w WeatherCondition) BoostToString() string {
+	if w == WeatherNone {
+		return "no weather boost"
+	}
+	return w.ToString() + " (boosts " + strings.Join(w.BoostedTypes(), ", ") + ")"
+}
+
+// AlertSeverity of a weather warning
+type AlertSeverity int
+
+// mapping from protos
+const (
+	AlertNone AlertSeverity = iota
+	AlertModerate
+	AlertExtreme
+)
+
+// ToAlertSeverity converts an int from a proto to an AlertSeverity
+func ToAlertSeverity(val int) AlertSeverity {
+	switch val {
+	case 1:
+		return AlertModerate
+	case 2:
+		return AlertExtreme
+	default:
+		return AlertNone
+	}
+}
+
+// ToString converts the AlertSeverity to a string
+func (a AlertSeverity) ToString() (s string) {
+	switch a {
+	case AlertModerate:
+		s = "moderate"
+	case AlertExtreme:
+		s = "extreme"
+	default:
+		s = "none"
+	}
+	return
+}
+
+// Weather describes the weather in a level 10 S2 cell
+type Weather struct {
+	CellID        uint64
+	Condition     WeatherCondition
+	AlertSeverity AlertSeverity
+	Location      Location // cell center
+	UpdateTime    int64
+}
+
+// WeatherCellID returns the ID of the weather cell that contains the location
+func WeatherCellID(l *Location) uint64 {
+	latLng := s2.LatLngFromDegrees(l.Latitude, l.Longitude)
+	return uint64(s2.CellIDFromLatLng(latLng).Parent(WeatherCellLevel))
+}
+
+// IsWeatherCellID checks if the ID is a valid S2 cell ID of the weather cell level
+func IsWeatherCellID(id uint64) bool {
+	cellID := s2.CellID(id)
+	return cellID.IsValid() && cellID.Level() == WeatherCellLevel
+}
@@ -0,0 +1,44 @@
+package roomservice
+
+import "github.com/spezifisch/silphtelescope/pkg/pogo"
+
+// ActiveEvents keeps every spawn, raid and invasion the scanner reported until it ends, regardless of room filters
+type ActiveEvents struct {
+	// EncounterID -> Spawn
+	Spawns map[string]pogo.Spawn
+	// Raid hash -> Raid, the hatched raid replaces the egg
+	Raids map[string]pogo.Raid
+	// Invasion hash -> Invasion
+	Invasions map[string]pogo.Invasion
+}
+
+// NewActiveEvents creates an ActiveEvents object
+func NewActiveEvents() *ActiveEvents {
+	return &ActiveEvents{
+		Spawns:    make(map[string]pogo.Spawn),
+		Raids:     make(map[string]pogo.Raid),
+		Invasions: make(map[string]pogo.Invasion),
+	}
+}
+
+func (a *ActiveEvents) removeExpired(before int64) (deleted int) {
+	for k, s := range a.Spawns {
+		if s.EndTime < before {
+			delete(a.Spawns, k)
+			deleted++
+		}
+	}
+	for k, r := range a.Raids {
+		if r.EndTime < before {
+			delete(a.Raids, k)
+			deleted++
+		}
+	}
+	for k, i := range a.Invasions {
+		if i.EndTime < before {
+			delete(a.Invasions, k)
+			deleted++
+		}
+	}
+	return
+}
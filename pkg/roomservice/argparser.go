@@ -113,6 +113,130 @@ func (a *ArgParser) AsLocationRadius(latIdx, lonIdx, radiusIdx int) (lr pogo.Loc
 	if err != nil {
 		return
 	}
-	// TODO check for inf, nan and stuff
-	return pogo.NewLocationRadius(lat, lon, radius), nil
+	area := pogo.NewLocationRadius(lat, lon, radius)
+	if err = validateArea(area); err != nil {
+		return
+	}
+	return area, nil
+}
+
+// AsRaidLevelArray works like AsIntArray, but also accepts "mega" for mega raids
+func (a *ArgParser) AsRaidLevelArray(index int) (arr []int, err error) {
+	val, err := a.AsString(index)
+	if err != nil {
+		return
+	}
+
+	parts := strings.Split(val, ",")
+	for i, part := range parts {
+		if strings.ToLower(part) == "mega" {
+			parts[i] = strconv.Itoa(pogo.RaidLevelMega)
+		}
+	}
+
+	arrayParser := NewArgParser([]string{strings.Join(parts, ",")})
+	arr, err = arrayParser.AsIntArray(0)
+	if err != nil {
+		return
+	}
+	for _, level := range arr {
+		if level < 1 || level > pogo.RaidLevelMega {
+			return nil, errors.New("invalid raid level")
+		}
+	}
+	return
+}
+
+// AsTeamArray works like AsIntArray, but also accepts team colors like "red"
+func (a *ArgParser) AsTeamArray(index int) (arr []int, err error) {
+	val, err := a.AsString(index)
+	if err != nil {
+		return
+	}
+
+	parts := []string{}
+	for _, part := range strings.Split(val, ",") {
+		if team, ok := pogo.TeamColorFromString(strings.ToLower(part)); ok {
+			part = strconv.Itoa(int(team))
+		}
+		parts = append(parts, part)
+	}
+
+	arrayParser := NewArgParser([]string{strings.Join(parts, ",")})
+	arr, err = arrayParser.AsIntArray(0)
+	if err != nil {
+		return
+	}
+	for _, team := range arr {
+		if team < int(pogo.Neutral) || team > int(pogo.Yellow) {
+			return nil, errors.New("invalid team")
+		}
+	}
+	return
+}
+
+// AsLureTypeArray works like AsIntArray, but also accepts lure names like "glacial"
+func (a *ArgParser) AsLureTypeArray(index int) (arr []int, err error) {
+	val, err := a.AsString(index)
+	if err != nil {
+		return
+	}
+
+	parts := []string{}
+	for _, part := range strings.Split(val, ",") {
+		name := strings.ToLower(part)
+		for _, lureType := range pogo.LureTypes() {
+			if name == lureType.ShortName() {
+				part = strconv.Itoa(int(lureType))
+				break
+			}
+		}
+		parts = append(parts, part)
+	}
+
+	arrayParser := NewArgParser([]string{strings.Join(parts, ",")})
+	arr, err = arrayParser.AsIntArray(0)
+	if err != nil {
+		return
+	}
+	for _, lureType := range arr {
+		if !pogo.LureType(lureType).IsValid() {
+			return nil, errors.New("invalid lure type")
+		}
+	}
+	return
+}
+
+// AsGruntTypeArray works like AsIntArray, but also accepts "leaders" and "giovanni"
+func (a *ArgParser) AsGruntTypeArray(index int) (arr []int, err error) {
+	val, err := a.AsString(index)
+	if err != nil {
+		return
+	}
+
+	parts := []string{}
+	for _, part := range strings.Split(val, ",") {
+		switch strings.ToLower(part) {
+		case "leaders":
+			for _, leader := range pogo.LeaderGruntTypes() {
+				parts = append(parts, strconv.Itoa(int(leader)))
+			}
+		case "giovanni":
+			parts = append(parts, strconv.Itoa(int(pogo.GruntTypeGiovanni)))
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	arrayParser := NewArgParser([]string{strings.Join(parts, ",")})
+	arr, err = arrayParser.AsIntArray(0)
+	if err != nil {
+		return
+	}
+	for _, gruntType := range arr {
+		if gruntType <= int(pogo.GruntTypeUnset) {
+			return nil, errors.New("invalid grunt type")
+		}
+	}
+	return
 }
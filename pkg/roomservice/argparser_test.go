@@ -136,6 +136,86 @@ func TestArgParser_AsIntArray(t *testing.T) {
 	}
 }
 
+func TestArgParser_AsRaidLevelArray(t *testing.T) {
+	a := NewArgParser([]string{"5", "1,mega", "MEGA", "0", "7", "5,foo"})
+
+	arr, err := a.AsRaidLevelArray(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{5}, arr)
+
+	arr, err = a.AsRaidLevelArray(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, pogo.RaidLevelMega}, arr)
+
+	arr, err = a.AsRaidLevelArray(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{pogo.RaidLevelMega}, arr)
+
+	for _, index := range []int{3, 4, 5, 6} {
+		_, err = a.AsRaidLevelArray(index)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestArgParser_AsGruntTypeArray(t *testing.T) {
+	a := NewArgParser([]string{"37", "leaders,4", "GIOVANNI", "0", "-1", "foo"})
+
+	arr, err := a.AsGruntTypeArray(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{37}, arr)
+
+	arr, err = a.AsGruntTypeArray(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{41, 42, 43, 4}, arr)
+
+	arr, err = a.AsGruntTypeArray(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{44}, arr)
+
+	for _, index := range []int{3, 4, 5, 6} {
+		_, err = a.AsGruntTypeArray(index)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestArgParser_AsTeamArray(t *testing.T) {
+	a := NewArgParser([]string{"red,Blue", "0,3", "green", "4"})
+
+	arr, err := a.AsTeamArray(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 1}, arr)
+
+	arr, err = a.AsTeamArray(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 3}, arr)
+
+	for _, index := range []int{2, 3, 4} {
+		_, err = a.AsTeamArray(index)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestArgParser_AsLureTypeArray(t *testing.T) {
+	a := NewArgParser([]string{"glacial,MAGNETIC", "501,sparkly", "502", "500", "foo", "mossy,701"})
+
+	arr, err := a.AsLureTypeArray(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{502, 504}, arr)
+
+	arr, err = a.AsLureTypeArray(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{501, 506}, arr)
+
+	arr, err = a.AsLureTypeArray(2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{502}, arr)
+
+	for _, index := range []int{3, 4, 5, 6} {
+		_, err = a.AsLureTypeArray(index)
+		assert.NotNil(t, err)
+	}
+}
+
 func TestArgParser_AsLocation(t *testing.T) {
 	type fields struct {
 		args []string
@@ -247,6 +327,30 @@ func TestArgParser_AsLocationRadius(t *testing.T) {
 			args:    args{0, 1, 2},
 			wantErr: true,
 		},
+		{
+			name: "latitude out of range",
+			fields: fields{
+				args: []string{"91", "3.4", "100"},
+			},
+			args:    args{0, 1, 2},
+			wantErr: true,
+		},
+		{
+			name: "negative radius",
+			fields: fields{
+				args: []string{"1.2", "3.4", "-100"},
+			},
+			args:    args{0, 1, 2},
+			wantErr: true,
+		},
+		{
+			name: "NaN",
+			fields: fields{
+				args: []string{"NaN", "3.4", "100"},
+			},
+			args:    args{0, 1, 2},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,7 +1,10 @@
 package roomservice
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -18,14 +21,23 @@ const (
 
 var (
 	commands = []Command{
-		{"help", helpCallback, true},
-		{"admin", adminCallback, false},
-		{"status", statusCallback, true},
-		{"mon", monCallback, true},
-		{"fort", fortCallback, true},
-		{"filter", filterCallback, true},
-		{"spawn", spawnCallback, true},
-		{"raid", raidCallback, true},
+		{"help", helpCallback, PermissionEveryone},
+		{"admin", adminCallback, PermissionAdmin},
+		{"status", statusCallback, PermissionEveryone},
+		{"mon", monCallback, PermissionEveryone},
+		{"fort", fortCallback, PermissionEveryone},
+		{"filter", filterCallback, PermissionModerator},
+		{"spawn", spawnCallback, PermissionModerator},
+		{"raid", raidCallback, PermissionModerator},
+		{"egg", eggCallback, PermissionModerator},
+		{"weather", weatherCallback, PermissionEveryone},
+		{"invasion", invasionCallback, PermissionModerator},
+		{"quest", questCallback, PermissionModerator},
+		{"lure", lureCallback, PermissionModerator},
+		{"gym", gymCallback, PermissionEveryone},
+		{"takeover", takeoverCallback, PermissionModerator},
+		{"web", webCallback, PermissionModerator},
+		{"stream", streamCallback, PermissionModerator},
 	}
 	commandList string
 )
@@ -38,8 +50,8 @@ type Command struct {
 	// Command without / or ! prefix
 	Command  string
 	Callback CommandCallback
-	// accessible by everyone? false=admin only
-	Public bool
+	// who is allowed to use this command
+	Permission Permission
 }
 
 func generateCommandList() {
@@ -65,10 +77,26 @@ func (p *Poster) ParseMessage(msg string, context Context) (handled bool, err er
 
 	for _, cmd := range commands {
 		if msgParts[0] == cmd.Command {
-			log.Println("Command called:", msg)
 			if context.Poster == nil {
 				context.Poster = p
 			}
+
+			// bot admins can always use commands, e.g. to unlock the room again
+			if !p.acceptsCommands(context.RoomID) && !p.isBotAdmin(context.Sender) {
+				log.Debugln("Command ignored in locked room:", context.RoomID)
+				handled = false
+				return
+			}
+
+			if !p.hasPermission(context, cmd.Permission) {
+				log.Warnf("Command %s refused for %s in %s", cmd.Command, context.Sender, context.RoomID)
+				text := fmt.Sprintf("permission denied: %s needs %s privileges", cmd.Command, cmd.Permission.ToString())
+				simpleResponse(context, text)
+				handled = true
+				return
+			}
+
+			log.Println("Command called:", msg)
 			handled, err = cmd.Callback(msgParts, context)
 			return
 		}
@@ -91,7 +119,7 @@ func jsonResponse(context Context, title, roomID string, jsonData []byte) {
 }
 
 func adminPostRoomState(context Context) (err error) {
-	rs, ok := context.Poster.roomStates[context.RoomID]
+	rs, ok := context.Poster.GetRoomState(context.RoomID)
 	if !ok {
 		simpleResponse(context, "room state doesn't exist")
 		return
@@ -105,13 +133,9 @@ func adminPostRoomState(context Context) (err error) {
 }
 
 func adminClearRoomState(context Context) (err error) {
-	rs, ok := context.Poster.roomStates[context.RoomID]
-	if !ok {
+	if !context.Poster.ClearRoomState(context.RoomID) {
 		simpleResponse(context, "room state doesn't exist")
-		return
 	}
-
-	rs.clear(true, true)
 	return
 }
 
@@ -125,6 +149,55 @@ func adminPostRoomConfig(context Context) (err error) {
 	return
 }
 
+func adminSetAcceptCommands(args []string, context Context) (err error) {
+	arg := NewArgParser(args)
+	if arg.Count() != 3 {
+		simpleResponse(context, "Usage: admin commands <on|off>\nAllow room members to use commands or lock the room to notifications only.")
+		return
+	}
+
+	value, _ := arg.AsString(2)
+	if value != "on" && value != "off" {
+		simpleResponse(context, "invalid parameter")
+		return
+	}
+
+	change := &RoomConfigChange{
+		ChangeAcceptCommands: true,
+	}
+	newValues := &RoomConfig{
+		AcceptCommands: value == "on",
+	}
+	err2 := context.Poster.ChangeRoomConfig(context.RoomID, change, newValues)
+	if err2 == nil {
+		text := fmt.Sprintf("commands turned %s for this room", value)
+		simpleResponse(context, text)
+	} else {
+		text := fmt.Sprintf("failed: %s", err2.Error())
+		simpleResponse(context, text)
+	}
+	return
+}
+
+func adminPostIngest(context Context) (err error) {
+	if context.Poster.Ingest == nil {
+		simpleResponse(context, "no ingest sources")
+		return
+	}
+
+	text := "Ingest:"
+	for _, src := range context.Poster.Ingest.Status() {
+		text += fmt.Sprintf("\nSource %s: %s", src.Name, src.Counters.ToString())
+	}
+	if status, ok := context.Poster.Ingest.QueueStatus(); ok {
+		text += fmt.Sprintf("\nQueue: %s", status.ToString())
+	} else {
+		text += "\nQueue: none"
+	}
+	simpleResponse(context, text)
+	return
+}
+
 func adminCallback(args []string, context Context) (handled bool, err error) {
 	handled = true
 
@@ -140,12 +213,16 @@ func adminCallback(args []string, context Context) (handled bool, err error) {
 		err = adminPostRoomState(context)
 	case "roomstate_clear":
 		err = adminClearRoomState(context)
+	case "commands":
+		err = adminSetAcceptCommands(args, context)
+	case "ingest":
+		err = adminPostIngest(context)
 	case "shutdown":
 		context.Poster.saveStateAndQuit <- true
 	case "help":
 		fallthrough
 	default:
-		simpleResponse(context, "Usage: admin [roomconfig|roomstate[_clear]|shutdown]")
+		simpleResponse(context, "Usage: admin [roomconfig|roomstate[_clear]|commands|ingest|shutdown]")
 	}
 
 	return
@@ -158,17 +235,28 @@ func statusCallback(args []string, context Context) (handled bool, err error) {
 		return
 	}
 
+	startTime, lastDataTime := context.Poster.getStatusTimes()
+
 	emptyTime := time.Time{}
 	now := time.Now()
-	uptime := now.Sub(context.Poster.startTime)
+	uptime := now.Sub(startTime)
 
-	lastData := now.Sub(context.Poster.lastDataTime)
+	lastData := now.Sub(lastDataTime)
 	lastDataStr := "never"
-	if context.Poster.lastDataTime != emptyTime {
+	if lastDataTime != emptyTime {
 		lastDataStr = fmt.Sprintf("%s ago", lastData)
 	}
 
-	text := fmt.Sprintf("Bot uptime: %s\nLast MAD data: %s", uptime, lastDataStr)
+	text := fmt.Sprintf("Bot uptime: %s\nLast scanner data: %s", uptime, lastDataStr)
+	if context.Poster.Ingest != nil {
+		for _, src := range context.Poster.Ingest.Status() {
+			state := "running"
+			if !src.Running {
+				state = "stopped"
+			}
+			text += fmt.Sprintf("\nSource %s (%s): %s", src.Name, state, src.Counters.ToString())
+		}
+	}
 
 	simpleResponse(context, text)
 	return
@@ -247,15 +335,19 @@ func filterCallback(args []string, context Context) (handled bool, err error) {
 	switch subCmd {
 	case "add":
 		if arg.Count() != 6 {
-			simpleResponse(context, "Usage: filter add <raid|spawn> <lat> <lon> <radius_m>\nAdd a new filter that matches raids or spawns around the given location.")
+			simpleResponse(context, "Usage: filter add <raid|spawn|allraid|allspawn|hundo|nundo> <lat> <lon> <radius_m>\n"+
+				"Add a new filter that matches raids or spawns around the given location.\n"+
+				"raid and spawn filters post the Pokemon you add to them, "+
+				"allraid and allspawn filters post everything except the Pokemon you add to them.\n"+
+				"hundo and nundo filters post spawns of every species with 100% or 0% IV.")
 			return
 		}
 
 		// parse args
 		typ, err2 := arg.AsString(2)
-		listRaids := typ == "raid"
-		listWanted := typ == "spawn"
-		validTyp := listRaids || listWanted
+		listRaids := typ == "raid" || typ == "allraid"
+		listWanted := typ == "raid" || typ == "spawn"
+		validTyp := listRaids || typ == "spawn" || typ == "allspawn" || typ == "hundo" || typ == "nundo"
 
 		area, err3 := arg.AsLocationRadius(3, 4, 5)
 		if err2 != nil || err3 != nil || !validTyp {
@@ -263,30 +355,22 @@ func filterCallback(args []string, context Context) (handled bool, err error) {
 			return
 		}
 
-		// check limits
-		if rc, ok := context.Poster.GetRoomConfig(context.RoomID); ok {
-			// RoomConfig exists
-			if len(rc.Filter) >= roomConfigFilterLimit {
-				simpleResponse(context, "you've reached the allowed limit of filters a room can have")
-				return
-			}
+		filter := PokemonFilter{
+			ListRaids:  listRaids,
+			ListWanted: listWanted,
+			PokemonIDs: []int{},
+			Area:       area,
 		}
-
-		// add roomconfig
-		rc := &RoomConfig{
-			RoomID:     context.RoomID,
-			FormatText: true,
-			Filter: []PokemonFilter{
-				{
-					ListRaids:  listRaids,
-					ListWanted: listWanted,
-					PokemonIDs: []int{},
-					Area:       area,
-				},
-			},
+		switch typ {
+		case "hundo":
+			filter.Encounter = &EncounterCondition{IV: &FloatRange{Min: 100, Max: 100}}
+		case "nundo":
+			filter.Encounter = &EncounterCondition{IV: &FloatRange{Min: 0, Max: 0}}
 		}
-		context.Poster.UpdateRoomConfig(rc)
-		simpleResponse(context, "added filter to roomconfig")
+
+		appendFilters(context, &RoomConfig{
+			Filter: []PokemonFilter{filter},
+		})
 	case "rm":
 		if arg.Count() != 3 {
 			simpleResponse(context, "Usage: spawn rm <filter_id>\nRemove filter from RoomConfig.")
@@ -343,6 +427,34 @@ func filterCallback(args []string, context Context) (handled bool, err error) {
 			text := fmt.Sprintf("failed: %s", err.Error())
 			simpleResponse(context, text)
 		}
+	case "weatheralerts":
+		if arg.Count() != 3 {
+			simpleResponse(context, "Usage: filter weatheralerts <on|off>\nPost severe weather warnings for the filter areas.")
+			return
+		}
+
+		value, _ := arg.AsString(2)
+		if value != "on" && value != "off" {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		change := &RoomConfigChange{
+			ChangeWeatherAlerts: true,
+		}
+		newValues := &RoomConfig{
+			WeatherAlerts: value == "on",
+		}
+		err := context.Poster.ChangeRoomConfig(context.RoomID, change, newValues)
+		if err == nil {
+			text := fmt.Sprintf("weather alerts turned %s for this room", value)
+			simpleResponse(context, text)
+		} else {
+			text := fmt.Sprintf("failed: %s", err.Error())
+			simpleResponse(context, text)
+		}
+	case "iv", "stats", "cp", "level", "anystats":
+		changeFilterEncounter(subCmd, arg, context)
 	case "drop":
 		simpleResponse(context, "this removes ALL filters from this room IRREVOCABLY! type \"filter dropreally\" if you really intend to do this.")
 	case "dropreally":
@@ -355,11 +467,178 @@ func filterCallback(args []string, context Context) (handled bool, err error) {
 	case "help":
 		fallthrough
 	default:
-		simpleResponse(context, "Usage: filter [add|rm|area|drop]")
+		simpleResponse(context, "Usage: filter [add|rm|area|iv|stats|cp|level|anystats|weatheralerts|drop]")
 	}
 	return
 }
 
+// appendFilters adds the filters of all types in newValues to the room's config.
+// The RoomConfig is created with default settings if the room doesn't have one yet.
+func appendFilters(context Context, newValues *RoomConfig) {
+	if rc, ok := context.Poster.GetRoomConfig(context.RoomID); ok {
+		// RoomConfig exists, check limits
+		if rc.filterCount() >= roomConfigFilterLimit {
+			simpleResponse(context, "you've reached the allowed limit of filters a room can have")
+			return
+		}
+
+		// append filter and keep the room's settings
+		change := &RoomConfigChange{
+			Operation: RoomConfigOperationAppendFilter,
+		}
+		if err := context.Poster.ChangeRoomConfig(context.RoomID, change, newValues); err != nil {
+			text := fmt.Sprintf("failed: %s", err.Error())
+			simpleResponse(context, text)
+			return
+		}
+	} else {
+		// add roomconfig
+		newValues.RoomID = context.RoomID
+		newValues.Version = roomConfigVersion
+		newValues.AcceptCommands = true
+		newValues.FormatText = true
+		context.Poster.UpdateRoomConfig(newValues)
+	}
+	simpleResponse(context, "added filter to roomconfig")
+}
+
+// changeFilterEncounter handles the filter subcommands for IV/CP/level conditions of spawn filters
+func changeFilterEncounter(subCmd string, arg *ArgParser, context Context) {
+	usage := map[string]string{
+		"iv":       "Usage: filter iv <filter_id> <min_percent> [max_percent=100]\nOnly post spawns with IVs in this range.",
+		"stats":    "Usage: filter stats <filter_id> <min_atk> <min_def> <min_sta>\nOnly post spawns with at least these IVs.",
+		"cp":       "Usage: filter cp <filter_id> <min_cp> [max_cp]\nOnly post spawns with CP in this range.",
+		"level":    "Usage: filter level <filter_id> <min_level> [max_level]\nOnly post spawns with a level in this range.",
+		"anystats": "Usage: filter anystats <filter_id>\nRemove all IV/CP/level conditions from the filter.",
+	}
+
+	validCount := false
+	switch subCmd {
+	case "iv", "cp", "level":
+		validCount = arg.Count() == 4 || arg.Count() == 5
+	case "stats":
+		validCount = arg.Count() == 6
+	case "anystats":
+		validCount = arg.Count() == 3
+	}
+	if !validCount {
+		simpleResponse(context, usage[subCmd])
+		return
+	}
+
+	filterID, err := arg.AsInt(2)
+	if err != nil {
+		simpleResponse(context, "invalid parameter")
+		return
+	}
+
+	// only the given condition changes, the Poster keeps the others
+	condition := &EncounterCondition{}
+	var filterChange FilterChange
+	valid := false
+	switch subCmd {
+	case "iv":
+		filterChange = FilterChangeEncounterIV
+		condition.IV, valid = parseFloatRange(arg, 3, 100)
+	case "stats":
+		filterChange = FilterChangeEncounterStats
+		condition.MinAttack, condition.MinDefense, condition.MinStamina, valid = parseStats(arg, 3)
+	case "cp":
+		filterChange = FilterChangeEncounterCP
+		condition.CP, valid = parseIntRange(arg, 3)
+	case "level":
+		filterChange = FilterChangeEncounterLevel
+		condition.Level, valid = parseIntRange(arg, 3)
+	case "anystats":
+		filterChange = FilterChangeEncounter
+		condition = nil
+		valid = true
+	}
+	if !valid {
+		simpleResponse(context, "invalid parameter")
+		return
+	}
+
+	change := &RoomConfigChange{
+		Operation:    RoomConfigOperationUpdateFilter,
+		FilterIndex:  filterID,
+		FilterChange: filterChange,
+	}
+	newValues := &RoomConfig{
+		Filter: []PokemonFilter{
+			{
+				Encounter: condition,
+			},
+		},
+	}
+	if err = context.Poster.ChangeRoomConfig(context.RoomID, change, newValues); err != nil {
+		text := fmt.Sprintf("failed: %s", err.Error())
+		simpleResponse(context, text)
+		return
+	}
+
+	if condition == nil {
+		simpleResponse(context, "removed filter conditions")
+		return
+	}
+	// show all conditions of the filter, not just the changed one
+	if rc, ok := context.Poster.GetRoomConfig(context.RoomID); ok && filterID < len(rc.Filter) && rc.Filter[filterID].Encounter != nil {
+		condition = rc.Filter[filterID].Encounter
+	}
+	simpleResponse(context, fmt.Sprintf("filter conditions updated: %s", condition.ToString()))
+}
+
+// parseFloatRange parses "<min> [max]" from the given index, max defaults to upperLimit
+func parseFloatRange(arg *ArgParser, index int, upperLimit float64) (r *FloatRange, valid bool) {
+	min, err := arg.AsFloat(index)
+	if err != nil {
+		return
+	}
+	max := upperLimit
+	if arg.Count() > index+1 {
+		if max, err = arg.AsFloat(index + 1); err != nil {
+			return
+		}
+	}
+	r = &FloatRange{Min: min, Max: max}
+	if !r.valid(upperLimit) {
+		return nil, false
+	}
+	return r, true
+}
+
+// parseIntRange parses "<min> [max]" from the given index, no max means there's no upper limit
+func parseIntRange(arg *ArgParser, index int) (r *IntRange, valid bool) {
+	min, err := arg.AsInt(index)
+	if err != nil {
+		return
+	}
+	max := 0
+	if arg.Count() > index+1 {
+		if max, err = arg.AsInt(index + 1); err != nil {
+			return
+		}
+	}
+	r = &IntRange{Min: min, Max: max}
+	if !r.valid() {
+		return nil, false
+	}
+	return r, true
+}
+
+// parseStats parses attack, defense and stamina IVs starting at the given index
+func parseStats(arg *ArgParser, index int) (attack, defense, stamina int, valid bool) {
+	stats := make([]int, 3)
+	for i := range stats {
+		v, err := arg.AsInt(index + i)
+		if err != nil || !validStat(v) {
+			return
+		}
+		stats[i] = v
+	}
+	return stats[0], stats[1], stats[2], true
+}
+
 func changeFilterMon(verb changeFilterMonType, args []string, context Context) (handled bool, err error) {
 	handled = true
 	arg := NewArgParser(args)
@@ -372,7 +651,7 @@ func changeFilterMon(verb changeFilterMonType, args []string, context Context) (
 	switch subCmd {
 	case "add":
 		if arg.Count() != 4 {
-			text := fmt.Sprintf("Usage: %s add <filter_id> <pkmn_id[,id2[,id3...]]>\nAppend Pokemon ID(s) to filter.", verb)
+			text := fmt.Sprintf("Usage: %s add <filter_id> <pkmn_id[,id2[,id3...]]>\nAppend Pokemon ID(s) to filter. They are excluded from all%s filters.", verb, verb)
 			simpleResponse(context, text)
 			return
 		}
@@ -461,6 +740,435 @@ func spawnCallback(args []string, context Context) (handled bool, err error) {
 	return changeFilterMon(changeFilterMonSpawn, args, context)
 }
 
+func eggCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	arg := NewArgParser(args)
+
+	subCmd := "help"
+	if arg.Count() >= 2 {
+		subCmd, _ = arg.AsString(1)
+	}
+
+	var filterChange FilterChange
+	var successText string
+	switch subCmd {
+	case "add":
+		filterChange = FilterChangeAddRaidLevels
+		successText = "added to filter"
+	case "rm":
+		filterChange = FilterChangeRemoveRaidLevels
+		successText = "removed from filter"
+	default:
+		simpleResponse(context, "Usage: egg [add|rm]")
+		return
+	}
+
+	if arg.Count() != 4 {
+		text := fmt.Sprintf("Usage: egg %s <filter_id> <level[,level2...]>\nPost raid eggs of these levels (1-5 or mega) for a raid filter, what hatched follows unless the filter rules out the boss.", subCmd)
+		simpleResponse(context, text)
+		return
+	}
+
+	filterID, err2 := arg.AsInt(2)
+	levels, err3 := arg.AsRaidLevelArray(3)
+	if err2 != nil || err3 != nil {
+		simpleResponse(context, "invalid parameter")
+		return
+	}
+
+	change := &RoomConfigChange{
+		Operation:    RoomConfigOperationUpdateFilter,
+		FilterIndex:  filterID,
+		FilterChange: filterChange,
+	}
+	newValues := &RoomConfig{
+		Filter: []PokemonFilter{
+			{
+				RaidLevels: levels,
+			},
+		},
+	}
+	err2 = context.Poster.ChangeRoomConfig(context.RoomID, change, newValues)
+	if err2 == nil {
+		simpleResponse(context, successText)
+	} else {
+		text := fmt.Sprintf("failed: %s", err2.Error())
+		simpleResponse(context, text)
+	}
+	return
+}
+
+func weatherCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	if context.Poster == nil {
+		simpleResponse(context, "not ready")
+		return
+	}
+
+	arg := NewArgParser(args)
+	if arg.Count() != 3 {
+		simpleResponse(context, "Usage: weather <lat> <lon>\nShow the current weather at the location.")
+		return
+	}
+
+	location, err2 := arg.AsLocation(1, 2)
+	if err2 != nil {
+		simpleResponse(context, "invalid float")
+		return
+	}
+
+	w, ok := context.Poster.GetWeather(&location)
+	if !ok {
+		simpleResponse(context, "no weather data for this location")
+		return
+	}
+	simpleResponse(context, weatherToString(&w))
+	return
+}
+
+func invasionCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	arg := NewArgParser(args)
+
+	subCmd := "help"
+	if arg.Count() >= 2 {
+		subCmd, _ = arg.AsString(1)
+	}
+
+	switch subCmd {
+	case "add":
+		if arg.Count() != 5 && arg.Count() != 6 {
+			simpleResponse(context, "Usage: invasion add <lat> <lon> <radius_m> [grunt_type[,type2...]]\n"+
+				"Post Team GO Rocket invasions around the given location. "+
+				"Grunt types are character ids, \"leaders\" or \"giovanni\". Without grunt types all invasions are posted.")
+			return
+		}
+
+		area, err2 := arg.AsLocationRadius(2, 3, 4)
+		gruntTypes := []int{}
+		var err3 error
+		if arg.Count() == 6 {
+			gruntTypes, err3 = arg.AsGruntTypeArray(5)
+		}
+		if err2 != nil || err3 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		appendFilters(context, &RoomConfig{
+			Invasions: []InvasionFilter{
+				{
+					Area:       area,
+					GruntTypes: gruntTypes,
+				},
+			},
+		})
+	case "rm":
+		if arg.Count() != 3 {
+			simpleResponse(context, "Usage: invasion rm <filter_id>\nRemove invasion filter from RoomConfig.")
+			return
+		}
+
+		filterID, err2 := arg.AsInt(2)
+		if err2 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		change := &RoomConfigChange{
+			Operation:   RoomConfigOperationRemoveFilter,
+			FilterIndex: filterID,
+			FilterList:  FilterListInvasion,
+		}
+		err2 = context.Poster.ChangeRoomConfig(context.RoomID, change, &RoomConfig{})
+		if err2 == nil {
+			simpleResponse(context, "removed invasion filter")
+		} else {
+			text := fmt.Sprintf("failed: %s", err2.Error())
+			simpleResponse(context, text)
+		}
+	case "help":
+		fallthrough
+	default:
+		simpleResponse(context, "Usage: invasion [add|rm]")
+	}
+	return
+}
+
+func gymCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	if context.Poster == nil {
+		simpleResponse(context, "not ready")
+		return
+	}
+
+	if len(args) < 2 {
+		simpleResponse(context, "Usage: gym <GUID|name>\nShow the gym's team and its last team changes.")
+		return
+	}
+
+	query := strings.Join(args[1:], " ")
+	if gs, ok := context.Poster.GetGymState(query); ok {
+		simpleResponse(context, context.Poster.gymStateToString(gs))
+		return
+	}
+
+	found := context.Poster.FindGymStates(query)
+	switch {
+	case len(found) == 0:
+		simpleResponse(context, "no gym found")
+	case len(found) == 1:
+		simpleResponse(context, context.Poster.gymStateToString(found[0]))
+	default:
+		text := fmt.Sprintf("%d gyms found, use the GUID:", len(found))
+		for i, gs := range found {
+			if i == 10 {
+				text += "\n..."
+				break
+			}
+			text += fmt.Sprintf("\n%s: %s", gs.GUID, context.Poster.getFortNameOr(gs.GUID, gs.Name))
+		}
+		simpleResponse(context, text)
+	}
+	return
+}
+
+func takeoverCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	arg := NewArgParser(args)
+
+	subCmd := "help"
+	if arg.Count() >= 2 {
+		subCmd, _ = arg.AsString(1)
+	}
+
+	switch subCmd {
+	case "add":
+		filter := GymFilter{
+			GymIDs: []string{},
+			Teams:  []int{},
+		}
+		var err2, err3 error
+		switch arg.Count() {
+		case 3, 4:
+			guids, _ := arg.AsString(2)
+			for _, guid := range strings.Split(guids, ",") {
+				if !geodex.IsValidGUID(guid) {
+					err2 = errors.New("invalid GUID")
+				}
+				filter.GymIDs = append(filter.GymIDs, guid)
+			}
+			if arg.Count() == 4 {
+				filter.Teams, err3 = arg.AsTeamArray(3)
+			}
+		case 5, 6:
+			filter.Area, err2 = arg.AsLocationRadius(2, 3, 4)
+			if arg.Count() == 6 {
+				filter.Teams, err3 = arg.AsTeamArray(5)
+			}
+		default:
+			simpleResponse(context, "Usage: takeover add <GUID[,GUID2...]|<lat> <lon> <radius_m>> [team[,team2...]]\n"+
+				"Post when the given gyms or gyms around the given location change teams. "+
+				"Teams are white, blue, red or yellow. Without teams all changes are posted.")
+			return
+		}
+		if err2 != nil || err3 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		appendFilters(context, &RoomConfig{
+			Gyms: []GymFilter{filter},
+		})
+	case "rm":
+		if arg.Count() != 3 {
+			simpleResponse(context, "Usage: takeover rm <filter_id>\nRemove gym filter from RoomConfig.")
+			return
+		}
+
+		filterID, err2 := arg.AsInt(2)
+		if err2 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		change := &RoomConfigChange{
+			Operation:   RoomConfigOperationRemoveFilter,
+			FilterIndex: filterID,
+			FilterList:  FilterListGym,
+		}
+		err2 = context.Poster.ChangeRoomConfig(context.RoomID, change, &RoomConfig{})
+		if err2 == nil {
+			simpleResponse(context, "removed gym filter")
+		} else {
+			text := fmt.Sprintf("failed: %s", err2.Error())
+			simpleResponse(context, text)
+		}
+	case "help":
+		fallthrough
+	default:
+		simpleResponse(context, "Usage: takeover [add|rm]")
+	}
+	return
+}
+
+func lureCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	arg := NewArgParser(args)
+
+	subCmd := "help"
+	if arg.Count() >= 2 {
+		subCmd, _ = arg.AsString(1)
+	}
+
+	switch subCmd {
+	case "add":
+		if arg.Count() != 5 && arg.Count() != 6 {
+			simpleResponse(context, "Usage: lure add <lat> <lon> <radius_m> [lure_type[,type2...]]\n"+
+				"Post lure modules around the given location. "+
+				"Lure types are normal, glacial, mossy, magnetic, rainy, sparkly or item ids. Without lure types all lures are posted.")
+			return
+		}
+
+		area, err2 := arg.AsLocationRadius(2, 3, 4)
+		lureTypes := []int{}
+		var err3 error
+		if arg.Count() == 6 {
+			lureTypes, err3 = arg.AsLureTypeArray(5)
+		}
+		if err2 != nil || err3 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		appendFilters(context, &RoomConfig{
+			Lures: []LureFilter{
+				{
+					Area:      area,
+					LureTypes: lureTypes,
+				},
+			},
+		})
+	case "rm":
+		if arg.Count() != 3 {
+			simpleResponse(context, "Usage: lure rm <filter_id>\nRemove lure filter from RoomConfig.")
+			return
+		}
+
+		filterID, err2 := arg.AsInt(2)
+		if err2 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		change := &RoomConfigChange{
+			Operation:   RoomConfigOperationRemoveFilter,
+			FilterIndex: filterID,
+			FilterList:  FilterListLure,
+		}
+		err2 = context.Poster.ChangeRoomConfig(context.RoomID, change, &RoomConfig{})
+		if err2 == nil {
+			simpleResponse(context, "removed lure filter")
+		} else {
+			text := fmt.Sprintf("failed: %s", err2.Error())
+			simpleResponse(context, text)
+		}
+	case "help":
+		fallthrough
+	default:
+		simpleResponse(context, "Usage: lure [add|rm]")
+	}
+	return
+}
+
+// questRewardTypes maps the reward names of the quest command
+var questRewardTypes = map[string]pogo.QuestRewardType{
+	"encounter": pogo.QuestRewardPokemonEncounter,
+	"item":      pogo.QuestRewardItem,
+	"stardust":  pogo.QuestRewardStardust,
+	"mega":      pogo.QuestRewardMegaEnergy,
+	"candy":     pogo.QuestRewardCandy,
+	"xlcandy":   pogo.QuestRewardXLCandy,
+}
+
+func questCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	arg := NewArgParser(args)
+
+	subCmd := "help"
+	if arg.Count() >= 2 {
+		subCmd, _ = arg.AsString(1)
+	}
+
+	switch subCmd {
+	case "add":
+		if arg.Count() != 6 && arg.Count() != 7 {
+			simpleResponse(context, "Usage: quest add <encounter|item|stardust|mega|candy|xlcandy> <lat> <lon> <radius_m> [id[,id2...]|min_amount]\n"+
+				"Post field research quests with this reward around the given location. "+
+				"Limit it to pokemon ids (item ids for item rewards), or the minimum amount for stardust rewards.")
+			return
+		}
+
+		typ, _ := arg.AsString(2)
+		rewardType, validTyp := questRewardTypes[typ]
+		area, err2 := arg.AsLocationRadius(3, 4, 5)
+		if err2 != nil || !validTyp {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		filter := QuestFilter{
+			Area:       area,
+			RewardType: rewardType,
+			RewardIDs:  []int{},
+		}
+		if arg.Count() == 7 {
+			if rewardType == pogo.QuestRewardStardust {
+				filter.MinAmount, err2 = arg.AsInt(6)
+			} else {
+				filter.RewardIDs, err2 = arg.AsIntArray(6)
+			}
+			if err2 != nil {
+				simpleResponse(context, "invalid parameter")
+				return
+			}
+		}
+
+		appendFilters(context, &RoomConfig{
+			Quests: []QuestFilter{filter},
+		})
+	case "rm":
+		if arg.Count() != 3 {
+			simpleResponse(context, "Usage: quest rm <filter_id>\nRemove quest filter from RoomConfig.")
+			return
+		}
+
+		filterID, err2 := arg.AsInt(2)
+		if err2 != nil {
+			simpleResponse(context, "invalid parameter")
+			return
+		}
+
+		change := &RoomConfigChange{
+			Operation:   RoomConfigOperationRemoveFilter,
+			FilterIndex: filterID,
+			FilterList:  FilterListQuest,
+		}
+		err2 = context.Poster.ChangeRoomConfig(context.RoomID, change, &RoomConfig{})
+		if err2 == nil {
+			simpleResponse(context, "removed quest filter")
+		} else {
+			text := fmt.Sprintf("failed: %s", err2.Error())
+			simpleResponse(context, text)
+		}
+	case "help":
+		fallthrough
+	default:
+		simpleResponse(context, "Usage: quest [add|rm]")
+	}
+	return
+}
+
 func fortCallback(args []string, context Context) (handled bool, err error) {
 	handled = true
 
@@ -497,13 +1205,13 @@ func fortCallback(args []string, context Context) (handled bool, err error) {
 			Latitude:  lat,
 			Longitude: lon,
 		}
-		fort, err := context.Poster.GeoDex.Tile.GetNearestFort(center, radiusM)
+		fort, err := context.Poster.GeoDex.Index.GetNearestFort(center, radiusM)
 		if err != nil {
 			text := fmt.Sprintf("no fort found near (%f,%f) in %f m radius",
 				center.Latitude, center.Longitude, radiusM)
 			simpleResponse(context, text)
 		} else {
-			text := fmt.Sprintf("t38: %s", fort.ToString())
+			text := fmt.Sprintf("index: %s", fort.ToString())
 
 			// lookup name from geodex
 			nFort, err := context.Poster.GeoDex.Disk.GetFort(*fort.GUID)
@@ -511,7 +1219,7 @@ func fortCallback(args []string, context Context) (handled bool, err error) {
 				text = fmt.Sprintf("%s\ndisk: %s", text, nFort.ToString())
 			} else {
 				text = fmt.Sprintf("%s\ndisk: not found", text)
-				log.WithError(err).Warnf("guid %s from t38 is not on disk", *fort.GUID)
+				log.WithError(err).Warnf("guid %s from the index is not on disk", *fort.GUID)
 			}
 
 			text = fmt.Sprintf("%s\ndistance from fort to (%f,%f): %fm, bearing %f°",
@@ -548,3 +1256,95 @@ func fortCallback(args []string, context Context) (handled bool, err error) {
 
 	return
 }
+
+func webCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	if context.Poster == nil {
+		simpleResponse(context, "not ready")
+		return
+	}
+	if context.Poster.WebUI == nil {
+		simpleResponse(context, "web UI is disabled")
+		return
+	}
+	if _, ok := context.Poster.GetRoomConfig(context.RoomID); !ok {
+		simpleResponse(context, "roomconfig doesn't exist, add a filter first")
+		return
+	}
+
+	link, err := context.Poster.WebUI.CreateLoginLink(context.RoomID, context.Sender)
+	if err != nil {
+		simpleResponse(context, fmt.Sprintf("failed: %s", err.Error()))
+		return
+	}
+	simpleResponse(context, fmt.Sprintf("Edit this room's filters on a map, the link works once within 10 minutes: %s", link))
+	return
+}
+
+// streamInstructions tells how to connect, the header keeps the token out of access logs
+func streamInstructions(url, token string) string {
+	return fmt.Sprintf("%s with the header Authorization: Bearer %s (or ?token=%s for clients that can't set headers)", url, token, token)
+}
+
+func streamCallback(args []string, context Context) (handled bool, err error) {
+	handled = true
+	if context.Poster == nil {
+		simpleResponse(context, "not ready")
+		return
+	}
+	if context.Poster.Stream == nil {
+		simpleResponse(context, "event stream is disabled")
+		return
+	}
+	rc, ok := context.Poster.GetRoomConfig(context.RoomID)
+	if !ok {
+		simpleResponse(context, "roomconfig doesn't exist, add a filter first")
+		return
+	}
+
+	arg := NewArgParser(args)
+	subCmd, _ := arg.AsString(1)
+	switch {
+	case arg.Count() == 1:
+		if rc.StreamToken == "" {
+			simpleResponse(context, "event stream is off, turn it on with: stream on")
+		} else {
+			simpleResponse(context, "event stream: "+streamInstructions(context.Poster.Stream.StreamURL(rc.RoomID), rc.StreamToken))
+		}
+	case arg.Count() == 2 && (subCmd == "on" || subCmd == "off"):
+		newValues := &RoomConfig{}
+		if subCmd == "on" {
+			newValues.StreamToken, err = newStreamToken()
+			if err != nil {
+				simpleResponse(context, fmt.Sprintf("failed: %s", err.Error()))
+				return
+			}
+		}
+		err = context.Poster.ChangeRoomConfig(context.RoomID, &RoomConfigChange{ChangeStreamToken: true}, newValues)
+		if err != nil {
+			simpleResponse(context, fmt.Sprintf("failed: %s", err.Error()))
+			return
+		}
+
+		if subCmd == "on" {
+			url := context.Poster.Stream.StreamURL(rc.RoomID)
+			simpleResponse(context, fmt.Sprintf("event stream turned on, anyone with this token can follow the spawns and raids posted here: %s\nUse stream on again to replace the token.",
+				streamInstructions(url, newValues.StreamToken)))
+		} else {
+			simpleResponse(context, "event stream turned off")
+		}
+	default:
+		simpleResponse(context, "Usage: stream [on|off]\nFollow the spawns and raids posted in this room as Server-Sent Events.")
+	}
+	return
+}
+
+// newStreamToken returns a random token for RoomConfig.StreamToken
+func newStreamToken() (token string, err error) {
+	buf := make([]byte, 16)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+	token = hex.EncodeToString(buf)
+	return
+}
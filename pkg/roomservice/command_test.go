@@ -1,9 +1,12 @@
 package roomservice
 
 import (
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,10 +34,11 @@ func TestParseMessage(t *testing.T) {
 	c.ExpectNoMessage(t)
 	assert.Equal(t, false, handled)
 
+	// privileged commands are refused without a poster to check permissions
 	handled, _ = p.ParseMessage("admin", ctx)
 	c.ExpectMessage(t)
 	assert.Equal(t, true, handled)
-	assert.Contains(t, c.LastText, "Usage: admin")
+	assert.Contains(t, c.LastText, "permission denied")
 	assert.Equal(t, roomID, c.LastRoomID)
 
 	handled, _ = p.ParseMessage("status", ctx)
@@ -52,19 +56,19 @@ func TestParseMessage(t *testing.T) {
 	handled, _ = p.ParseMessage("filter", ctx)
 	c.ExpectMessage(t)
 	assert.Equal(t, true, handled)
-	assert.Contains(t, c.LastText, "Usage: filter")
+	assert.Contains(t, c.LastText, "permission denied")
 	assert.Equal(t, roomID, c.LastRoomID)
 
 	handled, _ = p.ParseMessage("spawn", ctx)
 	c.ExpectMessage(t)
 	assert.Equal(t, true, handled)
-	assert.Contains(t, c.LastText, "Usage: spawn")
+	assert.Contains(t, c.LastText, "permission denied")
 	assert.Equal(t, roomID, c.LastRoomID)
 
 	handled, _ = p.ParseMessage("raid", ctx)
 	c.ExpectMessage(t)
 	assert.Equal(t, true, handled)
-	assert.Contains(t, c.LastText, "Usage: raid")
+	assert.Contains(t, c.LastText, "permission denied")
 	assert.Equal(t, roomID, c.LastRoomID)
 
 	// test things that need the poster object
@@ -75,7 +79,7 @@ func TestParseMessage(t *testing.T) {
 	c.ExpectMessage(t)
 	assert.Equal(t, true, handled)
 	assert.Contains(t, c.LastText, "Bot uptime")
-	assert.Contains(t, c.LastText, "Last MAD data: never")
+	assert.Contains(t, c.LastText, "Last scanner data: never")
 	assert.Equal(t, roomID, c.LastRoomID)
 
 	p.startTime = time.Now()
@@ -85,9 +89,33 @@ func TestParseMessage(t *testing.T) {
 	c.PrintLastMessage()
 	assert.Equal(t, true, handled)
 	assert.Contains(t, c.LastText, "Bot uptime")
-	assert.Contains(t, c.LastText, "Last MAD data:")
-	assert.NotContains(t, c.LastText, "Last MAD data: never")
+	assert.Contains(t, c.LastText, "Last scanner data:")
+	assert.NotContains(t, c.LastText, "Last scanner data: never")
 	assert.Equal(t, roomID, c.LastRoomID)
+
+	p.Ingest = testIngestStatus{
+		{Name: "mad", Running: true, Counters: ingest.Counters{Events: map[string]uint64{ingest.EventSpawn: 3}}},
+		{Name: "rdm", Running: false, Counters: ingest.Counters{Errors: 1}},
+	}
+	p.ParseMessage("status", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "\nSource mad (running): 3 spawn, 0 errors")
+	assert.Contains(t, c.LastText, "\nSource rdm (stopped): 1 errors")
+}
+
+type testIngestStatus []ingest.SourceStatus
+
+func (s testIngestStatus) Status() []ingest.SourceStatus {
+	return s
+}
+
+func (s testIngestStatus) QueueStatus() (status ingest.QueueStatus, ok bool) {
+	return ingest.QueueStatus{
+		Length:   2,
+		Capacity: 10,
+		Policy:   ingest.PreferRaids,
+		Dropped:  map[string]uint64{ingest.EventSpawn: 5, ingest.EventGym: 1},
+	}, true
 }
 
 func TestParseSpawn(t *testing.T) {
@@ -97,11 +125,13 @@ func TestParseSpawn(t *testing.T) {
 		MessageReceived: make(chan bool, 1),
 	}
 	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
 	roomID := "!bar@example.com"
 	ctx := Context{
 		Chatter: c,
 		RoomID:  roomID,
 		Poster:  p,
+		Sender:  testAdminID,
 	}
 
 	var handled bool
@@ -200,6 +230,34 @@ func TestParseSpawn(t *testing.T) {
 	assert.Equal(t, 1, len(p.roomConfigs[roomID].Filter))
 	assert.Equal(t, 1, len(p.roomConfigs[roomID].Filter[0].PokemonIDs))
 	assert.Equal(t, 23, p.roomConfigs[roomID].Filter[0].PokemonIDs[0])
+
+	// exclusion filters
+	handled, _ = p.ParseMessage("filter add allspawn 0 0 200", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, 2, len(p.roomConfigs[roomID].Filter))
+	assert.Equal(t, false, p.roomConfigs[roomID].Filter[1].ListWanted)
+	assert.Equal(t, false, p.roomConfigs[roomID].Filter[1].ListRaids)
+
+	handled, _ = p.ParseMessage("filter add allraid 0 0 200", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, 3, len(p.roomConfigs[roomID].Filter))
+	assert.Equal(t, false, p.roomConfigs[roomID].Filter[2].ListWanted)
+	assert.Equal(t, true, p.roomConfigs[roomID].Filter[2].ListRaids)
+
+	handled, _ = p.ParseMessage("filter add raid 0 0 200", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, 4, len(p.roomConfigs[roomID].Filter))
+	assert.Equal(t, true, p.roomConfigs[roomID].Filter[3].ListWanted)
+	assert.Equal(t, true, p.roomConfigs[roomID].Filter[3].ListRaids)
+
+	handled, _ = p.ParseMessage("filter add somespawn 0 0 200", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "invalid parameter", c.LastText)
+	assert.Equal(t, 4, len(p.roomConfigs[roomID].Filter))
 }
 
 func TestParseRaid(t *testing.T) {
@@ -209,11 +267,13 @@ func TestParseRaid(t *testing.T) {
 		MessageReceived: make(chan bool, 1),
 	}
 	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
 	roomID := "!bar@example.com"
 	ctx := Context{
 		Chatter: c,
 		RoomID:  roomID,
 		Poster:  p,
+		Sender:  testAdminID,
 	}
 
 	var handled bool
@@ -223,6 +283,513 @@ func TestParseRaid(t *testing.T) {
 	assert.Contains(t, c.LastText, "Usage: raid")
 }
 
+func TestParseEgg(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	var handled bool
+	handled, _ = p.ParseMessage("egg foo", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "Usage: egg")
+
+	handled, _ = p.ParseMessage("egg add 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "Usage: egg add")
+
+	// filter 0 is a spawn filter, filter 1 a raid filter
+	p.ParseMessage("filter add spawn 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter add raid 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+
+	p.ParseMessage("egg add 0 5", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	p.ParseMessage("egg add 1 7", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "invalid parameter", c.LastText)
+
+	p.ParseMessage("egg add 1 5,mega", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added to filter", c.LastText)
+
+	// no duplicates
+	p.ParseMessage("egg add 1 5", ctx)
+	c.ExpectMessage(t)
+	rc, _ := p.GetRoomConfig(roomID)
+	assert.Equal(t, []int{5, 6}, rc.Filter[1].RaidLevels)
+
+	p.ParseMessage("egg rm 1 Mega", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed from filter", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, []int{5}, rc.Filter[1].RaidLevels)
+}
+
+func TestParseFilterConditions(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	// no filter yet
+	p.ParseMessage("filter iv 0 90", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	// filter 0 is a spawn filter, filter 1 a raid filter, filter 2 a hundo filter
+	p.ParseMessage("filter add spawn 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter add raid 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter add hundo 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter add nundo 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+
+	rc, _ := p.GetRoomConfig(roomID)
+	assert.Equal(t, false, rc.Filter[2].ListWanted)
+	assert.Equal(t, &EncounterCondition{IV: &FloatRange{Min: 100, Max: 100}}, rc.Filter[2].Encounter)
+	assert.Equal(t, &EncounterCondition{IV: &FloatRange{Min: 0, Max: 0}}, rc.Filter[3].Encounter)
+
+	// usage
+	for _, cmd := range []string{"iv", "stats", "cp", "level", "anystats"} {
+		p.ParseMessage("filter "+cmd, ctx)
+		c.ExpectMessage(t)
+		assert.Contains(t, c.LastText, "Usage: filter "+cmd)
+	}
+
+	// invalid values
+	for _, cmd := range []string{"iv 0 101", "iv 0 50 40", "iv x 50", "stats 0 16 0 0", "cp 0 100 50", "level 0 -1"} {
+		p.ParseMessage("filter "+cmd, ctx)
+		c.ExpectMessage(t)
+		assert.Equal(t, "invalid parameter", c.LastText, cmd)
+	}
+
+	p.ParseMessage("filter iv 1 90", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	p.ParseMessage("filter iv 0 90", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "IV:90-100%")
+	p.ParseMessage("filter stats 0 15 0 10", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter cp 0 1500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter level 0 30 35", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "filter conditions updated: stats>=15/0/10 IV:90-100% CP:1500+ L:30-35", c.LastText)
+
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, &EncounterCondition{
+		IV:         &FloatRange{Min: 90, Max: 100},
+		MinAttack:  15,
+		MinStamina: 10,
+		CP:         &IntRange{Min: 1500},
+		Level:      &IntRange{Min: 30, Max: 35},
+	}, rc.Filter[0].Encounter)
+
+	p.ParseMessage("filter anystats 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed filter conditions", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Nil(t, rc.Filter[0].Encounter)
+}
+
+func TestParseWeather(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  "@user:example.com",
+	}
+
+	p.ParseMessage("weather", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: weather")
+
+	p.ParseMessage("weather foo bar", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "invalid float", c.LastText)
+
+	p.ParseMessage("weather 30.05 31.22", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "no weather data for this location", c.LastText)
+
+	w := getTestWeather()
+	w.AlertSeverity = pogo.AlertExtreme
+	p.processWeatherUpdate(w)
+	p.ParseMessage("weather 30.05 31.22", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Weather: Rainy (boosts Water, Electric, Bug)")
+	assert.Contains(t, c.LastText, "Severe weather warning: extreme")
+
+	// opt in
+	ctx.Sender = testAdminID
+	p.ParseMessage("filter weatheralerts", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: filter weatheralerts")
+	p.ParseMessage("filter weatheralerts on", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	p.ParseMessage("filter add spawn 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("filter weatheralerts maybe", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "invalid parameter", c.LastText)
+	p.ParseMessage("filter weatheralerts on", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "weather alerts turned on for this room", c.LastText)
+	rc, _ := p.GetRoomConfig(roomID)
+	assert.True(t, rc.WeatherAlerts)
+}
+
+func TestParseInvasion(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.ParseMessage("invasion", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "Usage: invasion [add|rm]", c.LastText)
+
+	p.ParseMessage("invasion add 30.0", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: invasion add")
+
+	p.ParseMessage("invasion add 30.0 31.0 500 foo", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "invalid parameter", c.LastText)
+
+	// creates the roomconfig
+	p.ParseMessage("invasion add 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+
+	p.ParseMessage("invasion add 30.0 31.0 500 leaders,Giovanni,4", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+
+	rc, _ := p.GetRoomConfig(roomID)
+	assert.True(t, rc.AcceptCommands)
+	assert.Equal(t, 0, len(rc.Filter))
+	if assert.Equal(t, 2, len(rc.Invasions)) {
+		assert.Equal(t, 0, len(rc.Invasions[0].GruntTypes))
+		assert.Equal(t, []int{41, 42, 43, 44, 4}, rc.Invasions[1].GruntTypes)
+	}
+
+	p.ParseMessage("invasion rm", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: invasion rm")
+
+	p.ParseMessage("invasion rm 2", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	p.ParseMessage("invasion rm 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed invasion filter", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	if assert.Equal(t, 1, len(rc.Invasions)) {
+		assert.Equal(t, []int{41, 42, 43, 44, 4}, rc.Invasions[0].GruntTypes)
+	}
+
+	// dropping all filters includes invasion filters
+	p.ParseMessage("filter dropreally", ctx)
+	c.ExpectMessage(t)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, 0, len(rc.Invasions))
+}
+
+func TestParseGym(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.processGymUpdate(pogo.Gym{GUID: "abc.16", Name: "Old Town Hall", TeamColor: pogo.Blue})
+	p.processGymUpdate(pogo.Gym{GUID: "def.16", Name: "Old Mill", TeamColor: pogo.Red})
+
+	p.ParseMessage("gym", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: gym")
+
+	p.ParseMessage("gym abc.16", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Old Town Hall (abc.16): blue")
+
+	p.ParseMessage("gym old mill", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Old Mill (def.16): red")
+
+	p.ParseMessage("gym old", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "2 gyms found")
+
+	p.ParseMessage("gym pyramid", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "no gym found", c.LastText)
+
+	p.ParseMessage("takeover", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "Usage: takeover [add|rm]", c.LastText)
+
+	for _, cmd := range []string{"takeover add foo!", "takeover add abc.16 green", "takeover add 30.0 31.0 x"} {
+		p.ParseMessage(cmd, ctx)
+		c.ExpectMessage(t)
+		assert.Equal(t, "invalid parameter", c.LastText, cmd)
+	}
+
+	p.ParseMessage("takeover add abc.16,def.16 red", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+	p.ParseMessage("takeover add 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+
+	rc, _ := p.GetRoomConfig(roomID)
+	if assert.Equal(t, 2, len(rc.Gyms)) {
+		assert.Equal(t, []string{"abc.16", "def.16"}, rc.Gyms[0].GymIDs)
+		assert.Equal(t, []int{int(pogo.Red)}, rc.Gyms[0].Teams)
+		assert.Equal(t, 500.0, rc.Gyms[1].Area.RadiusM)
+	}
+
+	p.ParseMessage("takeover rm 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed gym filter", c.LastText)
+}
+
+func TestParseLure(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.ParseMessage("lure", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "Usage: lure [add|rm]", c.LastText)
+
+	p.ParseMessage("lure add 30.0 31.0 500 glacial,foo", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "invalid parameter", c.LastText)
+
+	p.ParseMessage("lure add 30.0 31.0 500 glacial,magnetic", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+	p.ParseMessage("lure add 30.0 31.0 1000", ctx)
+	c.ExpectMessage(t)
+
+	rc, _ := p.GetRoomConfig(roomID)
+	if assert.Equal(t, 2, len(rc.Lures)) {
+		assert.Equal(t, []int{502, 504}, rc.Lures[0].LureTypes)
+		assert.Equal(t, 0, len(rc.Lures[1].LureTypes))
+	}
+
+	p.ParseMessage("lure rm 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed lure filter", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, 1, len(rc.Lures))
+}
+
+func TestParseQuest(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.ParseMessage("quest", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "Usage: quest [add|rm]", c.LastText)
+
+	p.ParseMessage("quest add encounter", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: quest add")
+
+	for _, cmd := range []string{"quest add foo 30.0 31.0 500", "quest add encounter 30.0 31.0 x", "quest add stardust 30.0 31.0 500 1,2"} {
+		p.ParseMessage(cmd, ctx)
+		c.ExpectMessage(t)
+		assert.Equal(t, "invalid parameter", c.LastText, cmd)
+	}
+
+	p.ParseMessage("quest add encounter 30.0 31.0 500 147,246", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+	p.ParseMessage("quest add stardust 30.0 31.0 500 1000", ctx)
+	c.ExpectMessage(t)
+	p.ParseMessage("quest add mega 30.0 31.0 500", ctx)
+	c.ExpectMessage(t)
+
+	rc, _ := p.GetRoomConfig(roomID)
+	if assert.Equal(t, 3, len(rc.Quests)) {
+		assert.Equal(t, pogo.QuestRewardPokemonEncounter, rc.Quests[0].RewardType)
+		assert.Equal(t, []int{147, 246}, rc.Quests[0].RewardIDs)
+		assert.Equal(t, pogo.QuestRewardStardust, rc.Quests[1].RewardType)
+		assert.Equal(t, 1000, rc.Quests[1].MinAmount)
+		assert.Equal(t, pogo.QuestRewardMegaEnergy, rc.Quests[2].RewardType)
+		assert.Equal(t, 0, len(rc.Quests[2].RewardIDs))
+	}
+
+	p.ParseMessage("quest rm 3", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "failed")
+
+	p.ParseMessage("quest rm 1", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "removed quest filter", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, 2, len(rc.Quests))
+	assert.Equal(t, pogo.QuestRewardMegaEnergy, rc.Quests[1].RewardType)
+}
+
+func TestParseWeb(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.ParseMessage("web", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "web UI is disabled", c.LastText)
+
+	links := &testLoginLinks{}
+	p.WebUI = links
+	p.ParseMessage("web", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "roomconfig doesn't exist, add a filter first", c.LastText)
+
+	p.UpdateRoomConfig(&RoomConfig{RoomID: roomID})
+	p.ParseMessage("web", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "https://silpht.example.com/ui/#login=abc")
+	assert.Equal(t, roomID, links.roomID)
+	assert.Equal(t, testAdminID, links.userID)
+}
+
+func TestParseStream(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  testAdminID,
+	}
+
+	p.ParseMessage("stream", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "event stream is disabled", c.LastText)
+
+	p.Stream = &testStream{}
+	p.ParseMessage("stream on", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "roomconfig doesn't exist, add a filter first", c.LastText)
+
+	p.UpdateRoomConfig(&RoomConfig{RoomID: roomID})
+	p.ParseMessage("stream", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "event stream is off, turn it on with: stream on", c.LastText)
+
+	p.ParseMessage("stream on", ctx)
+	c.ExpectMessage(t)
+	rc, _ := p.GetRoomConfig(roomID)
+	assert.Equal(t, 32, len(rc.StreamToken))
+	assert.Contains(t, c.LastText, "https://silpht.example.com/stream/"+roomID+" with the header Authorization: Bearer "+rc.StreamToken)
+
+	// a new token replaces the old one
+	p.ParseMessage("stream on", ctx)
+	c.ExpectMessage(t)
+	rc2, _ := p.GetRoomConfig(roomID)
+	assert.NotEqual(t, rc.StreamToken, rc2.StreamToken)
+	p.ParseMessage("stream", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "event stream: https://silpht.example.com/stream/"+roomID+" with the header Authorization: Bearer "+rc2.StreamToken+
+		" (or ?token="+rc2.StreamToken+" for clients that can't set headers)", c.LastText)
+
+	p.ParseMessage("stream off", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, "event stream turned off", c.LastText)
+	rc, _ = p.GetRoomConfig(roomID)
+	assert.Equal(t, "", rc.StreamToken)
+
+	p.ParseMessage("stream foo", ctx)
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Usage: stream")
+}
+
 func TestAdmin(t *testing.T) {
 	c := &testChatter{
 		// we need to buffer one message because we're running
@@ -230,12 +797,14 @@ func TestAdmin(t *testing.T) {
 		MessageReceived: make(chan bool, 1),
 	}
 	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
 	p.saveStateAndQuit = make(chan bool, 1)
 	roomID := "!bar@example.com"
 	ctx := Context{
 		Chatter: c,
 		RoomID:  roomID,
 		Poster:  p,
+		Sender:  testAdminID,
 	}
 
 	handled, _ := p.ParseMessage("admin help", ctx)
@@ -281,6 +850,19 @@ func TestAdmin(t *testing.T) {
 	assert.Contains(t, c.LastText, "RoomConfig")
 	assert.Contains(t, c.LastFormattedText, "<code>")
 
+	handled, _ = p.ParseMessage("admin ingest", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "no ingest sources", c.LastText)
+
+	p.Ingest = testIngestStatus{
+		{Name: "mad", Running: true, Counters: ingest.Counters{Events: map[string]uint64{ingest.EventRaid: 2}}},
+	}
+	handled, _ = p.ParseMessage("admin ingest", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "Ingest:\nSource mad: 2 raid, 0 errors\nQueue: 2/10 events (prefer-raids), 6 dropped, 5 spawn, 1 gym", c.LastText)
+
 	handled, _ = p.ParseMessage("admin shutdown", ctx)
 	c.ExpectNoMessage(t)
 	assert.Equal(t, true, handled)
@@ -298,6 +880,12 @@ func TestAdmin(t *testing.T) {
 }
 
 func TestCommandList(t *testing.T) {
+	// restore commands for the following tests
+	defer func(saved []Command) {
+		commands = saved
+		generateCommandList()
+	}(commands)
+
 	generateCommandList()
 	assert.Contains(t, commandList, "commands:")
 
@@ -305,3 +893,128 @@ func TestCommandList(t *testing.T) {
 	generateCommandList()
 	assert.Equal(t, "there are no commands", commandList)
 }
+
+func TestPermissions(t *testing.T) {
+	c := &testChatter{
+		// we need to buffer one message because we're running
+		// the sender in the same thread as the receiver
+		MessageReceived: make(chan bool, 1),
+	}
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	p.saveStateAndQuit = make(chan bool, 1)
+	userID := "@user:example.com"
+	modID := "@mod:example.com"
+	p.PowerLevels = &testPowerLevels{
+		levels: map[string]int{
+			modID:       50,
+			testAdminID: 0,
+		},
+	}
+	roomID := "!bar@example.com"
+	ctx := Context{
+		Chatter: c,
+		RoomID:  roomID,
+		Poster:  p,
+		Sender:  userID,
+	}
+
+	// everyone
+	handled, _ := p.ParseMessage("help", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "commands:")
+
+	// moderator and admin commands are refused for normal users
+	handled, _ = p.ParseMessage("filter add spawn 0 0 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "permission denied: filter needs moderator privileges", c.LastText)
+	assert.Equal(t, 0, len(p.roomConfigs))
+
+	handled, _ = p.ParseMessage("admin shutdown", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "permission denied: admin needs admin privileges", c.LastText)
+	assert.Equal(t, 0, len(p.saveStateAndQuit))
+
+	// moderators can change filters, but can't use admin commands
+	ctx.Sender = modID
+	handled, _ = p.ParseMessage("filter add spawn 0 0 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "added filter to roomconfig", c.LastText)
+	assert.Equal(t, true, p.roomConfigs[roomID].AcceptCommands)
+	assert.Equal(t, roomConfigVersion, p.roomConfigs[roomID].Version)
+
+	handled, _ = p.ParseMessage("admin roomstate_clear", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "permission denied")
+
+	// bot admins don't need a power level
+	ctx.Sender = testAdminID
+	handled, _ = p.ParseMessage("admin commands", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "Usage: admin commands")
+
+	handled, _ = p.ParseMessage("admin commands maybe", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "invalid parameter", c.LastText)
+
+	// lock the room
+	handled, _ = p.ParseMessage("admin commands off", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, "commands turned off for this room", c.LastText)
+	assert.Equal(t, false, p.roomConfigs[roomID].AcceptCommands)
+
+	// adding filters keeps the lock
+	handled, _ = p.ParseMessage("filter add raid 0 0 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, 2, len(p.roomConfigs[roomID].Filter))
+	assert.Equal(t, false, p.roomConfigs[roomID].AcceptCommands)
+
+	// everyone else is ignored in a locked room
+	ctx.Sender = modID
+	handled, _ = p.ParseMessage("filter rm 0", ctx)
+	c.ExpectNoMessage(t)
+	assert.Equal(t, false, handled)
+	ctx.Sender = userID
+	handled, _ = p.ParseMessage("help", ctx)
+	c.ExpectNoMessage(t)
+	assert.Equal(t, false, handled)
+
+	// unlock
+	ctx.Sender = testAdminID
+	handled, _ = p.ParseMessage("admin commands on", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Equal(t, true, p.roomConfigs[roomID].AcceptCommands)
+
+	ctx.Sender = userID
+	handled, _ = p.ParseMessage("help", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+
+	// failing power level lookups don't grant anything
+	p.PowerLevels = &testPowerLevels{
+		err: errors.New("homeserver unreachable"),
+	}
+	ctx.Sender = modID
+	handled, _ = p.ParseMessage("filter rm 0", ctx)
+	c.ExpectMessage(t)
+	assert.Equal(t, true, handled)
+	assert.Contains(t, c.LastText, "permission denied")
+	assert.Equal(t, 2, len(p.roomConfigs[roomID].Filter))
+}
+
+func TestPermissionToString(t *testing.T) {
+	assert.Equal(t, "everyone", PermissionEveryone.ToString())
+	assert.Equal(t, "moderator", PermissionModerator.ToString())
+	assert.Equal(t, "admin", PermissionAdmin.ToString())
+	assert.Equal(t, "invalid", Permission(23).ToString())
+}
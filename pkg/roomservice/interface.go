@@ -1,5 +1,7 @@
 package roomservice
 
+import "github.com/spezifisch/silphtelescope/pkg/ingest"
+
 // Chatter posts messages into a chatroom
 type Chatter interface {
 	SendText(roomID, text string)
@@ -11,6 +13,7 @@ type Persister interface {
 	SaveRoomConfig(roomID string, roomConfig *RoomConfig)
 	SaveRoomConfigs(roomConfigs map[string]*RoomConfig)
 	ReadRoomConfigs(roomConfigs map[string]*RoomConfig)
+	DeleteRoomConfig(roomID string)
 
 	SaveRoomState(roomID string, roomState *RoomState)
 	SaveRoomStates(roomStates map[string]*RoomState)
@@ -21,3 +24,25 @@ type Persister interface {
 type MainController interface {
 	Stop()
 }
+
+// IngestStatusGetter lists the data sources, see ingest.Manager
+type IngestStatusGetter interface {
+	Status() []ingest.SourceStatus
+	QueueStatus() (status ingest.QueueStatus, ok bool)
+}
+
+// PowerLevelGetter looks up a user's power level in a room
+type PowerLevelGetter interface {
+	GetUserPowerLevel(roomID, userID string) (level int, err error)
+}
+
+// LoginLinkCreator hands out one-time links to the web UI, see http.WebUI
+type LoginLinkCreator interface {
+	CreateLoginLink(roomID, userID string) (link string, err error)
+}
+
+// PostPublisher receives the spawns and raids posted to rooms, see http.StreamHub
+type PostPublisher interface {
+	Publish(event PostEvent)
+	StreamURL(roomID string) string
+}
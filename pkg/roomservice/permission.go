@@ -0,0 +1,99 @@
+package roomservice
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Matrix' default power level for moderators
+	defaultModeratorPowerLevel = 50
+)
+
+// Permission is the privilege level that is needed to use a command
+type Permission int
+
+const (
+	// PermissionEveryone allows every room member to use the command
+	PermissionEveryone Permission = iota
+	// PermissionModerator needs a room moderator (by Matrix power level) or a bot admin
+	PermissionModerator
+	// PermissionAdmin needs a bot admin from the configuration
+	PermissionAdmin
+)
+
+// ToString returns the permission level in human-readable form
+func (p Permission) ToString() (s string) {
+	switch p {
+	case PermissionEveryone:
+		s = "everyone"
+	case PermissionModerator:
+		s = "moderator"
+	case PermissionAdmin:
+		s = "admin"
+	default:
+		s = "invalid"
+	}
+	return
+}
+
+// isBotAdmin checks if the user is in the configured list of bot admins
+func (p *Poster) isBotAdmin(userID string) bool {
+	if p == nil || userID == "" {
+		return false
+	}
+
+	for _, admin := range p.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// isRoomModerator checks the user's power level in the room
+func (p *Poster) isRoomModerator(roomID, userID string) bool {
+	if p == nil || p.PowerLevels == nil || userID == "" {
+		return false
+	}
+
+	level, err := p.PowerLevels.GetUserPowerLevel(roomID, userID)
+	if err != nil {
+		log.WithError(err).Warnf("failed getting power level of %s in %s", userID, roomID)
+		return false
+	}
+	return level >= p.ModeratorPowerLevel
+}
+
+// getPermission returns the highest permission level the sender has in the room
+func (p *Poster) getPermission(context Context) Permission {
+	if p.isBotAdmin(context.Sender) {
+		return PermissionAdmin
+	}
+	if p.isRoomModerator(context.RoomID, context.Sender) {
+		return PermissionModerator
+	}
+	return PermissionEveryone
+}
+
+// hasPermission checks if the sender is allowed to use a command with the required permission level
+func (p *Poster) hasPermission(context Context, required Permission) bool {
+	if required == PermissionEveryone {
+		// don't bother the homeserver with power level requests
+		return true
+	}
+	return p.getPermission(context) >= required
+}
+
+// acceptsCommands returns false if the room is locked to read-only notifications.
+// Rooms without RoomConfig accept commands, otherwise they couldn't be set up.
+func (p *Poster) acceptsCommands(roomID string) bool {
+	if p == nil {
+		return true
+	}
+
+	rc, ok := p.GetRoomConfig(roomID)
+	if !ok {
+		return true
+	}
+	return rc.AcceptCommands
+}
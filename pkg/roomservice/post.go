@@ -0,0 +1,78 @@
+package roomservice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// kinds of PostEvent
+const (
+	PostKindSpawn   = "spawn"
+	PostKindRaid    = "raid"
+	PostKindEgg     = "egg"
+	PostKindHatched = "hatched"
+)
+
+// PostEvent is a spawn or raid message that was posted to a room, see PostPublisher
+type PostEvent struct {
+	RoomID string
+	Kind   string     // PostKindSpawn, PostKindRaid, PostKindEgg or PostKindHatched
+	Time   int64      // when it was posted
+	Text   string     // the message without formatting
+	Spawn  *SpawnPost `json:",omitempty"`
+	Raid   *RaidPost  `json:",omitempty"`
+}
+
+// SpawnPost is the data a spawn message is made of
+type SpawnPost struct {
+	EncounterID    string
+	PokemonID      int
+	PokemonName    string // english and german name from the Pokedex
+	Location       pogo.Location
+	EndTime        int64
+	Encounter      *pogo.Encounter `json:",omitempty"` // nil if the scanner didn't encounter it
+	BoostedWeather string          `json:",omitempty"` // empty if not boosted
+	NearestFort    *NearestFort    `json:",omitempty"` // nil if there's none within 500m or no GeoDex
+}
+
+// NearestFort is the pokestop or gym closest to a spawn
+type NearestFort struct {
+	Name       string
+	Location   pogo.Location
+	DistanceM  float64
+	BearingDeg float64 // from the fort to the spawn
+}
+
+// RaidPost is the data raid, egg and hatch messages are made of
+type RaidPost struct {
+	Hash        string
+	GymID       string
+	GymName     string // from the GeoDex, the GUID if it's unknown
+	Location    pogo.Location
+	Level       int
+	LevelName   string // "Level 5" or "Mega"
+	PokemonID   int    `json:",omitempty"` // 0 for eggs
+	PokemonName string `json:",omitempty"` // english and german name from the Pokedex
+	Weather     string `json:",omitempty"` // types boosted by the weather at the gym if it's known
+	StartTime   int64
+	EndTime     int64
+}
+
+// pokemonString returns the raid boss and the weather like in raid messages
+func (r *RaidPost) pokemonString() string {
+	if r.Weather == "" {
+		return r.PokemonName
+	}
+	return fmt.Sprintf("%s [weather: %s]", r.PokemonName, r.Weather)
+}
+
+// publishPost hands a posted message to the stream if there is one
+func (p *Poster) publishPost(event PostEvent) {
+	if p.Stream == nil {
+		return
+	}
+	event.Time = time.Now().Unix()
+	p.Stream.Publish(event)
+}
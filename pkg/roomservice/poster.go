@@ -1,13 +1,16 @@
 package roomservice
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/spezifisch/silphtelescope/internal/helpers"
 	"github.com/spezifisch/silphtelescope/pkg/geodex"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 )
 
@@ -17,6 +20,13 @@ type Poster struct {
 	GymUpdates   chan pogo.Gym
 	SpawnUpdates chan pogo.Spawn
 	RaidUpdates  chan pogo.Raid
+	// weather and pokestop updates are optional
+	WeatherUpdates  chan pogo.Weather
+	InvasionUpdates chan pogo.Invasion
+	QuestUpdates    chan pogo.Quest
+	LureUpdates     chan pogo.Lure
+	// gym and pokestop details for the GeoDex, optional
+	FortUpdates chan pogo.Fort
 
 	// control channels
 	Quit             chan bool
@@ -29,14 +39,35 @@ type Poster struct {
 	// geodex
 	GeoDex *geodex.GeoDex
 
+	// data sources for status output, optional
+	Ingest IngestStatusGetter
+
+	// login links for the filter editor, the web command is disabled if nil
+	WebUI LoginLinkCreator
+
+	// live stream of posted spawns and raids, the stream command is disabled if nil
+	Stream PostPublisher
+
+	// permissions: Matrix user IDs of bot admins
+	Admins []string
+	// permissions: room members with at least this power level are moderators
+	ModeratorPowerLevel int
+	// permissions: power level source for moderator checks, disabled if nil
+	PowerLevels PowerLevelGetter
+
 	// read RoomConfigs and States from disk before starting the main loop in Run()
 	ResumeStateOnStartup bool
 
 	// remove expired spawns and raids from memory
 	ExpiryCheckPeriod time.Duration
 
+	// forget gyms without updates for this long, never if 0
+	GymStateTTL time.Duration
+
 	// output
 	chatter Chatter
+	// messages collected while processing an update, sent by Run() after releasing mu
+	outbox []outgoingMessage
 
 	// storage
 	db Persister
@@ -47,58 +78,128 @@ type Poster struct {
 	// in-memory state like posted encounter IDs
 	roomStates map[string]*RoomState
 
+	// all spawns, raids and invasions until they end
+	active *ActiveEvents
+
+	// last known weather: S2 cell ID -> Weather
+	weather map[uint64]*pogo.Weather
+
+	// last known gym states: GUID -> GymState, only in memory
+	gyms map[string]*GymState
+
 	// timestamp of last message from MAD
 	lastDataTime time.Time
 
 	// timestamp of bot start
 	startTime time.Time
+
+	// guards outbox, roomConfigs, roomStates, active, weather, gyms, lastDataTime and startTime.
+	// Run() holds it while processing an update, command handlers while reading or changing configs.
+	// Nobody holds it while talking to the homeserver.
+	mu sync.Mutex
 }
 
 // NewPoster creates some objects
 func NewPoster(chatter Chatter, persister Persister) *Poster {
 	return &Poster{
 		ExpiryCheckPeriod:    30 * time.Second,
+		GymStateTTL:          defaultGymStateTTL,
+		ModeratorPowerLevel:  defaultModeratorPowerLevel,
 		ResumeStateOnStartup: false,
 		roomConfigs:          make(map[string]*RoomConfig),
 		roomStates:           make(map[string]*RoomState),
+		active:               NewActiveEvents(),
+		weather:              make(map[uint64]*pogo.Weather),
+		gyms:                 make(map[string]*GymState),
 		saveStateAndQuit:     make(chan bool),
 		chatter:              chatter,
 		db:                   persister,
 	}
 }
 
+// Sink returns the update channels for ingest sources
+func (p *Poster) Sink() *ingest.Sink {
+	return &ingest.Sink{
+		Gyms:      p.GymUpdates,
+		Spawns:    p.SpawnUpdates,
+		Raids:     p.RaidUpdates,
+		Weather:   p.WeatherUpdates,
+		Invasions: p.InvasionUpdates,
+		Quests:    p.QuestUpdates,
+		Lures:     p.LureUpdates,
+		Forts:     p.FortUpdates,
+	}
+}
+
 // Run runs the poster main loop blockingly
 func (p *Poster) Run() {
+	p.mu.Lock()
 	p.startTime = time.Now()
 
 	if p.ResumeStateOnStartup {
 		p.readRoomConfigs()
 		p.readRoomStates()
 	}
+	p.mu.Unlock()
 
 	expiryTicker := time.NewTicker(p.ExpiryCheckPeriod)
 
 	for {
 		// wait for updates
 		select {
-		case <-p.GymUpdates:
-			//log.Debug("got gym update#")
+		case g := <-p.GymUpdates:
+			p.mu.Lock()
 			p.updateLastData()
+			p.processGymUpdate(g)
+			p.mu.Unlock()
 		case s := <-p.SpawnUpdates:
+			p.mu.Lock()
 			p.updateLastData()
 			p.processSpawnUpdate(s)
+			p.mu.Unlock()
 		case r := <-p.RaidUpdates:
+			p.mu.Lock()
 			p.updateLastData()
 			p.processRaidUpdate(r)
+			p.mu.Unlock()
+		case w := <-p.WeatherUpdates:
+			p.mu.Lock()
+			p.updateLastData()
+			p.processWeatherUpdate(w)
+			p.mu.Unlock()
+		case i := <-p.InvasionUpdates:
+			p.mu.Lock()
+			p.updateLastData()
+			p.processInvasionUpdate(i)
+			p.mu.Unlock()
+		case q := <-p.QuestUpdates:
+			p.mu.Lock()
+			p.updateLastData()
+			p.processQuestUpdate(q)
+			p.mu.Unlock()
+		case l := <-p.LureUpdates:
+			p.mu.Lock()
+			p.updateLastData()
+			p.processLureUpdate(l)
+			p.mu.Unlock()
+		case f := <-p.FortUpdates:
+			p.mu.Lock()
+			p.updateLastData()
+			p.processFortUpdate(f)
+			p.mu.Unlock()
 		case <-expiryTicker.C:
+			p.mu.Lock()
 			p.cleanupTick()
+			p.mu.Unlock()
 		case <-p.saveStateAndQuit:
 			expiryTicker.Stop()
 
 			// Save everything from memory that we need to resume our operation after starting again.
 			// RoomConfigs are already saved on modification.
 			// RoomStates aren't because they're changed very frequently, so save them here.
+			p.mu.Lock()
 			p.saveRoomStates()
+			p.mu.Unlock()
 
 			if p.MainControl != nil {
 				log.Info("poster stopped, shutting down maincontrol")
@@ -108,18 +209,58 @@ func (p *Poster) Run() {
 		case <-p.Quit:
 			return
 		}
+
+		p.sendOutbox()
+	}
+}
+
+// outgoingMessage is a message for a room, formattedText is optional
+type outgoingMessage struct {
+	roomID        string
+	text          string
+	formattedText string
+}
+
+// sendText queues a message, p.mu must be held
+func (p *Poster) sendText(roomID, text string) {
+	p.outbox = append(p.outbox, outgoingMessage{roomID: roomID, text: text})
+}
+
+// sendFormattedText queues a message with HTML, p.mu must be held
+func (p *Poster) sendFormattedText(roomID, text, formattedText string) {
+	p.outbox = append(p.outbox, outgoingMessage{roomID: roomID, text: text, formattedText: formattedText})
+}
+
+// sendOutbox sends the queued messages, p.mu must not be held so slow homeservers don't block commands and API calls
+func (p *Poster) sendOutbox() {
+	p.mu.Lock()
+	messages := p.outbox
+	p.outbox = nil
+	p.mu.Unlock()
+
+	for _, msg := range messages {
+		if msg.formattedText != "" {
+			p.chatter.SendFormattedText(msg.roomID, msg.text, msg.formattedText)
+		} else {
+			p.chatter.SendText(msg.roomID, msg.text)
+		}
 	}
 }
 
 // periodical memory cleanup of
-// * expired spawns
-// * nothing else yet
+// * expired events in room states
+// * expired active events
+// * gym states without updates within GymStateTTL
 func (p *Poster) cleanupTick() {
 	deleted := 0
 	now := time.Now().Unix()
 	for _, roomState := range p.roomStates {
 		deleted += roomState.removeExpired(now)
 	}
+	deleted += p.active.removeExpired(now)
+	if p.GymStateTTL > 0 {
+		deleted += p.removeOldGymStates(now - int64(p.GymStateTTL/time.Second))
+	}
 
 	if deleted > 0 {
 		log.Debugf("removed %d expired elements", deleted)
@@ -130,15 +271,60 @@ func (p *Poster) updateLastData() {
 	p.lastDataTime = time.Now()
 }
 
-func (p *Poster) processRaidUpdate(r pogo.Raid) {
-	if r.Pokemon == nil || r.Pokemon.ID == 0 {
+// LastDataTime returns when the last update came in, zero if none did yet
+func (p *Poster) LastDataTime() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.lastDataTime
+}
+
+// CheckData is a health check reporting the age of the last update.
+// It fails if there was none within maxAge, unless maxAge is 0.
+func (p *Poster) CheckData(maxAge time.Duration) (status string, err error) {
+	lastDataTime := p.LastDataTime()
+	if lastDataTime.IsZero() {
+		status = "no data yet"
+		if maxAge > 0 {
+			err = errors.New(status)
+		}
 		return
 	}
 
+	age := time.Since(lastDataTime).Round(time.Second)
+	status = fmt.Sprintf("last data %s ago", age)
+	if maxAge > 0 && age > maxAge {
+		err = fmt.Errorf("no data for %s", age)
+	}
+	return
+}
+
+// getStatusTimes returns when the bot was started and when it received the last data
+func (p *Poster) getStatusTimes() (startTime, lastDataTime time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.startTime, p.lastDataTime
+}
+
+func (p *Poster) processRaidUpdate(r pogo.Raid) {
+	isEgg := r.IsEgg()
+	matched := false
+	defer countProcessed(ingest.EventRaid, &matched)
+	p.active.Raids[r.Hash] = r
+
 	// TODO reduce complexity
 	for _, room := range p.roomConfigs {
 		roomState := p.getOrCreateRoomState(room.RoomID)
 		if roomState.raidIsPosted(r.Hash) {
+			if !isEgg && roomState.raidIsPostedEgg(r.Hash) {
+				// the room knows about the egg, tell them what hatched unless they don't want the boss
+				if room.wantsHatched(&r) {
+					p.postRaidHatched(room, &r)
+					matched = true
+				}
+				roomState.postedRaid(&r, true)
+			}
 			continue
 		}
 
@@ -147,10 +333,22 @@ func (p *Poster) processRaidUpdate(r pogo.Raid) {
 				continue
 			}
 
-			if helpers.IntArrayContains(filter.PokemonIDs, r.Pokemon.ID) {
+			var matches bool
+			if isEgg {
+				matches = filter.matchesEgg(r.Level)
+			} else {
+				matches = filter.matchesPokemon(r.Pokemon.ID)
+			}
+
+			if matches {
 				if filter.Area.Contains(&r.Location) {
-					p.postRaid(room, &r)
+					if isEgg {
+						p.postEgg(room, &r)
+					} else {
+						p.postRaid(room, &r)
+					}
 					roomState.postedRaid(&r, true)
+					matched = true
 					break
 				}
 			}
@@ -159,6 +357,9 @@ func (p *Poster) processRaidUpdate(r pogo.Raid) {
 }
 
 func (p *Poster) processSpawnUpdate(s pogo.Spawn) {
+	matched := false
+	defer countProcessed(ingest.EventSpawn, &matched)
+	p.active.Spawns[s.EncounterID] = s
 	// TODO reduce complexity
 	for _, room := range p.roomConfigs {
 		roomState := p.getOrCreateRoomState(room.RoomID)
@@ -167,14 +368,15 @@ func (p *Poster) processSpawnUpdate(s pogo.Spawn) {
 		}
 
 		for _, filter := range room.Filter {
-			if !filter.ListWanted {
+			if filter.ListRaids {
 				continue
 			}
 
-			if helpers.IntArrayContains(filter.PokemonIDs, s.Pokemon.ID) {
+			if filter.matchesPokemon(s.Pokemon.ID) && filter.matchesEncounter(s.Encounter) {
 				if filter.Area.Contains(&s.Location) {
 					p.postSpawn(room, &s)
 					roomState.postedSpawn(&s, true)
+					matched = true
 					break
 				}
 			}
@@ -182,6 +384,47 @@ func (p *Poster) processSpawnUpdate(s pogo.Spawn) {
 	}
 }
 
+// countProcessed updates the event metrics, matched is a pointer for use with defer
+func countProcessed(eventType string, matched *bool) {
+	metrics.EventsProcessed.WithLabelValues(eventType).Inc()
+	if *matched {
+		metrics.EventsMatched.WithLabelValues(eventType).Inc()
+	}
+}
+
+// UpdateMetrics sets the gauges for the update channels and room states, see metrics.OnScrape
+func (p *Poster) UpdateMetrics() {
+	for queue, length := range map[string]int{
+		ingest.EventGym:      len(p.GymUpdates),
+		ingest.EventSpawn:    len(p.SpawnUpdates),
+		ingest.EventRaid:     len(p.RaidUpdates),
+		ingest.EventWeather:  len(p.WeatherUpdates),
+		ingest.EventInvasion: len(p.InvasionUpdates),
+		ingest.EventQuest:    len(p.QuestUpdates),
+		ingest.EventLure:     len(p.LureUpdates),
+		ingest.EventFort:     len(p.FortUpdates),
+	} {
+		metrics.QueueLength.WithLabelValues("poster_" + queue).Set(float64(length))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// forget cleared rooms
+	metrics.RoomStateEntries.Reset()
+	for roomID, rs := range p.roomStates {
+		for kind, n := range map[string]int{
+			ingest.EventSpawn:    len(rs.Spawns),
+			ingest.EventRaid:     len(rs.Raids),
+			ingest.EventInvasion: len(rs.Invasions),
+			ingest.EventQuest:    len(rs.Quests),
+			ingest.EventLure:     len(rs.Lures),
+		} {
+			metrics.RoomStateEntries.WithLabelValues(roomID, kind).Set(float64(n))
+		}
+	}
+}
+
 func (p *Poster) getOrCreateRoomState(roomID string) *RoomState {
 	rs, ok := p.roomStates[roomID]
 	if !ok {
@@ -193,15 +436,11 @@ func (p *Poster) getOrCreateRoomState(roomID string) *RoomState {
 	return rs
 }
 
-func (p *Poster) postRaid(room *RoomConfig, r *pogo.Raid) {
-	startTime := time.Unix(r.StartTime, 0)
-	endTime := time.Unix(r.EndTime, 0)
-	startTimeStr := startTime.Format("15:04:05")
-	endTimeStr := endTime.Format("15:04:05")
-
-	pokemonStr := fmt.Sprintf("Pokemon #%d", r.Pokemon.ID)
+// getPokemonName returns the pokemon's name from the Pokedex, or its number if that isn't possible
+func (p *Poster) getPokemonName(pokemonID int) (pokemonStr string) {
+	pokemonStr = fmt.Sprintf("Pokemon #%d", pokemonID)
 	if p.Pokedex != nil {
-		nameEN, nameDE, err := p.Pokedex.GetNamesByID(r.Pokemon.ID)
+		nameEN, nameDE, err := p.Pokedex.GetNamesByID(pokemonID)
 		if err == nil {
 			if nameEN != nameDE {
 				pokemonStr = fmt.Sprintf("%s (de: %s)", nameEN, nameDE)
@@ -210,74 +449,173 @@ func (p *Poster) postRaid(room *RoomConfig, r *pogo.Raid) {
 			}
 		}
 	}
+	return
+}
 
-	raidLocation := r.Location
-	fortName := r.GymID
+// getFortName returns the fort's name from the GeoDex, or its GUID if that isn't possible
+func (p *Poster) getFortName(guid string) (fortName string) {
+	fortName = guid
 	if p.GeoDex != nil {
-		fort, err := p.GeoDex.Disk.GetFort(r.GymID)
+		fort, err := p.GeoDex.Disk.GetFort(guid)
 		if err == nil {
 			fortName = fort.GetName()
 		}
 	}
+	return
+}
+
+// getRaidWeather returns the types boosted by the weather at the raid if it's known.
+// The Pokedex doesn't know types, so it's up to the reader if the raid boss is boosted.
+func (p *Poster) getRaidWeather(r *pogo.Raid) string {
+	w := p.getWeatherAt(&r.Location)
+	if w == nil || w.Condition == pogo.WeatherNone {
+		return ""
+	}
+	return w.Condition.BoostToString()
+}
+
+// newRaidPost collects the data for raid, egg and hatch messages
+func (p *Poster) newRaidPost(r *pogo.Raid) *RaidPost {
+	post := &RaidPost{
+		Hash:      r.Hash,
+		GymID:     r.GymID,
+		GymName:   p.getFortName(r.GymID),
+		Location:  r.Location,
+		Level:     r.Level,
+		LevelName: r.LevelToString(),
+		StartTime: r.StartTime,
+		EndTime:   r.EndTime,
+	}
+	if !r.IsEgg() {
+		post.PokemonID = r.Pokemon.ID
+		post.PokemonName = p.getPokemonName(r.Pokemon.ID)
+		post.Weather = p.getRaidWeather(r)
+	}
+	return post
+}
+
+// sendRaidText posts a raid message which ends with "at <gym>" and links the gym in formatted text
+func (p *Poster) sendRaidText(room *RoomConfig, post *RaidPost, kind string, textFormat string, a ...interface{}) {
+	text := fmt.Sprintf(textFormat, a...)
+	plainText := fmt.Sprintf("%s at %s", text, post.GymName)
+	if room.FormatText {
+		fortStr := fmt.Sprintf("<a href=\"%s\">%s</a>", post.Location.ToLinkGMaps(), post.GymName)
+		fText := fmt.Sprintf("%s at %s", text, fortStr)
+		p.sendFormattedText(room.RoomID, plainText, fText)
+	} else {
+		p.sendText(room.RoomID, plainText)
+	}
+	p.publishPost(PostEvent{RoomID: room.RoomID, Kind: kind, Text: plainText, Raid: post})
+}
+
+func (p *Poster) postRaid(room *RoomConfig, r *pogo.Raid) {
+	post := p.newRaidPost(r)
+	startTimeStr := time.Unix(post.StartTime, 0).Format("15:04:05")
+	endTimeStr := time.Unix(post.EndTime, 0).Format("15:04:05")
+	pokemonStr := post.pokemonString()
 
 	text := fmt.Sprintf("Raid %s %s-%s at %s (Level %d)",
-		pokemonStr, startTimeStr, endTimeStr, fortName, r.Level)
+		pokemonStr, startTimeStr, endTimeStr, post.GymName, post.Level)
 	if room.FormatText {
-		fortStr := fmt.Sprintf("<a href=\"%s\">%s</a>", raidLocation.ToLinkGMaps(), fortName)
+		fortStr := fmt.Sprintf("<a href=\"%s\">%s</a>", post.Location.ToLinkGMaps(), post.GymName)
 		fText := fmt.Sprintf("Raid %s %s-%s at %s (Level %d)",
-			pokemonStr, startTimeStr, endTimeStr, fortStr, r.Level)
-		p.chatter.SendFormattedText(room.RoomID, text, fText)
+			pokemonStr, startTimeStr, endTimeStr, fortStr, post.Level)
+		p.sendFormattedText(room.RoomID, text, fText)
 	} else {
-		p.chatter.SendText(room.RoomID, text)
+		p.sendText(room.RoomID, text)
 	}
+	p.publishPost(PostEvent{RoomID: room.RoomID, Kind: PostKindRaid, Text: text, Raid: post})
 }
 
-func (p *Poster) postSpawn(room *RoomConfig, s *pogo.Spawn) {
-	endTime := time.Unix(s.EndTime, 0)
-	timeLeft := endTime.Sub(time.Now().Round(time.Second))
-
-	endTimeStr := endTime.Format("15:04:05")
+func (p *Poster) postEgg(room *RoomConfig, r *pogo.Raid) {
+	post := p.newRaidPost(r)
+	now := time.Now().Round(time.Second)
+	startTime := time.Unix(post.StartTime, 0)
+	startTimeStr := startTime.Format("15:04:05")
+	endTimeStr := time.Unix(post.EndTime, 0).Format("15:04:05")
 
-	pokemonStr := fmt.Sprintf("Pokemon #%d", s.Pokemon.ID)
-	if p.Pokedex != nil {
-		nameEN, nameDE, err := p.Pokedex.GetNamesByID(s.Pokemon.ID)
-		if err == nil {
-			if nameEN != nameDE {
-				pokemonStr = fmt.Sprintf("%s (de: %s)", nameEN, nameDE)
-			} else {
-				pokemonStr = nameEN
-			}
-		}
+	hatchStr := fmt.Sprintf("hatches at %s (in %s)", startTimeStr, startTime.Sub(now))
+	if !startTime.After(now) {
+		hatchStr = fmt.Sprintf("hatched at %s", startTimeStr)
 	}
 
-	gmapsLink := s.Location.ToLinkGMaps()
+	p.sendRaidText(room, post, PostKindEgg, "%s Egg %s, raid until %s", post.LevelName, hatchStr, endTimeStr)
+}
+
+func (p *Poster) postRaidHatched(room *RoomConfig, r *pogo.Raid) {
+	post := p.newRaidPost(r)
+	endTimeStr := time.Unix(post.EndTime, 0).Format("15:04:05")
+
+	p.sendRaidText(room, post, PostKindHatched, "%s Egg hatched: %s until %s", post.LevelName, post.pokemonString(), endTimeStr)
+}
+
+// newSpawnPost collects the data for spawn messages
+func (p *Poster) newSpawnPost(s *pogo.Spawn) *SpawnPost {
+	post := &SpawnPost{
+		EncounterID: s.EncounterID,
+		PokemonID:   s.Pokemon.ID,
+		PokemonName: p.getPokemonName(s.Pokemon.ID),
+		Location:    s.Location,
+		EndTime:     s.EndTime,
+		Encounter:   s.Encounter,
+	}
+	if s.BoostedWeather != pogo.WeatherNone {
+		post.BoostedWeather = s.BoostedWeather.ToString()
+	}
 
 	// lookup nearest stop or gym
-	nearStr := ""
-	fmtNearStr := ""
 	if p.GeoDex != nil {
 		radiusM := 500.0
 		if nearestFort, err := p.GeoDex.LookupFortNear(s.Location, radiusM); err == nil {
 			// get distance and bearing from fort to spawn point
 			fortLocation := nearestFort.Location()
-			distanceM := fortLocation.DistanceTo(&s.Location)
-			bearingDeg := fortLocation.BearingTo(&s.Location)
-			nearStr = fmt.Sprintf(" near %s (%dm, %d°)", nearestFort.GetName(), int(distanceM), int(bearingDeg))
-			fmtNearStr = fmt.Sprintf(" near <a href=\"%s\">%s (%dm, %d°)</a>", gmapsLink, nearestFort.GetName(), int(distanceM), int(bearingDeg))
+			post.NearestFort = &NearestFort{
+				Name:       nearestFort.GetName(),
+				Location:   *fortLocation,
+				DistanceM:  fortLocation.DistanceTo(&s.Location),
+				BearingDeg: fortLocation.BearingTo(&s.Location),
+			}
 		}
 	}
+	return post
+}
+
+func (p *Poster) postSpawn(room *RoomConfig, s *pogo.Spawn) {
+	post := p.newSpawnPost(s)
+	endTime := time.Unix(post.EndTime, 0)
+	timeLeft := endTime.Sub(time.Now().Round(time.Second))
+
+	endTimeStr := endTime.Format("15:04:05")
+
+	pokemonStr := post.PokemonName
+	if post.Encounter != nil {
+		pokemonStr = fmt.Sprintf("%s %s", pokemonStr, post.Encounter.ToString())
+	}
+	if post.BoostedWeather != "" {
+		pokemonStr = fmt.Sprintf("%s boosted by %s", pokemonStr, post.BoostedWeather)
+	}
+
+	gmapsLink := post.Location.ToLinkGMaps()
+
+	nearStr := ""
+	fmtNearStr := ""
+	if fort := post.NearestFort; fort != nil {
+		nearStr = fmt.Sprintf(" near %s (%dm, %d°)", fort.Name, int(fort.DistanceM), int(fort.BearingDeg))
+		fmtNearStr = fmt.Sprintf(" near <a href=\"%s\">%s (%dm, %d°)</a>", gmapsLink, fort.Name, int(fort.DistanceM), int(fort.BearingDeg))
+	}
 
 	text := fmt.Sprintf("%s until %s (%s left)%s at %s",
 		pokemonStr, endTimeStr, timeLeft, nearStr, gmapsLink)
 	if room.FormatText {
 		if fmtNearStr == "" {
-			fmtNearStr = fmt.Sprintf(" at <a href=\"%s\">(%f,%f)</a>", gmapsLink, s.Longitude, s.Latitude)
+			fmtNearStr = fmt.Sprintf(" at <a href=\"%s\">(%f,%f)</a>", gmapsLink, post.Location.Longitude, post.Location.Latitude)
 		}
 
 		fText := fmt.Sprintf("%s until %s (%s left)%s",
 			pokemonStr, endTimeStr, timeLeft, fmtNearStr)
-		p.chatter.SendFormattedText(room.RoomID, text, fText)
+		p.sendFormattedText(room.RoomID, text, fText)
 	} else {
-		p.chatter.SendText(room.RoomID, text)
+		p.sendText(room.RoomID, text)
 	}
+	p.publishPost(PostEvent{RoomID: room.RoomID, Kind: PostKindSpawn, Text: text, Spawn: post})
 }
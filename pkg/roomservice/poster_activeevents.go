@@ -0,0 +1,115 @@
+package roomservice
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/internal/helpers"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// ActiveEventList is a snapshot of the active events and known gyms with names filled in from Pokedex and GeoDex
+type ActiveEventList struct {
+	Spawns    []pogo.Spawn
+	Raids     []ActiveRaid
+	Invasions []pogo.Invasion
+	Gyms      []*GymState
+}
+
+// ActiveRaid is a raid with the name of its gym
+type ActiveRaid struct {
+	pogo.Raid
+	GymName string
+}
+
+// GetActiveEvents returns copies of the events that haven't ended yet and of all known gyms.
+// types limits the result to ingest.EventSpawn, EventRaid, EventInvasion or EventGym, all if it's empty.
+// bbox limits it to an area if it's not nil. The lists are ordered by ID.
+func (p *Poster) GetActiveEvents(types []string, bbox *pogo.BoundingBox) (list ActiveEventList) {
+	// the names are looked up after releasing the lock, the feed is public and GeoDex reads are slow
+	list = p.copyActiveEvents(types, bbox)
+
+	for i := range list.Spawns {
+		s := &list.Spawns[i]
+		s.Pokemon.Name = p.getPokemonNameEN(s.Pokemon.ID, s.Pokemon.Name)
+	}
+	for i := range list.Raids {
+		r := &list.Raids[i]
+		if r.Pokemon != nil {
+			r.Pokemon.Name = p.getPokemonNameEN(r.Pokemon.ID, r.Pokemon.Name)
+		}
+		r.GymName = p.getFortName(r.GymID)
+	}
+	for i := range list.Invasions {
+		inv := &list.Invasions[i]
+		inv.PokestopName = p.getFortNameOr(inv.PokestopID, inv.PokestopName)
+	}
+	for _, gs := range list.Gyms {
+		gs.Name = p.getFortNameOr(gs.GUID, gs.Name)
+	}
+
+	sort.Slice(list.Spawns, func(i, j int) bool {
+		return list.Spawns[i].EncounterID < list.Spawns[j].EncounterID
+	})
+	sort.Slice(list.Raids, func(i, j int) bool {
+		return list.Raids[i].Hash < list.Raids[j].Hash
+	})
+	sort.Slice(list.Invasions, func(i, j int) bool {
+		return list.Invasions[i].Hash < list.Invasions[j].Hash
+	})
+	sort.Slice(list.Gyms, func(i, j int) bool {
+		return list.Gyms[i].GUID < list.Gyms[j].GUID
+	})
+	return
+}
+
+// copyActiveEvents returns copies of the wanted events without names
+func (p *Poster) copyActiveEvents(types []string, bbox *pogo.BoundingBox) (list ActiveEventList) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wanted := func(eventType string, l *pogo.Location) bool {
+		if len(types) > 0 && !helpers.StringArrayContains(types, eventType) {
+			return false
+		}
+		return bbox == nil || bbox.Contains(l)
+	}
+	now := time.Now().Unix()
+
+	for _, s := range p.active.Spawns {
+		if s.EndTime >= now && wanted(ingest.EventSpawn, &s.Location) {
+			list.Spawns = append(list.Spawns, s)
+		}
+	}
+	for _, r := range p.active.Raids {
+		if r.EndTime >= now && wanted(ingest.EventRaid, &r.Location) {
+			if r.Pokemon != nil {
+				pokemon := *r.Pokemon
+				r.Pokemon = &pokemon
+			}
+			list.Raids = append(list.Raids, ActiveRaid{Raid: r})
+		}
+	}
+	for _, i := range p.active.Invasions {
+		if i.EndTime >= now && wanted(ingest.EventInvasion, &i.Location) {
+			list.Invasions = append(list.Invasions, i)
+		}
+	}
+	for _, gs := range p.gyms {
+		if wanted(ingest.EventGym, &gs.Location) {
+			list.Gyms = append(list.Gyms, gs.copy())
+		}
+	}
+	return
+}
+
+// getPokemonNameEN returns the english name from the Pokedex, or fallback if it's unknown
+func (p *Poster) getPokemonNameEN(pokemonID int, fallback string) string {
+	if p.Pokedex != nil {
+		if nameEN, _, err := p.Pokedex.GetNamesByID(pokemonID); err == nil {
+			return nameEN
+		}
+	}
+	return fallback
+}
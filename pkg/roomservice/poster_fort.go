@@ -0,0 +1,43 @@
+package roomservice
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/geodex"
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// processFortUpdate adds new forts to the GeoDex and fills in missing names, existing names are kept
+func (p *Poster) processFortUpdate(f pogo.Fort) {
+	if p.GeoDex == nil || !geodex.IsValidGUID(f.GUID) {
+		return
+	}
+
+	guid := f.GUID
+	fort := &geodex.Fort{
+		GUID:      &guid,
+		Latitude:  f.Location.Latitude,
+		Longitude: f.Location.Longitude,
+		Type:      geodex.FortTypeStop,
+	}
+	if f.IsGym {
+		fort.Type = geodex.FortTypeGym
+	}
+	if f.Name != "" {
+		name := f.Name
+		fort.Name = &name
+	}
+	if f.URL != "" {
+		url := f.URL
+		fort.URL = &url
+	}
+
+	changed, err := p.GeoDex.UpdateFort(fort)
+	if err != nil {
+		log.WithError(err).Warnf("couldn't update fort %s in GeoDex", f.GUID)
+		return
+	}
+	if changed {
+		log.Debugf("updated GeoDex: %s", fort.ToString())
+	}
+}
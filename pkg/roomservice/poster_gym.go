@@ -0,0 +1,181 @@
+package roomservice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+const (
+	// gymHistoryLength is the number of ownership changes remembered per gym
+	gymHistoryLength = 5
+	// defaultGymStateTTL is how long gyms are remembered without updates, e.g. after leaving the scan area
+	defaultGymStateTTL = 7 * 24 * time.Hour
+)
+
+// GymState is the last known state of a gym
+type GymState struct {
+	GUID           string
+	Name           string // from the webhook, might be empty
+	Location       pogo.Location
+	TeamColor      pogo.TeamColor
+	SlotsAvailable int
+	ExRaidEligible bool
+	LastUpdate     int64           // last time we got info about this gym
+	LastChange     int64           // last time the gym changed teams, 0 if we haven't seen it change
+	History        []GymTeamChange // most recent ownership changes, oldest first
+}
+
+// GymTeamChange is an ownership change of a gym
+type GymTeamChange struct {
+	Time int64
+	From pogo.TeamColor
+	To   pogo.TeamColor
+}
+
+// ToString converts GymTeamChange into a human-readable string
+func (c GymTeamChange) ToString() string {
+	return fmt.Sprintf("%s %s -> %s",
+		time.Unix(c.Time, 0).Format("2006-01-02 15:04:05"), c.From.ToString(), c.To.ToString())
+}
+
+// GetGymState returns a copy of the last known state of the gym with this GUID
+func (p *Poster) GetGymState(guid string) (*GymState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	gs, ok := p.gyms[guid]
+	if !ok {
+		return nil, false
+	}
+	return gs.copy(), true
+}
+
+// FindGymStates returns copies of the gyms whose name contains the query, ignoring case
+func (p *Poster) FindGymStates(query string) (found []*GymState) {
+	// match the GeoDex names without holding the lock, anyone can send the gym command
+	p.mu.Lock()
+	gyms := make([]*GymState, 0, len(p.gyms))
+	for _, gs := range p.gyms {
+		gyms = append(gyms, gs.copy())
+	}
+	p.mu.Unlock()
+
+	query = strings.ToLower(query)
+	for _, gs := range gyms {
+		name := p.getFortNameOr(gs.GUID, gs.Name)
+		if strings.Contains(strings.ToLower(name), query) {
+			found = append(found, gs)
+		}
+	}
+	return
+}
+
+func (gs *GymState) copy() *GymState {
+	gsCopy := *gs
+	gsCopy.History = append([]GymTeamChange{}, gs.History...)
+	return &gsCopy
+}
+
+// removeOldGymStates forgets gyms whose last update is before minUpdate, returns the number of removed gyms
+func (p *Poster) removeOldGymStates(minUpdate int64) (deleted int) {
+	for guid, gs := range p.gyms {
+		if gs.LastUpdate < minUpdate {
+			delete(p.gyms, guid)
+			deleted++
+		}
+	}
+	return
+}
+
+func (p *Poster) processGymUpdate(g pogo.Gym) {
+	if g.GUID == "" {
+		return
+	}
+
+	updateTime := g.UpdateTime
+	if updateTime == 0 {
+		updateTime = time.Now().Unix()
+	}
+
+	gs, known := p.gyms[g.GUID]
+	if !known {
+		gs = &GymState{GUID: g.GUID}
+		p.gyms[g.GUID] = gs
+	}
+	prevTeam := gs.TeamColor
+
+	if g.Name != "" {
+		gs.Name = g.Name
+	}
+	gs.Location = g.Location
+	gs.TeamColor = g.TeamColor
+	gs.SlotsAvailable = g.SlotsAvailable
+	gs.ExRaidEligible = g.ExRaidEligible
+	gs.LastUpdate = updateTime
+
+	// the first sighting is no change
+	if !known || prevTeam == g.TeamColor {
+		return
+	}
+
+	change := GymTeamChange{
+		Time: updateTime,
+		From: prevTeam,
+		To:   g.TeamColor,
+	}
+	gs.LastChange = updateTime
+	gs.History = append(gs.History, change)
+	if len(gs.History) > gymHistoryLength {
+		gs.History = gs.History[len(gs.History)-gymHistoryLength:]
+	}
+
+	log.Debugf("gym %s changed from %s to %s", g.GUID, prevTeam.ToString(), g.TeamColor.ToString())
+	for _, room := range p.roomConfigs {
+		for _, filter := range room.Gyms {
+			if filter.matchesGym(gs) {
+				p.postGymTeamChange(room, gs, &change)
+				break
+			}
+		}
+	}
+}
+
+func (p *Poster) postGymTeamChange(room *RoomConfig, gs *GymState, c *GymTeamChange) {
+	gymName := p.getFortNameOr(gs.GUID, gs.Name)
+
+	text := fmt.Sprintf("Gym: %s turned %s (was %s), %d free slots",
+		gymName, c.To.ToString(), c.From.ToString(), gs.SlotsAvailable)
+	if room.FormatText {
+		gymStr := fmt.Sprintf("<a href=\"%s\">%s</a>", gs.Location.ToLinkGMaps(), gymName)
+		fText := fmt.Sprintf("Gym: %s turned %s (was %s), %d free slots",
+			gymStr, c.To.ToString(), c.From.ToString(), gs.SlotsAvailable)
+		p.sendFormattedText(room.RoomID, text, fText)
+	} else {
+		p.sendText(room.RoomID, text)
+	}
+}
+
+// gymStateToString shows the gym state with its recent ownership changes
+func (p *Poster) gymStateToString(gs *GymState) string {
+	gymName := p.getFortNameOr(gs.GUID, gs.Name)
+
+	s := fmt.Sprintf("%s (%s): %s, %d free slots", gymName, gs.GUID, gs.TeamColor.ToString(), gs.SlotsAvailable)
+	if gs.ExRaidEligible {
+		s += ", EX raid eligible"
+	}
+	s += fmt.Sprintf("\nlast update: %s", time.Unix(gs.LastUpdate, 0).Format("2006-01-02 15:04:05"))
+	if gs.LastChange == 0 {
+		s += "\nno team changes seen yet"
+		return s
+	}
+	s += "\nlast team changes:"
+	for i := len(gs.History) - 1; i >= 0; i-- {
+		s += "\n" + gs.History[i].ToString()
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package roomservice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+func (p *Poster) processInvasionUpdate(i pogo.Invasion) {
+	p.active.Invasions[i.Hash] = i
+	for _, room := range p.roomConfigs {
+		roomState := p.getOrCreateRoomState(room.RoomID)
+		if roomState.invasionIsPosted(i.Hash) {
+			continue
+		}
+
+		for _, filter := range room.Invasions {
+			if filter.matchesGruntType(i.GruntType) && filter.Area.Contains(&i.Location) {
+				p.postInvasion(room, &i)
+				roomState.postedInvasion(&i, true)
+				break
+			}
+		}
+	}
+}
+
+// getFortNameOr prefers the GeoDex name, then the name from the webhook, then the GUID
+func (p *Poster) getFortNameOr(guid, webhookName string) string {
+	name := p.getFortName(guid)
+	if name == guid && webhookName != "" {
+		name = webhookName
+	}
+	return name
+}
+
+func (p *Poster) postInvasion(room *RoomConfig, i *pogo.Invasion) {
+	endTime := time.Unix(i.EndTime, 0)
+	timeLeft := endTime.Sub(time.Now().Round(time.Second))
+	endTimeStr := endTime.Format("15:04:05")
+
+	stopName := p.getFortNameOr(i.PokestopID, i.PokestopName)
+
+	text := fmt.Sprintf("Invasion: %s until %s (%s left) at %s",
+		i.GruntType.ToString(), endTimeStr, timeLeft, stopName)
+	if room.FormatText {
+		stopStr := fmt.Sprintf("<a href=\"%s\">%s</a>", i.Location.ToLinkGMaps(), stopName)
+		fText := fmt.Sprintf("Invasion: %s until %s (%s left) at %s",
+			i.GruntType.ToString(), endTimeStr, timeLeft, stopStr)
+		p.sendFormattedText(room.RoomID, text, fText)
+	} else {
+		p.sendText(room.RoomID, text)
+	}
+}
@@ -0,0 +1,44 @@
+package roomservice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+func (p *Poster) processLureUpdate(l pogo.Lure) {
+	for _, room := range p.roomConfigs {
+		roomState := p.getOrCreateRoomState(room.RoomID)
+		if roomState.lureIsPosted(l.Hash) {
+			continue
+		}
+
+		for _, filter := range room.Lures {
+			if filter.matchesLureType(l.LureType) && filter.Area.Contains(&l.Location) {
+				p.postLure(room, &l)
+				roomState.postedLure(&l, true)
+				break
+			}
+		}
+	}
+}
+
+func (p *Poster) postLure(room *RoomConfig, l *pogo.Lure) {
+	endTime := time.Unix(l.EndTime, 0)
+	timeLeft := endTime.Sub(time.Now().Round(time.Second))
+	endTimeStr := endTime.Format("15:04:05")
+
+	stopName := p.getFortNameOr(l.PokestopID, l.PokestopName)
+
+	text := fmt.Sprintf("Lure: %s until %s (%s left) at %s",
+		l.LureType.ToString(), endTimeStr, timeLeft, stopName)
+	if room.FormatText {
+		stopStr := fmt.Sprintf("<a href=\"%s\">%s</a>", l.Location.ToLinkGMaps(), stopName)
+		fText := fmt.Sprintf("Lure: %s until %s (%s left) at %s",
+			l.LureType.ToString(), endTimeStr, timeLeft, stopStr)
+		p.sendFormattedText(room.RoomID, text, fText)
+	} else {
+		p.sendText(room.RoomID, text)
+	}
+}
@@ -0,0 +1,53 @@
+package roomservice
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+func (p *Poster) processQuestUpdate(q pogo.Quest) {
+	for _, room := range p.roomConfigs {
+		roomState := p.getOrCreateRoomState(room.RoomID)
+		if roomState.questIsPosted(q.Hash) {
+			continue
+		}
+
+		for _, filter := range room.Quests {
+			if filter.matchesQuest(&q) && filter.Area.Contains(&q.Location) {
+				p.postQuest(room, &q)
+				roomState.postedQuest(&q, true)
+				break
+			}
+		}
+	}
+}
+
+// getQuestRewardString returns the quest reward in human-readable form
+func (p *Poster) getQuestRewardString(q *pogo.Quest) (s string) {
+	switch q.RewardType {
+	case pogo.QuestRewardPokemonEncounter:
+		s = fmt.Sprintf("%s encounter", p.getPokemonName(q.PokemonID))
+	case pogo.QuestRewardItem:
+		s = fmt.Sprintf("%dx %s", q.Amount, pogo.ItemName(q.ItemID))
+	case pogo.QuestRewardCandy, pogo.QuestRewardXLCandy, pogo.QuestRewardMegaEnergy:
+		s = fmt.Sprintf("%d %s %s", q.Amount, p.getPokemonName(q.PokemonID), q.RewardType.ToString())
+	default:
+		s = fmt.Sprintf("%d %s", q.Amount, q.RewardType.ToString())
+	}
+	return
+}
+
+func (p *Poster) postQuest(room *RoomConfig, q *pogo.Quest) {
+	rewardStr := p.getQuestRewardString(q)
+	stopName := p.getFortNameOr(q.PokestopID, q.PokestopName)
+
+	text := fmt.Sprintf("Quest: %s at %s (%s), until midnight", rewardStr, stopName, q.Task)
+	if room.FormatText {
+		stopStr := fmt.Sprintf("<a href=\"%s\">%s</a>", q.Location.ToLinkGMaps(), stopName)
+		fText := fmt.Sprintf("Quest: %s at %s (%s), until midnight", rewardStr, stopStr, q.Task)
+		p.sendFormattedText(room.RoomID, text, fText)
+	} else {
+		p.sendText(room.RoomID, text)
+	}
+}
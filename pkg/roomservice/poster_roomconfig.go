@@ -2,6 +2,7 @@ package roomservice
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/jinzhu/copier"
 	log "github.com/sirupsen/logrus"
@@ -11,49 +12,126 @@ import (
 
 // GetRoomConfig returns a copy of the room configuration if there is one
 func (p *Poster) GetRoomConfig(roomID string) (*RoomConfig, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	rc, ok := p.roomConfigs[roomID]
-	return rc, ok
+	if !ok {
+		return nil, false
+	}
+
+	rcCopy := &RoomConfig{}
+	copier.CopyWithOption(rcCopy, rc, copier.Option{DeepCopy: true})
+	return rcCopy, true
+}
+
+// GetRoomIDs returns the IDs of all rooms with a RoomConfig, sorted
+func (p *Poster) GetRoomIDs() (roomIDs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	roomIDs = make([]string, 0, len(p.roomConfigs))
+	for roomID := range p.roomConfigs {
+		roomIDs = append(roomIDs, roomID)
+	}
+	sort.Strings(roomIDs)
+	return
 }
 
 // UpdateRoomConfig overwrites or adds the RoomConfig and appends the Filter
 func (p *Poster) UpdateRoomConfig(rcUpdate *RoomConfig) (created bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	defer p.commitRoomConfig(rcUpdate.RoomID)
 
+	// don't share filter slices with the caller
+	update := &RoomConfig{}
+	copier.CopyWithOption(update, rcUpdate, copier.Option{DeepCopy: true})
+	rcUpdate = update
+
 	if rc, ok := p.roomConfigs[rcUpdate.RoomID]; ok {
 		rc.AcceptCommands = rcUpdate.AcceptCommands
 		rc.FormatText = rcUpdate.FormatText
 		rc.Filter = append(rc.Filter, rcUpdate.Filter...)
+		rc.Invasions = append(rc.Invasions, rcUpdate.Invasions...)
+		rc.Quests = append(rc.Quests, rcUpdate.Quests...)
+		rc.Lures = append(rc.Lures, rcUpdate.Lures...)
+		rc.Gyms = append(rc.Gyms, rcUpdate.Gyms...)
 
 		created = false
 		return
 	}
 
 	// room config doesn't exist yet
-	p.roomConfigs[rcUpdate.RoomID] = &RoomConfig{}
-	copier.Copy(p.roomConfigs[rcUpdate.RoomID], rcUpdate)
+	if rcUpdate.Version == 0 {
+		// new configs are always in the current format
+		rcUpdate.Version = roomConfigVersion
+	}
+	p.roomConfigs[rcUpdate.RoomID] = rcUpdate
 	created = true
 	return
 }
 
-// DeleteFilters deletes all pokemon filters for a room if a RoomConfig exists
+// DeleteFilters deletes all filters for a room if a RoomConfig exists
 func (p *Poster) DeleteFilters(roomID string) (deleted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if rc, ok := p.roomConfigs[roomID]; ok {
 		rc.Filter = nil
+		rc.Invasions = nil
+		rc.Quests = nil
+		rc.Lures = nil
+		rc.Gyms = nil
 		p.commitRoomConfig(roomID)
 		deleted = true
 	}
 	return
 }
 
+// DeleteRoomConfig removes the RoomConfig, the room isn't posted to anymore
+func (p *Poster) DeleteRoomConfig(roomID string) (deleted bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.roomConfigs[roomID]; !ok {
+		return false
+	}
+	delete(p.roomConfigs, roomID)
+	if p.db != nil {
+		p.db.DeleteRoomConfig(roomID)
+	}
+	return true
+}
+
 // RoomConfigChange describes an update operation for an existing RoomConfig
 type RoomConfigChange struct {
 	ChangeAcceptCommands bool // update RC with value from given RoomConfig
 	ChangeFormatText     bool // same as above
+	ChangeWeatherAlerts  bool // same as above
+	ChangeStreamToken    bool // same as above
 	Operation            RoomConfigOperation
 	FilterIndex          int          // only when UpdateFilter=true
 	FilterChange         FilterChange // only when UpdateFilter=true
+	FilterList           FilterList   // only when RemoveFilter=true
 }
 
+// FilterList selects which filter list of a RoomConfig is meant
+type FilterList int
+
+const (
+	// FilterListPokemon is RoomConfig.Filter
+	FilterListPokemon FilterList = iota
+	// FilterListInvasion is RoomConfig.Invasions
+	FilterListInvasion
+	// FilterListQuest is RoomConfig.Quests
+	FilterListQuest
+	// FilterListLure is RoomConfig.Lures
+	FilterListLure
+	// FilterListGym is RoomConfig.Gyms
+	FilterListGym
+)
+
 // RoomConfigOperation describes what should be done in a RoomConfigChange
 type RoomConfigOperation int
 
@@ -75,10 +153,38 @@ const (
 	FilterChangeRemovePokemon
 	// FilterChangeArea replaces the area
 	FilterChangeArea
+	// FilterChangeAddRaidLevels adds egg levels to list
+	FilterChangeAddRaidLevels
+	// FilterChangeRemoveRaidLevels removes egg levels from list
+	FilterChangeRemoveRaidLevels
+	// FilterChangeEncounter replaces the encounter conditions
+	FilterChangeEncounter
+	// FilterChangeReplace replaces the whole filter
+	FilterChangeReplace
+	// FilterChangeEncounterIV replaces the IV range and keeps the other encounter conditions
+	FilterChangeEncounterIV
+	// FilterChangeEncounterStats replaces the per-stat minimums and keeps the other encounter conditions
+	FilterChangeEncounterStats
+	// FilterChangeEncounterCP replaces the CP range and keeps the other encounter conditions
+	FilterChangeEncounterCP
+	// FilterChangeEncounterLevel replaces the level range and keeps the other encounter conditions
+	FilterChangeEncounterLevel
 )
 
+// changesEncounter is true for the changes that only make sense for spawn filters
+func (c FilterChange) changesEncounter() bool {
+	switch c {
+	case FilterChangeEncounter, FilterChangeEncounterIV, FilterChangeEncounterStats, FilterChangeEncounterCP, FilterChangeEncounterLevel:
+		return true
+	}
+	return false
+}
+
 // ChangeRoomConfig edits an existing RoomConfig with the given changeset
 func (p *Poster) ChangeRoomConfig(roomID string, rcChange *RoomConfigChange, newValues *RoomConfig) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	// get RC
 	rc, ok := p.roomConfigs[roomID]
 	if !ok {
@@ -90,9 +196,12 @@ func (p *Poster) ChangeRoomConfig(roomID string, rcChange *RoomConfigChange, new
 
 	// sanity check
 	if rcChange.Operation == RoomConfigOperationAppendFilter {
-		if len(newValues.Filter) == 0 {
+		if newValues == nil || newValues.filterCount() == 0 {
 			return errors.New("there are no filters to append")
 		}
+		if rc.filterCount()+newValues.filterCount() > roomConfigFilterLimit {
+			return errors.New("too many filters")
+		}
 	}
 	if rcChange.Operation == RoomConfigOperationUpdateFilter {
 		if rcChange.FilterIndex < 0 || rcChange.FilterIndex >= len(rc.Filter) {
@@ -101,9 +210,22 @@ func (p *Poster) ChangeRoomConfig(roomID string, rcChange *RoomConfigChange, new
 		if len(newValues.Filter) != 1 {
 			return errors.New("supplied Filter count must be 1 for UpdateFilter operation")
 		}
+		if rcChange.FilterChange == FilterChangeAddRaidLevels || rcChange.FilterChange == FilterChangeRemoveRaidLevels {
+			if !rc.Filter[rcChange.FilterIndex].ListRaids {
+				return errors.New("raid levels can only be changed for raid filters")
+			}
+		}
+		if rcChange.FilterChange.changesEncounter() {
+			if rc.Filter[rcChange.FilterIndex].ListRaids {
+				return errors.New("encounter conditions can only be changed for spawn filters")
+			}
+			if rcChange.FilterChange != FilterChangeEncounter && newValues.Filter[0].Encounter == nil {
+				return errors.New("no encounter condition supplied")
+			}
+		}
 	}
 	if rcChange.Operation == RoomConfigOperationRemoveFilter {
-		if rcChange.FilterIndex < 0 || rcChange.FilterIndex >= len(rc.Filter) {
+		if rcChange.FilterIndex < 0 || rcChange.FilterIndex >= rc.filterListLength(rcChange.FilterList) {
 			return errors.New("invalid filter id")
 		}
 	}
@@ -123,14 +245,36 @@ func (p *Poster) ChangeRoomConfig(roomID string, rcChange *RoomConfigChange, new
 	if rcChange.ChangeFormatText {
 		rc.FormatText = newValues.FormatText
 	}
+	if rcChange.ChangeWeatherAlerts {
+		rc.WeatherAlerts = newValues.WeatherAlerts
+	}
+	if rcChange.ChangeStreamToken {
+		rc.StreamToken = newValues.StreamToken
+	}
 	switch rcChange.Operation {
 	case RoomConfigOperationAppendFilter:
 		rc.Filter = append(rc.Filter, newValues.Filter...)
+		rc.Invasions = append(rc.Invasions, newValues.Invasions...)
+		rc.Quests = append(rc.Quests, newValues.Quests...)
+		rc.Lures = append(rc.Lures, newValues.Lures...)
+		rc.Gyms = append(rc.Gyms, newValues.Gyms...)
 	case RoomConfigOperationUpdateFilter:
 		f := &rc.Filter[rcChange.FilterIndex]
 		p.changeRoomConfigFilter(f, rcChange.FilterChange, &newValues.Filter[0])
 	case RoomConfigOperationRemoveFilter:
-		rc.Filter = append(rc.Filter[:rcChange.FilterIndex], rc.Filter[rcChange.FilterIndex+1:]...)
+		i := rcChange.FilterIndex
+		switch rcChange.FilterList {
+		case FilterListPokemon:
+			rc.Filter = append(rc.Filter[:i], rc.Filter[i+1:]...)
+		case FilterListInvasion:
+			rc.Invasions = append(rc.Invasions[:i], rc.Invasions[i+1:]...)
+		case FilterListQuest:
+			rc.Quests = append(rc.Quests[:i], rc.Quests[i+1:]...)
+		case FilterListLure:
+			rc.Lures = append(rc.Lures[:i], rc.Lures[i+1:]...)
+		case FilterListGym:
+			rc.Gyms = append(rc.Gyms[:i], rc.Gyms[i+1:]...)
+		}
 	}
 
 	return
@@ -157,11 +301,53 @@ func (p *Poster) changeRoomConfigFilter(f *PokemonFilter, op FilterChange, newFi
 		f.PokemonIDs = newPokemonList
 	case FilterChangeArea:
 		f.Area = newFilter.Area
+	case FilterChangeAddRaidLevels:
+		for _, level := range newFilter.RaidLevels {
+			if !helpers.IntArrayContains(f.RaidLevels, level) {
+				f.RaidLevels = append(f.RaidLevels, level)
+			}
+		}
+	case FilterChangeRemoveRaidLevels:
+		newLevelList := []int{}
+		for _, level := range f.RaidLevels {
+			if !helpers.IntArrayContains(newFilter.RaidLevels, level) {
+				newLevelList = append(newLevelList, level)
+			}
+		}
+		f.RaidLevels = newLevelList
+	case FilterChangeEncounter:
+		f.Encounter = newFilter.Encounter
+	case FilterChangeReplace:
+		*f = *newFilter
+	case FilterChangeEncounterIV, FilterChangeEncounterStats, FilterChangeEncounterCP, FilterChangeEncounterLevel:
+		f.Encounter = mergeEncounterCondition(f.Encounter, op, newFilter.Encounter)
+	}
+}
+
+// mergeEncounterCondition returns a copy of c with the condition selected by op taken from update
+func mergeEncounterCondition(c *EncounterCondition, op FilterChange, update *EncounterCondition) *EncounterCondition {
+	merged := EncounterCondition{}
+	if c != nil {
+		merged = *c
 	}
+
+	switch op {
+	case FilterChangeEncounterIV:
+		merged.IV = update.IV
+	case FilterChangeEncounterStats:
+		merged.MinAttack = update.MinAttack
+		merged.MinDefense = update.MinDefense
+		merged.MinStamina = update.MinStamina
+	case FilterChangeEncounterCP:
+		merged.CP = update.CP
+	case FilterChangeEncounterLevel:
+		merged.Level = update.Level
+	}
+	return &merged
 }
 
 // Write RoomConfig changes to disk.
-// This needs to be called after every modification of a RoomConfig object!
+// This needs to be called after every modification of a RoomConfig object while holding p.mu!
 func (p *Poster) commitRoomConfig(roomID string) {
 	if p.db == nil {
 		return
@@ -177,4 +363,12 @@ func (p *Poster) readRoomConfigs() {
 
 	p.db.ReadRoomConfigs(p.roomConfigs)
 	log.Infof("read RoomConfig for %d rooms", len(p.roomConfigs))
+
+	for roomID, rc := range p.roomConfigs {
+		fromVersion := rc.Version
+		if rc.migrate() {
+			log.Infof("migrated RoomConfig of %s from version %d to %d", roomID, fromVersion, rc.Version)
+			p.commitRoomConfig(roomID)
+		}
+	}
 }
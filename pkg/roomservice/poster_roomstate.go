@@ -1,6 +1,36 @@
 package roomservice
 
-import log "github.com/sirupsen/logrus"
+import (
+	"github.com/jinzhu/copier"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetRoomState returns a copy of the room's state if there is one
+func (p *Poster) GetRoomState(roomID string) (*RoomState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rs, ok := p.roomStates[roomID]
+	if !ok {
+		return nil, false
+	}
+
+	rsCopy := NewRoomState()
+	copier.CopyWithOption(rsCopy, rs, copier.Option{DeepCopy: true})
+	return rsCopy, true
+}
+
+// ClearRoomState forgets which spawns, raids and other events were posted in the room
+func (p *Poster) ClearRoomState(roomID string) (cleared bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rs, ok := p.roomStates[roomID]; ok {
+		rs.clear(true, true, true)
+		cleared = true
+	}
+	return
+}
 
 func (p *Poster) saveRoomStates() {
 	if p.db == nil {
@@ -1,12 +1,20 @@
 package roomservice
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jinzhu/copier"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/spezifisch/silphtelescope/pkg/geodex"
+	"github.com/spezifisch/silphtelescope/pkg/ingest"
+	"github.com/spezifisch/silphtelescope/pkg/metrics"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 )
 
@@ -31,6 +39,13 @@ func TestRoomConfigChanges(t *testing.T) {
 	assert.Equal(t, true, ok)
 	assert.Contains(t, gotRC.ToString(), testRoom)
 
+	// it's a copy
+	gotRC.Filter[0].PokemonIDs[0] = 999
+	assert.Equal(t, 2, p.roomConfigs[testRoom].Filter[0].PokemonIDs[0])
+	rcA.Filter[0].PokemonIDs[0] = 999
+	assert.Equal(t, 2, p.roomConfigs[testRoom].Filter[0].PokemonIDs[0])
+	rcA.Filter[0].PokemonIDs[0] = 2
+
 	// add another filter
 	rcB := getTestRoomConfig(testRoom)
 	rcB.AcceptCommands = true
@@ -47,6 +62,68 @@ func TestRoomConfigChanges(t *testing.T) {
 	assert.Equal(t, 2, len(p.roomConfigs[testRoom].Filter))
 }
 
+func TestRoomConfigReplaceAndDelete(t *testing.T) {
+	db := getMockDB()
+	p := NewPoster(&testChatter{}, db)
+
+	p.UpdateRoomConfig(getTestRoomConfig("!b@example.com"))
+	p.UpdateRoomConfig(getTestRoomConfig("!a@example.com"))
+	assert.Equal(t, []string{"!a@example.com", "!b@example.com"}, p.GetRoomIDs())
+
+	// replace the whole filter
+	replacement := PokemonFilter{ListRaids: true, PokemonIDs: []int{}, RaidLevels: []int{5}}
+	change := &RoomConfigChange{
+		Operation:    RoomConfigOperationUpdateFilter,
+		FilterChange: FilterChangeReplace,
+	}
+	assert.Nil(t, p.ChangeRoomConfig("!a@example.com", change, &RoomConfig{Filter: []PokemonFilter{replacement}}))
+	rc, _ := p.GetRoomConfig("!a@example.com")
+	assert.Equal(t, replacement, rc.Filter[0])
+
+	// the limit counts all filter lists
+	tooMany := &RoomConfig{Filter: make([]PokemonFilter, roomConfigFilterLimit)}
+	change = &RoomConfigChange{Operation: RoomConfigOperationAppendFilter}
+	assert.NotNil(t, p.ChangeRoomConfig("!a@example.com", change, tooMany))
+
+	assert.True(t, p.DeleteRoomConfig("!a@example.com"))
+	assert.False(t, p.DeleteRoomConfig("!a@example.com"))
+	assert.Equal(t, []string{"!b@example.com"}, p.GetRoomIDs())
+	_, saved := db.savedRoomConfigs["!a@example.com"]
+	assert.False(t, saved)
+}
+
+func TestRoomConfigChangeEncounter(t *testing.T) {
+	p := NewPoster(&testChatter{}, nil)
+	rc := getTestRoomConfig("!a@example.com")
+	rc.Filter = append(rc.Filter, PokemonFilter{ListRaids: true, Area: rc.Filter[0].Area})
+	p.UpdateRoomConfig(rc)
+
+	change := func(index int, op FilterChange, condition *EncounterCondition) error {
+		return p.ChangeRoomConfig("!a@example.com", &RoomConfigChange{
+			Operation:    RoomConfigOperationUpdateFilter,
+			FilterIndex:  index,
+			FilterChange: op,
+		}, &RoomConfig{Filter: []PokemonFilter{{Encounter: condition}}})
+	}
+
+	// each change keeps the other conditions
+	assert.Nil(t, change(0, FilterChangeEncounterIV, &EncounterCondition{IV: &FloatRange{Min: 90, Max: 100}}))
+	assert.Nil(t, change(0, FilterChangeEncounterCP, &EncounterCondition{CP: &IntRange{Min: 1500}}))
+	assert.Nil(t, change(0, FilterChangeEncounterStats, &EncounterCondition{MinAttack: 15}))
+	assert.Nil(t, change(0, FilterChangeEncounterLevel, &EncounterCondition{Level: &IntRange{Min: 30}}))
+	rc, _ = p.GetRoomConfig("!a@example.com")
+	assert.Equal(t, &EncounterCondition{
+		IV:        &FloatRange{Min: 90, Max: 100},
+		MinAttack: 15,
+		CP:        &IntRange{Min: 1500},
+		Level:     &IntRange{Min: 30},
+	}, rc.Filter[0].Encounter)
+
+	assert.NotNil(t, change(0, FilterChangeEncounterCP, nil))
+	assert.NotNil(t, change(1, FilterChangeEncounterCP, &EncounterCondition{CP: &IntRange{Min: 1500}}))
+	assert.NotNil(t, change(2, FilterChangeEncounterCP, &EncounterCondition{CP: &IntRange{Min: 1500}}))
+}
+
 func TestRoomStateChanges(t *testing.T) {
 	rs := NewRoomState()
 
@@ -89,6 +166,25 @@ func TestRoomStateChanges(t *testing.T) {
 	assert.Equal(t, false, rs.spawnIsPosted(encA))
 }
 
+func TestPosterCheckData(t *testing.T) {
+	p := NewPoster(nil, nil)
+
+	status, err := p.CheckData(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "no data yet", status)
+	_, err = p.CheckData(time.Minute)
+	assert.NotNil(t, err)
+
+	p.lastDataTime = time.Now().Add(-2 * time.Minute)
+	status, err = p.CheckData(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "last data 2m0s ago", status)
+	_, err = p.CheckData(time.Minute)
+	assert.NotNil(t, err)
+	_, err = p.CheckData(5 * time.Minute)
+	assert.Nil(t, err)
+}
+
 func TestPosterTicker(t *testing.T) {
 	c := &testChatter{
 		MessageReceived: make(chan bool, 1),
@@ -130,6 +226,11 @@ func startPoster() (*Poster, chan bool, *testChatter) {
 	p.GymUpdates = make(chan pogo.Gym)
 	p.RaidUpdates = make(chan pogo.Raid)
 	p.SpawnUpdates = make(chan pogo.Spawn)
+	p.WeatherUpdates = make(chan pogo.Weather)
+	p.InvasionUpdates = make(chan pogo.Invasion)
+	p.QuestUpdates = make(chan pogo.Quest)
+	p.LureUpdates = make(chan pogo.Lure)
+	p.FortUpdates = make(chan pogo.Fort)
 	p.Quit = make(chan bool)
 
 	// p.Run blocks, so wrap it in a goroutine
@@ -156,6 +257,11 @@ func startPosterResumable(doResume bool, mockDB Persister, mockMainControl MainC
 	p.GymUpdates = make(chan pogo.Gym)
 	p.RaidUpdates = make(chan pogo.Raid)
 	p.SpawnUpdates = make(chan pogo.Spawn)
+	p.WeatherUpdates = make(chan pogo.Weather)
+	p.InvasionUpdates = make(chan pogo.Invasion)
+	p.QuestUpdates = make(chan pogo.Quest)
+	p.LureUpdates = make(chan pogo.Lure)
+	p.FortUpdates = make(chan pogo.Fort)
 	p.Quit = make(chan bool)
 
 	// p.Run blocks, so wrap it in a goroutine
@@ -178,6 +284,11 @@ func startPosterPokedex(t *testing.T, pokedexFile string) (*Poster, chan bool, *
 	p.GymUpdates = make(chan pogo.Gym)
 	p.RaidUpdates = make(chan pogo.Raid)
 	p.SpawnUpdates = make(chan pogo.Spawn)
+	p.WeatherUpdates = make(chan pogo.Weather)
+	p.InvasionUpdates = make(chan pogo.Invasion)
+	p.QuestUpdates = make(chan pogo.Quest)
+	p.LureUpdates = make(chan pogo.Lure)
+	p.FortUpdates = make(chan pogo.Fort)
 	p.Quit = make(chan bool)
 
 	var err error
@@ -199,6 +310,10 @@ func TestPosterAllTypesEmpty(t *testing.T) {
 	// each line blocks until p.Run processes it
 	p.GymUpdates <- pogo.Gym{}
 	p.RaidUpdates <- pogo.Raid{}
+	p.WeatherUpdates <- pogo.Weather{}
+	p.InvasionUpdates <- pogo.Invasion{}
+	p.QuestUpdates <- pogo.Quest{}
+	p.LureUpdates <- pogo.Lure{}
 	p.SpawnUpdates <- pogo.Spawn{}
 	// no need to read from MessageReceived as it has space for 10 msgs
 	p.Quit <- true
@@ -282,6 +397,7 @@ func getTestPoint2KMAway() pogo.Location {
 func getTestRoomConfig(testRoom string) *RoomConfig {
 	return &RoomConfig{
 		RoomID:         testRoom,
+		Version:        roomConfigVersion,
 		AcceptCommands: false,
 		FormatText:     false,
 		Filter: []PokemonFilter{
@@ -289,6 +405,7 @@ func getTestRoomConfig(testRoom string) *RoomConfig {
 				ListRaids:  false,
 				ListWanted: true,
 				PokemonIDs: []int{2, 4, 8, 16, 25, 32},
+				RaidLevels: []int{}, // see below
 				Area: pogo.LocationRadius{
 					Location: pogo.Location{
 						Latitude:  30.04896,
@@ -298,6 +415,11 @@ func getTestRoomConfig(testRoom string) *RoomConfig {
 				},
 			},
 		},
+		// copier turns nil slices into empty ones, so start with empty ones for comparisons
+		Invasions: []InvasionFilter{},
+		Quests:    []QuestFilter{},
+		Lures:     []LureFilter{},
+		Gyms:      []GymFilter{},
 	}
 }
 
@@ -364,6 +486,174 @@ func TestPosterSpawns(t *testing.T) {
 	<-done
 }
 
+func TestPosterMetrics(t *testing.T) {
+	p, done, c := startPoster()
+	processed := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues(ingest.EventSpawn))
+	matched := testutil.ToFloat64(metrics.EventsMatched.WithLabelValues(ingest.EventSpawn))
+
+	testRoom := "!metrics@example.com"
+	p.UpdateRoomConfig(getTestRoomConfig(testRoom))
+
+	s := getTestSpawn()
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+	s.Pokemon.ID = 5
+	s.EncounterID = "x"
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	p.Quit <- true
+	<-done
+
+	assert.Equal(t, processed+2, testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues(ingest.EventSpawn)))
+	assert.Equal(t, matched+1, testutil.ToFloat64(metrics.EventsMatched.WithLabelValues(ingest.EventSpawn)))
+
+	p.UpdateMetrics()
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.RoomStateEntries.WithLabelValues(testRoom, ingest.EventSpawn)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.RoomStateEntries.WithLabelValues(testRoom, ingest.EventRaid)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.QueueLength.WithLabelValues("poster_spawn")))
+}
+
+func TestPosterExclusionFilter(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+
+	// everything except Pidgey and Rattata
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter[0].ListWanted = false
+	rc.Filter[0].PokemonIDs = []int{16, 19}
+	// raids except Mewtwo
+	raidFilter := rc.Filter[0]
+	raidFilter.ListRaids = true
+	raidFilter.PokemonIDs = []int{150}
+	rc.Filter = append(rc.Filter, raidFilter)
+	p.UpdateRoomConfig(rc)
+
+	// excluded
+	s := getTestSpawn()
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	// not excluded
+	s = getTestSpawn()
+	s.EncounterID = "notapidgey"
+	s.Pokemon.ID = 25
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+
+	// not excluded, but out of area
+	s = getTestSpawn()
+	s.EncounterID = "faraway"
+	s.Pokemon.ID = 25
+	s.Location = getTestPoint2KMAway()
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	// excluded raid
+	r := getTestRaid()
+	p.RaidUpdates <- r
+	c.ExpectNoMessage(t)
+
+	// any other raid
+	r = getTestRaid()
+	r.Hash = "notmewtwo"
+	r.Pokemon.ID = 151
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+
+	p.Quit <- true
+	<-done
+}
+
+func TestPosterEncounterFilter(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+
+	// hundos of every species
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter[0].ListWanted = false
+	rc.Filter[0].PokemonIDs = []int{}
+	rc.Filter[0].Encounter = &EncounterCondition{IV: &FloatRange{Min: 100, Max: 100}}
+	// Pikachu with good attack and level 30+
+	pikaFilter := getTestRoomConfig(testRoom).Filter[0]
+	pikaFilter.PokemonIDs = []int{25}
+	pikaFilter.Encounter = &EncounterCondition{
+		MinAttack: 14,
+		Level:     &IntRange{Min: 30},
+		CP:        &IntRange{Min: 10, Max: 1000},
+	}
+	rc.Filter = append(rc.Filter, pikaFilter)
+	p.UpdateRoomConfig(rc)
+
+	// no encounter data
+	s := getTestSpawn()
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	// not a hundo
+	s = getTestSpawn()
+	s.EncounterID = "meh"
+	s.Encounter = &pogo.Encounter{Attack: 15, Defense: 15, Stamina: 14}
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	// hundo, later update of the spawn without encounter data isn't reposted
+	s.Encounter = &pogo.Encounter{Attack: 15, Defense: 15, Stamina: 15}
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+	s.Encounter = nil
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	// Pikachu conditions
+	s = getTestSpawn()
+	s.EncounterID = "pika1"
+	s.Pokemon.ID = 25
+	s.Encounter = &pogo.Encounter{Attack: 13, Defense: 15, Stamina: 15, Level: 35, CP: 900}
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	s.EncounterID = "pika2"
+	s.Encounter = &pogo.Encounter{Attack: 14, Level: 29, CP: 900}
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	s.EncounterID = "pika3"
+	s.Encounter = &pogo.Encounter{Attack: 14, Level: 35, CP: 1001}
+	p.SpawnUpdates <- s
+	c.ExpectNoMessage(t)
+
+	s.EncounterID = "pika4"
+	s.Encounter = &pogo.Encounter{Attack: 14, Level: 35, CP: 1000}
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
+func TestEncounterCondition(t *testing.T) {
+	nundo := &EncounterCondition{IV: &FloatRange{Min: 0, Max: 0}}
+	assert.True(t, nundo.matches(&pogo.Encounter{}))
+	assert.False(t, nundo.matches(&pogo.Encounter{Stamina: 1}))
+	assert.False(t, nundo.matches(nil))
+
+	c := &EncounterCondition{MinDefense: 10, Level: &IntRange{Min: 20, Max: 25}}
+	assert.True(t, c.matches(&pogo.Encounter{Defense: 10, Level: 25}))
+	assert.False(t, c.matches(&pogo.Encounter{Defense: 9, Level: 25}))
+	assert.False(t, c.matches(&pogo.Encounter{Defense: 10, Level: 26}))
+	assert.Equal(t, "stats>=0/10/0 L:20-25", c.ToString())
+
+	// no conditions
+	f := PokemonFilter{}
+	assert.True(t, f.matchesEncounter(nil))
+}
+
 func TestPosterResumeWithoutDB(t *testing.T) {
 	mockMainControl := getMockMainControl()
 
@@ -433,6 +723,44 @@ func TestPosterQuitAndResume(t *testing.T) {
 	assert.Equal(t, false, mockMainControl.stopCalled)
 }
 
+func TestPosterMigrateRoomConfig(t *testing.T) {
+	mockDB := getMockDB()
+	mockMainControl := getMockMainControl()
+
+	// a config saved before versioning was introduced
+	testRoom := "!old@example.com"
+	rc := getTestRoomConfig(testRoom)
+	rc.Version = 0
+	rc.AcceptCommands = false
+	// raid filter as created by the filter command before version 2
+	raidFilter := rc.Filter[0]
+	raidFilter.ListRaids = true
+	raidFilter.ListWanted = false
+	raidFilter.PokemonIDs = []int{150}
+	rc.Filter = append(rc.Filter, raidFilter)
+	mockDB.savedRoomConfigs[testRoom] = rc
+
+	doResume := true
+	p, done, _ := startPosterResumable(doResume, mockDB, mockMainControl)
+
+	// wait until Poster.Run() read the configs
+	p.SpawnUpdates <- pogo.Spawn{}
+
+	assert.Equal(t, roomConfigVersion, p.roomConfigs[testRoom].Version)
+	assert.Equal(t, true, p.roomConfigs[testRoom].AcceptCommands)
+	assert.Equal(t, true, p.roomConfigs[testRoom].Filter[0].ListWanted)
+	assert.Equal(t, false, p.roomConfigs[testRoom].Filter[0].ListRaids)
+	assert.Equal(t, true, p.roomConfigs[testRoom].Filter[1].ListWanted)
+	assert.Equal(t, true, p.roomConfigs[testRoom].Filter[1].ListRaids)
+	assert.Equal(t, roomConfigVersion, mockDB.savedRoomConfigs[testRoom].Version)
+
+	// already migrated configs stay untouched
+	assert.Equal(t, false, p.roomConfigs[testRoom].migrate())
+
+	p.Quit <- true
+	<-done
+}
+
 type mockMainController struct {
 	stopCalled bool
 }
@@ -461,6 +789,9 @@ func (p *mockPersister) SaveRoomConfigs(roomConfigs map[string]*RoomConfig) {
 func (p *mockPersister) ReadRoomConfigs(roomConfigs map[string]*RoomConfig) {
 	copier.Copy(&roomConfigs, p.savedRoomConfigs)
 }
+func (p *mockPersister) DeleteRoomConfig(roomID string) {
+	delete(p.savedRoomConfigs, roomID)
+}
 
 func (p *mockPersister) SaveRoomState(roomID string, roomState *RoomState) {
 	p.savedRoomStates[roomID] = roomState
@@ -481,6 +812,32 @@ func getMockDB() *mockPersister {
 
 var testPokedexFile = "../../data/pokedex.json"
 
+func TestPosterSendsWithoutLock(t *testing.T) {
+	p, done, c := startPoster()
+	// the homeserver takes its time, sending blocks until the message is expected
+	c.MessageReceived = make(chan bool)
+
+	testRoom := "!foo@example.com"
+	p.UpdateRoomConfig(getTestRoomConfig(testRoom))
+	p.SpawnUpdates <- getTestSpawn()
+
+	// commands and API calls don't wait for the homeserver
+	roomIDs := make(chan []string)
+	go func() {
+		roomIDs <- p.GetRoomIDs()
+	}()
+	select {
+	case ids := <-roomIDs:
+		assert.Equal(t, []string{testRoom}, ids)
+	case <-time.After(time.Second):
+		t.Error("GetRoomIDs blocked while sending")
+	}
+	c.ExpectMessage(t)
+
+	p.Quit <- true
+	<-done
+}
+
 func TestPosterSpawnsWithPokedex(t *testing.T) {
 	p, done, c := startPosterPokedex(t, testPokedexFile)
 
@@ -526,6 +883,23 @@ func TestPosterSpawnsWithPokedex(t *testing.T) {
 	assert.Equal(t, testRoom, c.LastRoomID)
 	assert.Contains(t, c.LastFormattedText, "Pikachu")
 
+	// encountered spawn
+	s = getTestSpawn()
+	s.EncounterID = "fuenf"
+	s.Encounter = &pogo.Encounter{
+		Attack:  15,
+		Defense: 15,
+		Stamina: 15,
+		CP:      512,
+		Level:   21,
+		Move1:   219,
+		Move2:   80,
+	}
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Pidgey (de: Taubsi) 100% (15/15/15) CP 512 L21")
+	assert.Contains(t, c.LastFormattedText, "Pidgey (de: Taubsi) 100% (15/15/15) CP 512 L21")
+
 	// wait
 	p.Quit <- true
 	<-done
@@ -540,7 +914,7 @@ func TestPosterRaids(t *testing.T) {
 
 	// add raid config
 	rc := getTestRoomConfig(testRoom)
-	rc.Filter[0].ListWanted = false
+	rc.Filter[0].ListWanted = true
 	rc.Filter[0].ListRaids = true
 	rc.Filter[0].PokemonIDs = []int{150, 151}
 	p.UpdateRoomConfig(rc)
@@ -548,7 +922,7 @@ func TestPosterRaids(t *testing.T) {
 	// add raid config for room with formatted text
 	rc = getTestRoomConfig(formattedRoom)
 	rc.FormatText = true
-	rc.Filter[0].ListWanted = false
+	rc.Filter[0].ListWanted = true
 	rc.Filter[0].ListRaids = true
 	rc.Filter[0].PokemonIDs = []int{1}
 	p.UpdateRoomConfig(rc)
@@ -589,6 +963,8 @@ func TestPosterRaids(t *testing.T) {
 	p.RaidUpdates <- r
 	c.ExpectNoMessage(t)
 
+	// new raid object, the Pokemon pointer of the last one might still be in use by Run()
+	r = getTestRaid()
 	r.Pokemon.ID = 25
 	r.Hash = "abc3"
 	r.Level = 3
@@ -603,6 +979,8 @@ func TestPosterRaids(t *testing.T) {
 	c.ExpectNoMessage(t)
 
 	// we should have 2 raids now
+	// (lock the poster because Run() might still be working on the last update)
+	p.mu.Lock()
 	rs := p.getOrCreateRoomState(testRoom)
 	assert.Equal(t, 2, len(rs.Raids))
 	assert.Equal(t, 0, len(rs.Spawns))
@@ -622,6 +1000,7 @@ func TestPosterRaids(t *testing.T) {
 	now = postedRaidEndTime + 1
 	rs.removeExpired(now)
 	assert.Equal(t, false, rs.raidIsPosted(postedRaidHash))
+	p.mu.Unlock()
 
 	// post it again
 	// this should work now as we removed the hash above
@@ -629,13 +1008,15 @@ func TestPosterRaids(t *testing.T) {
 	r.Hash = postedRaidHash
 	p.RaidUpdates <- r
 	c.ExpectMessage(t)
+	p.mu.Lock()
 	assert.Equal(t, 1, len(rs.Raids))
 	assert.Equal(t, 0, len(rs.Spawns))
 
 	// clear all states
-	rs.clear(true, true)
+	rs.clear(true, true, true)
 	assert.Equal(t, 0, len(rs.Raids))
 	assert.Equal(t, 0, len(rs.Spawns))
+	p.mu.Unlock()
 
 	// send raid to formatted room
 	r = getTestRaid()
@@ -663,7 +1044,7 @@ func TestPosterRaidsDex(t *testing.T) {
 	testRoom := "!foo@example.com"
 
 	rc := getTestRoomConfig(testRoom)
-	rc.Filter[0].ListWanted = false
+	rc.Filter[0].ListWanted = true
 	rc.Filter[0].ListRaids = true
 	rc.Filter[0].PokemonIDs = []int{150, 151}
 	p.UpdateRoomConfig(rc)
@@ -676,6 +1057,7 @@ func TestPosterRaidsDex(t *testing.T) {
 	assert.Contains(t, c.LastText, "conke")
 	c.PrintLastMessage()
 
+	r = getTestRaid()
 	r.Pokemon.ID = 151
 	r.Hash = "itsamew"
 	r.Level = 6
@@ -690,6 +1072,716 @@ func TestPosterRaidsDex(t *testing.T) {
 	<-done
 }
 
+func TestPosterRaidEggs(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+	formattedRoom := "!formatted@example.com"
+
+	// level 5 eggs, no raid bosses
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter[0].ListWanted = true
+	rc.Filter[0].ListRaids = true
+	rc.Filter[0].PokemonIDs = []int{}
+	rc.Filter[0].RaidLevels = []int{5, pogo.RaidLevelMega}
+	p.UpdateRoomConfig(rc)
+
+	// only raid bosses, no eggs
+	rc = getTestRoomConfig(formattedRoom)
+	rc.FormatText = true
+	rc.Filter[0].ListWanted = true
+	rc.Filter[0].ListRaids = true
+	rc.Filter[0].PokemonIDs = []int{150}
+	p.UpdateRoomConfig(rc)
+
+	// egg level isn't wanted
+	r := getTestEgg()
+	p.RaidUpdates <- r
+	c.ExpectNoMessage(t)
+
+	// wanted egg
+	r = getTestEgg()
+	r.Hash = "egg5"
+	r.Level = 5
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+	assert.Contains(t, c.LastText, "Level 5 Egg")
+	assert.Contains(t, c.LastText, "bepis")
+
+	// same egg again
+	p.RaidUpdates <- r
+	c.ExpectNoMessage(t)
+
+	p.mu.Lock()
+	rs := p.getOrCreateRoomState(testRoom)
+	assert.True(t, rs.raidIsPostedEgg("egg5"))
+	p.mu.Unlock()
+
+	// egg hatched: follow-up in the egg room and a normal post in the boss room
+	r = getTestRaid()
+	r.Hash = "egg5"
+	r.GymID = "bepis"
+	p.RaidUpdates <- r
+	c.ExpectMessages(t, 2)
+
+	// the hatched raid isn't posted twice
+	p.RaidUpdates <- r
+	c.ExpectNoMessage(t)
+
+	p.mu.Lock()
+	rs = p.getOrCreateRoomState(testRoom)
+	assert.True(t, rs.raidIsPosted("egg5"))
+	assert.False(t, rs.raidIsPostedEgg("egg5"))
+	rs = p.getOrCreateRoomState(formattedRoom)
+	assert.True(t, rs.raidIsPosted("egg5"))
+	assert.False(t, rs.raidIsPostedEgg("egg5"))
+	p.mu.Unlock()
+
+	// mega egg in both rooms
+	err := p.ChangeRoomConfig(formattedRoom, &RoomConfigChange{
+		Operation:    RoomConfigOperationUpdateFilter,
+		FilterIndex:  0,
+		FilterChange: FilterChangeAddRaidLevels,
+	}, &RoomConfig{
+		Filter: []PokemonFilter{{RaidLevels: []int{pogo.RaidLevelMega}}},
+	})
+	assert.Nil(t, err)
+
+	r = getTestEgg()
+	r.Hash = "mega"
+	r.Level = pogo.RaidLevelMega
+	p.RaidUpdates <- r
+	c.ExpectMessages(t, 2)
+	assert.Contains(t, c.LastText, "Mega Egg")
+
+	// level 5 eggs, but not this boss
+	err = p.ChangeRoomConfig(testRoom, &RoomConfigChange{
+		Operation:    RoomConfigOperationUpdateFilter,
+		FilterIndex:  0,
+		FilterChange: FilterChangeReplace,
+	}, &RoomConfig{
+		Filter: []PokemonFilter{{
+			Area:       rc.Filter[0].Area,
+			ListRaids:  true,
+			ListWanted: false,
+			PokemonIDs: []int{150},
+			RaidLevels: []int{5},
+		}},
+	})
+	assert.Nil(t, err)
+
+	r = getTestEgg()
+	r.Hash = "excluded"
+	r.Level = 5
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+
+	// only the boss room gets the hatched raid
+	r = getTestRaid()
+	r.Hash = "excluded"
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.Equal(t, formattedRoom, c.LastRoomID)
+	c.ExpectNoMessage(t)
+
+	p.mu.Lock()
+	rs = p.getOrCreateRoomState(testRoom)
+	assert.True(t, rs.raidIsPosted("excluded"))
+	assert.False(t, rs.raidIsPostedEgg("excluded"))
+	p.mu.Unlock()
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
+func getTestWeather() pogo.Weather {
+	// the cell containing the test spawns and raids
+	l := pogo.Location{
+		Latitude:  30.05113,
+		Longitude: 31.21918,
+	}
+	return pogo.Weather{
+		CellID:     pogo.WeatherCellID(&l),
+		Condition:  pogo.WeatherRainy,
+		Location:   l,
+		UpdateTime: 1613800682,
+	}
+}
+
+func TestPosterWeather(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+	alertRoom := "!alerts@example.com"
+
+	rc := getTestRoomConfig(testRoom)
+	raidFilter := getTestRoomConfig(testRoom).Filter[0]
+	raidFilter.ListRaids = true
+	raidFilter.PokemonIDs = []int{150}
+	rc.Filter = append(rc.Filter, raidFilter)
+	p.UpdateRoomConfig(rc)
+
+	// only gets weather alerts
+	rc = getTestRoomConfig(alertRoom)
+	rc.Filter[0].PokemonIDs = []int{}
+	rc.WeatherAlerts = true
+	p.UpdateRoomConfig(rc)
+
+	// raid without known weather
+	r := getTestRaid()
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.NotContains(t, c.LastText, "weather")
+
+	// no alert
+	w := getTestWeather()
+	p.WeatherUpdates <- w
+	// this blocks until the weather update is processed
+	p.GymUpdates <- pogo.Gym{}
+	c.ExpectNoMessage(t)
+
+	l := getTestSpawn().Location
+	gotWeather, ok := p.GetWeather(&l)
+	assert.True(t, ok)
+	assert.Equal(t, w, gotWeather)
+	// weather cells are ~10 km wide
+	l.Latitude += 0.5
+	_, ok = p.GetWeather(&l)
+	assert.False(t, ok)
+
+	r = getTestRaid()
+	r.Hash = "rainy"
+	p.RaidUpdates <- r
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "[weather: Rainy (boosts Water, Electric, Bug)]")
+
+	s := getTestSpawn()
+	s.BoostedWeather = pogo.WeatherRainy
+	p.SpawnUpdates <- s
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+	assert.Contains(t, c.LastText, "boosted by Rainy")
+
+	// alert only in the room that opted in
+	w.AlertSeverity = pogo.AlertModerate
+	p.WeatherUpdates <- w
+	c.ExpectMessage(t)
+	assert.Equal(t, alertRoom, c.LastRoomID)
+	assert.Contains(t, c.LastText, "moderate")
+
+	// same alert again
+	p.WeatherUpdates <- w
+	c.ExpectNoMessage(t)
+
+	// more severe
+	w.AlertSeverity = pogo.AlertExtreme
+	p.WeatherUpdates <- w
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "extreme")
+
+	// other cell
+	w = getTestWeather()
+	w.Location.Latitude += 0.5
+	w.CellID = pogo.WeatherCellID(&w.Location)
+	w.AlertSeverity = pogo.AlertExtreme
+	p.WeatherUpdates <- w
+	c.ExpectNoMessage(t)
+
+	// wait
+	p.Quit <- true
+	<-done
+	c.ExpectNoMessage(t)
+}
+
+func getTestInvasion() pogo.Invasion {
+	var endTime int64 = 1613800682 // 6:58:02
+	startTime := endTime - 30*60
+
+	return pogo.Invasion{
+		Hash:         "stop1:1613798882",
+		PokestopID:   "stop1",
+		PokestopName: "Sphinx",
+		GruntType:    37,
+		TimestampRange: pogo.TimestampRange{
+			StartTime: startTime,
+			EndTime:   endTime,
+		},
+		Location: pogo.Location{
+			Latitude:  30.05113,
+			Longitude: 31.21918,
+		},
+	}
+}
+
+func TestPosterInvasions(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+	leaderRoom := "!leaders@example.com"
+
+	// all invasions
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter = nil
+	rc.Invasions = []InvasionFilter{
+		{Area: getTestRoomConfig(testRoom).Filter[0].Area},
+	}
+	p.UpdateRoomConfig(rc)
+
+	// only leaders
+	rc = getTestRoomConfig(leaderRoom)
+	rc.Filter = nil
+	rc.FormatText = true
+	rc.Invasions = []InvasionFilter{
+		{Area: getTestRoomConfig(testRoom).Filter[0].Area, GruntTypes: []int{41, 42, 43}},
+	}
+	p.UpdateRoomConfig(rc)
+
+	i := getTestInvasion()
+	p.InvasionUpdates <- i
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+	assert.Contains(t, c.LastText, "Invasion: Rock Grunt (male)")
+	assert.Contains(t, c.LastText, "at Sphinx")
+
+	// dedup by pokestop and incident start
+	p.InvasionUpdates <- i
+	c.ExpectNoMessage(t)
+
+	// leader at the same stop, new incident
+	i = getTestInvasion()
+	i.Hash = "stop1:1613799000"
+	i.GruntType = pogo.GruntTypeSierra
+	i.PokestopName = ""
+	p.InvasionUpdates <- i
+	c.ExpectMessages(t, 2)
+
+	// out of area
+	i = getTestInvasion()
+	i.Hash = "stop2:1613799000"
+	i.Location = getTestPoint2KMAway()
+	p.InvasionUpdates <- i
+	c.ExpectNoMessage(t)
+
+	p.mu.Lock()
+	rs := p.getOrCreateRoomState(testRoom)
+	assert.Equal(t, 2, len(rs.Invasions))
+	assert.True(t, rs.invasionIsPosted("stop1:1613799000"))
+	rs = p.getOrCreateRoomState(leaderRoom)
+	assert.Equal(t, 1, len(rs.Invasions))
+
+	// expiry
+	rs.removeExpired(i.EndTime + 1)
+	assert.Equal(t, 0, len(rs.Invasions))
+	p.mu.Unlock()
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
+func TestPosterFortNameOr(t *testing.T) {
+	p := NewPoster(&testChatter{}, nil)
+	assert.Equal(t, "Sphinx", p.getFortNameOr("stop1", "Sphinx"))
+	assert.Equal(t, "stop1", p.getFortNameOr("stop1", ""))
+}
+
+func getTestLure() pogo.Lure {
+	var endTime int64 = 1613800682 // 6:58:02
+
+	return pogo.Lure{
+		Hash:         "stop1:1613800682",
+		PokestopID:   "stop1",
+		PokestopName: "Sphinx",
+		LureType:     pogo.LureTypeGlacial,
+		TimestampRange: pogo.TimestampRange{
+			StartTime: endTime - pogo.LureDuration,
+			EndTime:   endTime,
+		},
+		Location: pogo.Location{
+			Latitude:  30.05113,
+			Longitude: 31.21918,
+		},
+	}
+}
+
+func TestPosterLures(t *testing.T) {
+	p, done, c := startPoster()
+
+	testRoom := "!foo@example.com"
+
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter = nil
+	rc.Lures = []LureFilter{
+		{
+			Area:      getTestRoomConfig(testRoom).Filter[0].Area,
+			LureTypes: []int{int(pogo.LureTypeGlacial), int(pogo.LureTypeMagnetic)},
+		},
+	}
+	p.UpdateRoomConfig(rc)
+
+	l := getTestLure()
+	p.LureUpdates <- l
+	c.ExpectMessage(t)
+	assert.Equal(t, testRoom, c.LastRoomID)
+	assert.Contains(t, c.LastText, "Lure: Glacial Lure Module until ")
+	assert.Contains(t, c.LastText, "left) at Sphinx")
+
+	// dedup by pokestop and lure expiration
+	p.LureUpdates <- l
+	c.ExpectNoMessage(t)
+
+	// unwanted lure type
+	l = getTestLure()
+	l.Hash = "stop2:1613800682"
+	l.LureType = pogo.LureTypeNormal
+	p.LureUpdates <- l
+	c.ExpectNoMessage(t)
+
+	// out of area
+	l = getTestLure()
+	l.Hash = "stop3:1613800682"
+	l.LureType = pogo.LureTypeMagnetic
+	l.Location = getTestPoint2KMAway()
+	p.LureUpdates <- l
+	c.ExpectNoMessage(t)
+
+	// new lure at the same stop
+	l = getTestLure()
+	l.Hash = "stop1:1613802482"
+	l.LureType = pogo.LureTypeMagnetic
+	l.EndTime = 1613802482
+	p.LureUpdates <- l
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Lure: Magnetic Lure Module")
+
+	p.mu.Lock()
+	rs := p.getOrCreateRoomState(testRoom)
+	assert.Equal(t, 2, len(rs.Lures))
+	rs.removeExpired(getTestLure().EndTime + 1)
+	assert.Equal(t, 1, len(rs.Lures))
+	assert.True(t, rs.lureIsPosted("stop1:1613802482"))
+	p.mu.Unlock()
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
+func TestPosterFortUpdates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silpht-geodex")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	gd, err := geodex.NewGeoDex(dir, "", "")
+	assert.NoError(t, err)
+	defer gd.Close()
+
+	p, done, c := startPoster()
+	p.GeoDex = gd
+
+	testRoom := "!foo@example.com"
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter = nil
+	rc.Lures = []LureFilter{
+		{
+			Area:      getTestRoomConfig(testRoom).Filter[0].Area,
+			LureTypes: []int{int(pogo.LureTypeGlacial)},
+		},
+	}
+	p.UpdateRoomConfig(rc)
+
+	l := getTestLure()
+	stop := pogo.Fort{
+		GUID:     "5707",
+		Name:     "Sphinx",
+		URL:      "http://example.com/sphinx.jpg",
+		Location: l.Location,
+	}
+	p.FortUpdates <- stop
+	// the name isn't lost when the scanner doesn't know it
+	stop.Name = ""
+	p.FortUpdates <- stop
+	// invalid GUID
+	p.FortUpdates <- pogo.Fort{GUID: "nope", Name: "Nope"}
+	// moved gym
+	p.FortUpdates <- pogo.Fort{GUID: "6ae", Location: l.Location, IsGym: true}
+	p.FortUpdates <- pogo.Fort{GUID: "6ae", Name: "Gym", Location: getTestPoint2KMAway(), IsGym: true}
+
+	// the new stop is named in posts
+	l.PokestopID = "5707"
+	l.PokestopName = ""
+	p.LureUpdates <- l
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "left) at Sphinx")
+
+	p.Quit <- true
+	<-done
+
+	f, err := gd.LookupFortNear(l.Location, 10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "5707", *f.GUID)
+		assert.Equal(t, geodex.FortTypeStop, f.Type)
+		assert.Equal(t, "Sphinx", f.GetName())
+	}
+	f, err = gd.Disk.GetFort("5707")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "http://example.com/sphinx.jpg", *f.URL)
+	}
+	_, err = gd.Disk.GetFort("nope")
+	assert.Error(t, err)
+
+	f, err = gd.LookupFortNear(getTestPoint2KMAway(), 10)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "6ae", *f.GUID)
+		assert.Equal(t, geodex.FortTypeGym, f.Type)
+		assert.Equal(t, "Gym", f.GetName())
+	}
+}
+
+func getTestGym(team pogo.TeamColor, updateTime int64) pogo.Gym {
+	return pogo.Gym{
+		GUID:           "bepis",
+		Name:           "Sphinx Gym",
+		TeamColor:      team,
+		SlotsAvailable: 3,
+		UpdateTime:     updateTime,
+		Location: pogo.Location{
+			Latitude:  30.05113,
+			Longitude: 31.21918,
+		},
+	}
+}
+
+func TestPosterGyms(t *testing.T) {
+	p, done, c := startPoster()
+
+	areaRoom := "!foo@example.com"
+	redRoom := "!red@example.com"
+
+	rc := getTestRoomConfig(areaRoom)
+	rc.Filter = nil
+	rc.Gyms = []GymFilter{
+		{Area: getTestRoomConfig(areaRoom).Filter[0].Area},
+	}
+	p.UpdateRoomConfig(rc)
+
+	rc = getTestRoomConfig(redRoom)
+	rc.Filter = nil
+	rc.Gyms = []GymFilter{
+		{GymIDs: []string{"bepis"}, Teams: []int{int(pogo.Red)}},
+	}
+	p.UpdateRoomConfig(rc)
+
+	// first sighting
+	p.GymUpdates <- getTestGym(pogo.Blue, 1000)
+	c.ExpectNoMessage(t)
+
+	// no change
+	p.GymUpdates <- getTestGym(pogo.Blue, 1100)
+	c.ExpectNoMessage(t)
+
+	p.GymUpdates <- getTestGym(pogo.Yellow, 1200)
+	c.ExpectMessage(t)
+	assert.Equal(t, areaRoom, c.LastRoomID)
+	assert.Equal(t, "Gym: Sphinx Gym turned yellow (was blue), 3 free slots", c.LastText)
+
+	p.GymUpdates <- getTestGym(pogo.Red, 1300)
+	c.ExpectMessages(t, 2)
+
+	// out of area, but subscribed by GUID
+	g := getTestGym(pogo.Blue, 1400)
+	g.Location = getTestPoint2KMAway()
+	p.GymUpdates <- g
+	c.ExpectNoMessage(t)
+	g = getTestGym(pogo.Red, 1500)
+	g.Location = getTestPoint2KMAway()
+	p.GymUpdates <- g
+	c.ExpectMessage(t)
+	assert.Equal(t, redRoom, c.LastRoomID)
+
+	for i := 0; i < gymHistoryLength; i++ {
+		p.GymUpdates <- getTestGym(pogo.Neutral, int64(2000+2*i))
+		p.GymUpdates <- getTestGym(pogo.Blue, int64(2001+2*i))
+	}
+	c.ExpectMessages(t, 2*gymHistoryLength)
+
+	gs, ok := p.GetGymState("bepis")
+	if assert.True(t, ok) {
+		assert.Equal(t, pogo.Blue, gs.TeamColor)
+		assert.Equal(t, int64(2009), gs.LastChange)
+		assert.Equal(t, int64(2009), gs.LastUpdate)
+		if assert.Equal(t, gymHistoryLength, len(gs.History)) {
+			assert.Equal(t, GymTeamChange{Time: 2009, From: pogo.Neutral, To: pogo.Blue}, gs.History[gymHistoryLength-1])
+		}
+	}
+	_, ok = p.GetGymState("conke")
+	assert.False(t, ok)
+
+	assert.Equal(t, 1, len(p.FindGymStates("sphinx")))
+	assert.Equal(t, 0, len(p.FindGymStates("pyramid")))
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
+func TestPosterGymStateTTL(t *testing.T) {
+	p := NewPoster(nil, nil)
+	now := time.Now().Unix()
+	old := getTestGym(pogo.Red, now-int64(defaultGymStateTTL/time.Second)-60)
+	old.GUID = "old"
+	p.processGymUpdate(old)
+	p.processGymUpdate(getTestGym(pogo.Red, now))
+
+	p.cleanupTick()
+	_, ok := p.GetGymState("old")
+	assert.False(t, ok)
+	_, ok = p.GetGymState("bepis")
+	assert.True(t, ok)
+
+	// 0 keeps them forever
+	p.GymStateTTL = 0
+	p.processGymUpdate(old)
+	p.cleanupTick()
+	_, ok = p.GetGymState("old")
+	assert.True(t, ok)
+}
+
+func TestPosterGymStateToString(t *testing.T) {
+	p := NewPoster(&testChatter{}, nil)
+	gs := &GymState{
+		GUID:           "bepis",
+		Name:           "Sphinx Gym",
+		TeamColor:      pogo.Red,
+		SlotsAvailable: 1,
+		ExRaidEligible: true,
+		LastUpdate:     time.Date(2021, 2, 20, 7, 0, 0, 0, time.Local).Unix(),
+	}
+	assert.Equal(t, "Sphinx Gym (bepis): red, 1 free slots, EX raid eligible\n"+
+		"last update: 2021-02-20 07:00:00\nno team changes seen yet", p.gymStateToString(gs))
+
+	gs.LastChange = gs.LastUpdate
+	gs.History = []GymTeamChange{
+		{Time: gs.LastUpdate - 3600, From: pogo.Neutral, To: pogo.Blue},
+		{Time: gs.LastUpdate, From: pogo.Blue, To: pogo.Red},
+	}
+	assert.Contains(t, p.gymStateToString(gs), "last team changes:\n"+
+		"2021-02-20 07:00:00 blue -> red\n2021-02-20 06:00:00 white -> blue")
+}
+
+func getTestQuest() pogo.Quest {
+	scanTime := time.Date(2021, 2, 20, 6, 0, 0, 0, time.Local)
+	expiry := pogo.QuestExpiry(scanTime)
+
+	return pogo.Quest{
+		Hash:         pogo.QuestHash("stop1", pogo.QuestRewardPokemonEncounter, 147, expiry),
+		PokestopID:   "stop1",
+		PokestopName: "Sphinx",
+		Task:         "Catch 5 Dragon-type Pokemon",
+		RewardType:   pogo.QuestRewardPokemonEncounter,
+		PokemonID:    147,
+		Amount:       1,
+		TimestampRange: pogo.TimestampRange{
+			StartTime: scanTime.Unix(),
+			EndTime:   expiry.Unix(),
+		},
+		Location: pogo.Location{
+			Latitude:  30.05113,
+			Longitude: 31.21918,
+		},
+	}
+}
+
+func TestPosterQuests(t *testing.T) {
+	p, done, c := startPosterPokedex(t, testPokedexFile)
+
+	testRoom := "!foo@example.com"
+	area := getTestRoomConfig(testRoom).Filter[0].Area
+
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter = nil
+	rc.Quests = []QuestFilter{
+		{Area: area, RewardType: pogo.QuestRewardPokemonEncounter, RewardIDs: []int{147}},
+		{Area: area, RewardType: pogo.QuestRewardStardust, MinAmount: 1000},
+		{Area: area, RewardType: pogo.QuestRewardItem},
+		{Area: area, RewardType: pogo.QuestRewardMegaEnergy},
+	}
+	p.UpdateRoomConfig(rc)
+
+	q := getTestQuest()
+	p.QuestUpdates <- q
+	c.ExpectMessage(t)
+	assert.Equal(t, "Quest: Dratini encounter at Sphinx (Catch 5 Dragon-type Pokemon), until midnight", c.LastText)
+
+	// rescan on the same day
+	p.QuestUpdates <- q
+	c.ExpectNoMessage(t)
+
+	// unwanted encounter
+	q = getTestQuest()
+	q.PokemonID = 16
+	q.Hash = "pidgey"
+	p.QuestUpdates <- q
+	c.ExpectNoMessage(t)
+
+	// not enough stardust
+	q = getTestQuest()
+	q.RewardType = pogo.QuestRewardStardust
+	q.PokemonID = 0
+	q.Amount = 500
+	q.Hash = "dust500"
+	p.QuestUpdates <- q
+	c.ExpectNoMessage(t)
+
+	q.Amount = 1500
+	q.Hash = "dust1500"
+	p.QuestUpdates <- q
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Quest: 1500 Stardust at Sphinx")
+
+	q = getTestQuest()
+	q.RewardType = pogo.QuestRewardItem
+	q.PokemonID = 0
+	q.ItemID = 706
+	q.Amount = 3
+	q.Hash = "razz"
+	p.QuestUpdates <- q
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Quest: 3x Golden Razz Berry at Sphinx")
+
+	q = getTestQuest()
+	q.RewardType = pogo.QuestRewardMegaEnergy
+	q.PokemonID = 6
+	q.Amount = 20
+	q.Hash = "mega"
+	p.QuestUpdates <- q
+	c.ExpectMessage(t)
+	assert.Contains(t, c.LastText, "Quest: 20 Charizard (de: Glurak) Mega Energy at Sphinx")
+
+	// out of area
+	q = getTestQuest()
+	q.Hash = "faraway"
+	q.Location = getTestPoint2KMAway()
+	p.QuestUpdates <- q
+	c.ExpectNoMessage(t)
+
+	// quests expire at midnight
+	p.mu.Lock()
+	rs := p.getOrCreateRoomState(testRoom)
+	assert.Equal(t, 4, len(rs.Quests))
+	rs.removeExpired(q.EndTime - 1)
+	assert.Equal(t, 4, len(rs.Quests))
+	rs.removeExpired(q.EndTime + 1)
+	assert.Equal(t, 0, len(rs.Quests))
+	p.mu.Unlock()
+
+	// wait
+	p.Quit <- true
+	<-done
+}
+
 func TestPoster_ChangeRoomConfig(t *testing.T) {
 	testRoom := "!foo@example.com"
 
@@ -775,3 +1867,236 @@ func TestPoster_ChangeRoomConfig(t *testing.T) {
 		})
 	}
 }
+
+// TestPosterConcurrentCommands edits filters while Run() processes updates, run it with -race
+func TestPosterConcurrentCommands(t *testing.T) {
+	done := make(chan bool)
+	c := &testChatter{
+		MessageReceived: make(chan bool, 10),
+	}
+
+	p := NewPoster(c, nil)
+	p.Admins = []string{testAdminID}
+	// cleanup as often as possible
+	p.ExpiryCheckPeriod = 1 * time.Millisecond
+	p.GymUpdates = make(chan pogo.Gym)
+	p.RaidUpdates = make(chan pogo.Raid)
+	p.SpawnUpdates = make(chan pogo.Spawn)
+	p.WeatherUpdates = make(chan pogo.Weather)
+	p.InvasionUpdates = make(chan pogo.Invasion)
+	p.QuestUpdates = make(chan pogo.Quest)
+	p.LureUpdates = make(chan pogo.Lure)
+	p.FortUpdates = make(chan pogo.Fort)
+	p.Quit = make(chan bool)
+
+	testRoom := "!foo@example.com"
+	rc := getTestRoomConfig(testRoom)
+	p.UpdateRoomConfig(rc)
+
+	go func() {
+		p.Run()
+		done <- true
+	}()
+
+	// swallow all messages, we only care about the data accesses
+	stopDrain := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-c.MessageReceived:
+			case <-stopDrain:
+				return
+			}
+		}
+	}()
+
+	const rounds = 100
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	// scanner
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			s := getTestSpawn()
+			s.EncounterID = fmt.Sprintf("enc%d", i)
+			s.EndTime = time.Now().Unix() + int64(i%3) - 1
+			p.SpawnUpdates <- s
+
+			r := getTestRaid()
+			r.Hash = fmt.Sprintf("raid%d", i)
+			r.EndTime = s.EndTime
+			p.RaidUpdates <- r
+
+			p.GymUpdates <- pogo.Gym{}
+		}
+	}()
+
+	// room members
+	go func() {
+		defer wg.Done()
+		ctx := Context{
+			Chatter: c,
+			RoomID:  testRoom,
+			Poster:  p,
+			Sender:  testAdminID,
+		}
+		cmds := []string{
+			"spawn add 0 16",
+			"spawn rm 0 16",
+			"filter add raid 30.04896 31.22366 1000",
+			"raid add 1 150",
+			"filter area 0 30.04896 31.22366 500",
+			"filter rm 1",
+			"admin roomstate",
+			"admin roomconfig",
+			"admin roomstate_clear",
+			"status",
+		}
+		for i := 0; i < rounds; i++ {
+			p.ParseMessage(cmds[i%len(cmds)], ctx)
+		}
+	}()
+
+	// something reading configs, like a web interface
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if rc, ok := p.GetRoomConfig(testRoom); ok {
+				// the copy belongs to us
+				rc.Filter = nil
+			}
+			p.GetRoomState(testRoom)
+		}
+	}()
+
+	wg.Wait()
+	p.Quit <- true
+	<-done
+	stopDrain <- true
+
+	rc, ok := p.GetRoomConfig(testRoom)
+	assert.Equal(t, true, ok)
+	assert.NotEmpty(t, rc.Filter)
+}
+
+func TestPosterActiveEvents(t *testing.T) {
+	p := NewPoster(nil, nil)
+	dex, err := pogo.NewPokedex(testPokedexFile)
+	assert.Nil(t, err)
+	p.Pokedex = dex
+	endTime := time.Now().Add(10 * time.Minute).Unix()
+
+	spawn := getTestSpawn()
+	spawn.EndTime = endTime
+	expired := getTestSpawn()
+	expired.EncounterID = "expired"
+	far := getTestSpawn()
+	far.EncounterID = "far"
+	far.EndTime = endTime
+	far.Location = getTestPoint2KMAway()
+	egg := getTestEgg()
+	egg.EndTime = endTime
+	raid := getTestRaid()
+	raid.Hash = egg.Hash
+	raid.EndTime = endTime
+	invasion := getTestInvasion()
+	invasion.EndTime = endTime
+
+	p.processSpawnUpdate(spawn)
+	p.processSpawnUpdate(expired)
+	p.processSpawnUpdate(far)
+	p.processRaidUpdate(egg)
+	p.processRaidUpdate(raid)
+	p.processInvasionUpdate(invasion)
+	p.processGymUpdate(getTestGym(pogo.Red, 1613800000))
+
+	list := p.GetActiveEvents(nil, nil)
+	if assert.Equal(t, 2, len(list.Spawns)) {
+		assert.Equal(t, "abc", list.Spawns[0].EncounterID)
+		assert.Equal(t, "Pidgey", list.Spawns[0].Pokemon.Name)
+		assert.Equal(t, "far", list.Spawns[1].EncounterID)
+	}
+	// the hatched raid replaced the egg
+	if assert.Equal(t, 1, len(list.Raids)) {
+		assert.Equal(t, "Mewtwo", list.Raids[0].Pokemon.Name)
+		assert.Equal(t, "conke", list.Raids[0].GymName)
+	}
+	assert.Equal(t, 1, len(list.Invasions))
+	if assert.Equal(t, 1, len(list.Gyms)) {
+		assert.Equal(t, "Sphinx Gym", list.Gyms[0].Name)
+	}
+
+	bbox := pogo.NewBoundingBox(30.05, 31.21, 30.06, 31.22)
+	list = p.GetActiveEvents([]string{ingest.EventSpawn, ingest.EventGym}, &bbox)
+	assert.Equal(t, 1, len(list.Spawns))
+	assert.Equal(t, 0, len(list.Raids))
+	assert.Equal(t, 0, len(list.Invasions))
+	assert.Equal(t, 1, len(list.Gyms))
+
+	// the expired spawn is only removed by the cleanup
+	assert.Equal(t, 3, len(p.active.Spawns))
+	p.cleanupTick()
+	assert.Equal(t, 2, len(p.active.Spawns))
+	// snapshots don't share the stored pokemon
+	assert.Equal(t, "", p.active.Raids[egg.Hash].Pokemon.Name)
+}
+
+func TestPosterPublish(t *testing.T) {
+	c := &testChatter{
+		MessageReceived: make(chan bool, 10),
+	}
+	p := NewPoster(c, nil)
+	stream := &testStream{}
+	p.Stream = stream
+	testRoom := "!foo@example.com"
+	rc := getTestRoomConfig(testRoom)
+	rc.Filter = append(rc.Filter, PokemonFilter{
+		ListRaids:  true,
+		ListWanted: true,
+		PokemonIDs: []int{150},
+		RaidLevels: []int{3},
+		Area:       rc.Filter[0].Area,
+	})
+	p.UpdateRoomConfig(rc)
+
+	spawn := getTestSpawn()
+	spawn.Encounter = &pogo.Encounter{Attack: 15, Defense: 15, Stamina: 15}
+	p.processSpawnUpdate(spawn)
+	egg := getTestEgg()
+	p.processRaidUpdate(egg)
+	raid := getTestRaid()
+	raid.Hash = egg.Hash
+	raid.Level = egg.Level
+	p.processRaidUpdate(raid)
+	p.sendOutbox()
+	c.ExpectMessages(t, 3)
+
+	if assert.Equal(t, 3, len(stream.events)) {
+		event := stream.events[0]
+		assert.Equal(t, testRoom, event.RoomID)
+		assert.Equal(t, PostKindSpawn, event.Kind)
+		assert.NotZero(t, event.Time)
+		assert.Nil(t, event.Raid)
+		if assert.NotNil(t, event.Spawn) {
+			assert.Equal(t, "abc", event.Spawn.EncounterID)
+			assert.Equal(t, "Pokemon #16", event.Spawn.PokemonName)
+			assert.Equal(t, 100.0, event.Spawn.Encounter.IVPercent())
+		}
+
+		event = stream.events[1]
+		assert.Equal(t, PostKindEgg, event.Kind)
+		if assert.NotNil(t, event.Raid) {
+			assert.Equal(t, "Level 3", event.Raid.LevelName)
+			assert.Equal(t, 0, event.Raid.PokemonID)
+		}
+
+		event = stream.events[2]
+		assert.Equal(t, PostKindHatched, event.Kind)
+		assert.Equal(t, c.LastText, event.Text)
+		if assert.NotNil(t, event.Raid) {
+			assert.Equal(t, 150, event.Raid.PokemonID)
+			assert.Equal(t, "conke", event.Raid.GymName)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package roomservice
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spezifisch/silphtelescope/pkg/pogo"
+)
+
+// GetWeather returns a copy of the last known weather in the cell containing the location
+func (p *Poster) GetWeather(l *pogo.Location) (w pogo.Weather, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cw := p.getWeatherAt(l); cw != nil {
+		w = *cw
+		ok = true
+	}
+	return
+}
+
+// getWeatherAt returns the last known weather in the cell containing the location. p.mu must be held.
+func (p *Poster) getWeatherAt(l *pogo.Location) *pogo.Weather {
+	return p.weather[pogo.WeatherCellID(l)]
+}
+
+func (p *Poster) processWeatherUpdate(w pogo.Weather) {
+	prev, known := p.weather[w.CellID]
+	p.weather[w.CellID] = &w
+
+	// only alert when a warning is issued or gets more severe, not on every update
+	if w.AlertSeverity == pogo.AlertNone {
+		return
+	}
+	if known && prev.AlertSeverity >= w.AlertSeverity {
+		return
+	}
+
+	log.Debugf("weather alert in cell %d: %s", w.CellID, w.AlertSeverity.ToString())
+	for _, room := range p.roomConfigs {
+		if room.WeatherAlerts && room.coversWeatherCell(&w) {
+			p.postWeatherAlert(room, &w)
+		}
+	}
+}
+
+// coversWeatherCell checks if any filter area is in the weather cell
+func (r *RoomConfig) coversWeatherCell(w *pogo.Weather) bool {
+	for _, filter := range r.Filter {
+		if filter.Area.Contains(&w.Location) || pogo.WeatherCellID(&filter.Area.Location) == w.CellID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Poster) postWeatherAlert(room *RoomConfig, w *pogo.Weather) {
+	text := fmt.Sprintf("Severe weather warning (%s) near %s: %s",
+		w.AlertSeverity.ToString(), w.Location.ToLinkGMaps(), w.Condition.ToString())
+	if room.FormatText {
+		fText := fmt.Sprintf("Severe weather warning (%s) <a href=\"%s\">nearby</a>: %s",
+			w.AlertSeverity.ToString(), w.Location.ToLinkGMaps(), w.Condition.ToString())
+		p.sendFormattedText(room.RoomID, text, fText)
+	} else {
+		p.sendText(room.RoomID, text)
+	}
+}
+
+// weatherToString returns the weather in human-readable form, used by the weather command
+func weatherToString(w *pogo.Weather) string {
+	text := fmt.Sprintf("Weather: %s, updated %s", w.Condition.BoostToString(),
+		time.Unix(w.UpdateTime, 0).Format("15:04:05"))
+	if w.AlertSeverity != pogo.AlertNone {
+		text = fmt.Sprintf("%s\nSevere weather warning: %s", text, w.AlertSeverity.ToString())
+	}
+	return text
+}
@@ -1,8 +1,11 @@
 package roomservice
 
 import (
+	"errors"
 	"fmt"
+	"math"
 
+	"github.com/spezifisch/silphtelescope/internal/helpers"
 	"github.com/spezifisch/silphtelescope/pkg/pogo"
 )
 
@@ -12,14 +15,289 @@ type PokemonFilter struct {
 	ListRaids  bool                // true if this filter only matches raids, false for only spawns
 	ListWanted bool                // true if only wanted pokemon ids are in the list, false for unwanted pokemon
 	PokemonIDs []int               // pokedex numbers
+	RaidLevels []int               // post raid eggs of these levels (only for raid filters)
+	Encounter  *EncounterCondition // IV/CP/level conditions (only for spawn filters), nil matches every spawn
 }
 
+// IntRange is an inclusive range, Max=0 means there is no upper limit
+type IntRange struct {
+	Min int
+	Max int
+}
+
+func (r *IntRange) contains(v int) bool {
+	return v >= r.Min && (r.Max == 0 || v <= r.Max)
+}
+
+func (r *IntRange) valid() bool {
+	return r.Min >= 0 && r.Max >= 0 && (r.Max == 0 || r.Min <= r.Max)
+}
+
+// ToString converts IntRange into a human-readable string
+func (r IntRange) ToString() string {
+	if r.Max == 0 {
+		return fmt.Sprintf("%d+", r.Min)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// FloatRange is an inclusive range
+type FloatRange struct {
+	Min float64
+	Max float64
+}
+
+func (r *FloatRange) contains(v float64) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// valid checks 0 <= Min <= Max <= upperLimit, NaN is never valid
+func (r *FloatRange) valid(upperLimit float64) bool {
+	return r.Min >= 0 && r.Min <= r.Max && r.Max <= upperLimit
+}
+
+// EncounterCondition restricts a spawn filter to pokemon with certain stats.
+// Spawns without encounter data never match a filter with conditions because their stats are unknown.
+type EncounterCondition struct {
+	IV         *FloatRange // IV percent, nil for any
+	MinAttack  int         // per-stat minimum, 0-15
+	MinDefense int
+	MinStamina int
+	CP         *IntRange // nil for any
+	Level      *IntRange // nil for any
+}
+
+// ToString converts EncounterCondition into a human-readable string
+func (c *EncounterCondition) ToString() string {
+	s := fmt.Sprintf("stats>=%d/%d/%d", c.MinAttack, c.MinDefense, c.MinStamina)
+	if c.IV != nil {
+		s = fmt.Sprintf("%s IV:%.0f-%.0f%%", s, c.IV.Min, c.IV.Max)
+	}
+	if c.CP != nil {
+		s = fmt.Sprintf("%s CP:%s", s, c.CP.ToString())
+	}
+	if c.Level != nil {
+		s = fmt.Sprintf("%s L:%s", s, c.Level.ToString())
+	}
+	return s
+}
+
+// validStat checks a single IV
+func validStat(v int) bool {
+	return v >= 0 && v <= 15
+}
+
+// validate checks the conditions like the filter commands do
+func (c *EncounterCondition) validate() error {
+	if c.IV != nil && !c.IV.valid(100) {
+		return errors.New("invalid IV range")
+	}
+	if !validStat(c.MinAttack) || !validStat(c.MinDefense) || !validStat(c.MinStamina) {
+		return errors.New("invalid stats")
+	}
+	if c.CP != nil && !c.CP.valid() {
+		return errors.New("invalid CP range")
+	}
+	if c.Level != nil && !c.Level.valid() {
+		return errors.New("invalid level range")
+	}
+	return nil
+}
+
+// matches checks the encounter data against all conditions
+func (c *EncounterCondition) matches(e *pogo.Encounter) bool {
+	if e == nil {
+		return false
+	}
+	if c.IV != nil && !c.IV.contains(e.IVPercent()) {
+		return false
+	}
+	if e.Attack < c.MinAttack || e.Defense < c.MinDefense || e.Stamina < c.MinStamina {
+		return false
+	}
+	if c.CP != nil && !c.CP.contains(e.CP) {
+		return false
+	}
+	if c.Level != nil && !c.Level.contains(e.Level) {
+		return false
+	}
+	return true
+}
+
+// Validate checks a filter that didn't come from the filter commands, e.g. from the admin API
+func (f *PokemonFilter) Validate() error {
+	if err := validateArea(f.Area); err != nil {
+		return err
+	}
+	if f.ListRaids && f.Encounter != nil {
+		return errors.New("encounter conditions are only for spawn filters")
+	}
+	if !f.ListRaids && len(f.RaidLevels) > 0 {
+		return errors.New("raid levels are only for raid filters")
+	}
+	for _, level := range f.RaidLevels {
+		if level < 1 || level > pogo.RaidLevelMega {
+			return errors.New("invalid raid level")
+		}
+	}
+	if f.Encounter != nil {
+		return f.Encounter.validate()
+	}
+	return nil
+}
+
+// validateArea checks the coordinates and that the radius isn't negative
+func validateArea(a pogo.LocationRadius) error {
+	for _, v := range []float64{a.Latitude, a.Longitude, a.RadiusM} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return errors.New("invalid area")
+		}
+	}
+	if a.Latitude < -90 || a.Latitude > 90 || a.Longitude < -180 || a.Longitude > 180 {
+		return errors.New("invalid location")
+	}
+	if a.RadiusM < 0 {
+		return errors.New("invalid radius")
+	}
+	return nil
+}
+
+// matchesPokemon checks the pokedex number against the wanted or unwanted list
+func (f *PokemonFilter) matchesPokemon(pokemonID int) bool {
+	listed := helpers.IntArrayContains(f.PokemonIDs, pokemonID)
+	if f.ListWanted {
+		return listed
+	}
+	// exclusion list: everything that isn't listed
+	return !listed
+}
+
+// matchesEncounter checks the spawn's encounter data against the filter's conditions
+func (f *PokemonFilter) matchesEncounter(e *pogo.Encounter) bool {
+	if f.Encounter == nil {
+		return true
+	}
+	return f.Encounter.matches(e)
+}
+
+// matchesEgg checks if eggs of this raid level should be posted
+func (f *PokemonFilter) matchesEgg(level int) bool {
+	return f.ListRaids && helpers.IntArrayContains(f.RaidLevels, level)
+}
+
+// matchesHatched checks the boss of an egg this filter matched.
+// Filters that only list eggs (no wanted Pokemon) want to know every boss.
+func (f *PokemonFilter) matchesHatched(pokemonID int) bool {
+	if f.ListWanted && len(f.PokemonIDs) == 0 {
+		return true
+	}
+	return f.matchesPokemon(pokemonID)
+}
+
+// InvasionFilter specifies which Team GO Rocket invasions in an area should be posted
+type InvasionFilter struct {
+	Area       pogo.LocationRadius // area to include
+	GruntTypes []int               // InvasionCharacter ids, empty for all invasions
+}
+
+// matchesGruntType checks if the invasion's character is wanted
+func (f *InvasionFilter) matchesGruntType(g pogo.GruntType) bool {
+	return len(f.GruntTypes) == 0 || helpers.IntArrayContains(f.GruntTypes, int(g))
+}
+
+// QuestFilter specifies which field research rewards in an area should be posted
+type QuestFilter struct {
+	Area       pogo.LocationRadius  // area to include
+	RewardType pogo.QuestRewardType // encounter, item, stardust, mega energy, ...
+	RewardIDs  []int                // pokemon ids or item ids depending on the reward type, empty for all
+	MinAmount  int                  // e.g. for stardust rewards, 0 for any amount
+}
+
+// matchesQuest checks the quest reward
+func (f *QuestFilter) matchesQuest(q *pogo.Quest) bool {
+	if q.RewardType != f.RewardType || q.Amount < f.MinAmount {
+		return false
+	}
+	return len(f.RewardIDs) == 0 || helpers.IntArrayContains(f.RewardIDs, q.RewardID())
+}
+
+// LureFilter specifies which lure modules in an area should be posted
+type LureFilter struct {
+	Area      pogo.LocationRadius // area to include
+	LureTypes []int               // lure item ids, empty for all lures
+}
+
+// matchesLureType checks if the lure module is wanted
+func (f *LureFilter) matchesLureType(l pogo.LureType) bool {
+	return len(f.LureTypes) == 0 || helpers.IntArrayContains(f.LureTypes, int(l))
+}
+
+// GymFilter specifies which gyms should be watched for team changes, either by GUID or by area
+type GymFilter struct {
+	Area   pogo.LocationRadius // area to include, only used without GymIDs
+	GymIDs []string            // GUIDs of the gyms
+	Teams  []int               // TeamColors the gym turned to, empty for all
+}
+
+// matchesGym checks if the gym and its new team are wanted
+func (f *GymFilter) matchesGym(gs *GymState) bool {
+	if len(f.Teams) > 0 && !helpers.IntArrayContains(f.Teams, int(gs.TeamColor)) {
+		return false
+	}
+	if len(f.GymIDs) > 0 {
+		return helpers.StringArrayContains(f.GymIDs, gs.GUID)
+	}
+	return f.Area.Contains(&gs.Location)
+}
+
+// roomConfigVersion is the current format version of RoomConfig, see migrate()
+const roomConfigVersion = 2
+
 // RoomConfig contains settings for a room with one or more people
 type RoomConfig struct {
 	RoomID         string
+	Version        int  // format version to migrate old configs read from disk
 	AcceptCommands bool // parse commands from users in this room (admin privileges are checked seperately)
 	FormatText     bool
+	WeatherAlerts  bool   // post severe weather warnings for the filter areas
+	StreamToken    string // bearer token for the room's event stream, disabled if empty
 	Filter         []PokemonFilter
+	Invasions      []InvasionFilter
+	Quests         []QuestFilter
+	Lures          []LureFilter
+	Gyms           []GymFilter
+}
+
+// wantsHatched checks the boss against the filters matching the egg it hatched from
+func (r *RoomConfig) wantsHatched(raid *pogo.Raid) bool {
+	for _, filter := range r.Filter {
+		if filter.matchesEgg(raid.Level) && filter.Area.Contains(&raid.Location) && filter.matchesHatched(raid.Pokemon.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterListLength returns the number of filters in the given list
+func (r *RoomConfig) filterListLength(list FilterList) int {
+	switch list {
+	case FilterListPokemon:
+		return len(r.Filter)
+	case FilterListInvasion:
+		return len(r.Invasions)
+	case FilterListQuest:
+		return len(r.Quests)
+	case FilterListLure:
+		return len(r.Lures)
+	case FilterListGym:
+		return len(r.Gyms)
+	}
+	return 0
+}
+
+// filterCount returns the number of filters of all types
+func (r *RoomConfig) filterCount() int {
+	return len(r.Filter) + len(r.Invasions) + len(r.Quests) + len(r.Lures) + len(r.Gyms)
 }
 
 // ToString converts RoomConfig into a human-readable string
@@ -27,3 +305,27 @@ func (r RoomConfig) ToString() string {
 	s := fmt.Sprintf("RoomID:%s", r.RoomID)
 	return s
 }
+
+// migrate upgrades a RoomConfig read from disk to the current format version
+func (r *RoomConfig) migrate() (changed bool) {
+	if r.Version < 1 {
+		// AcceptCommands wasn't checked before version 1 and the filter command never set it,
+		// so every existing room would be locked if we took it literally.
+		r.AcceptCommands = true
+		r.Version = 1
+		changed = true
+	}
+	if r.Version < 2 {
+		// ListWanted was only checked for spawns before version 2 and the filter command
+		// created raid filters with ListWanted=false. Now that it's checked for raids too,
+		// they'd turn into exclusion lists, so make them lists of wanted raid bosses.
+		for i := range r.Filter {
+			if r.Filter[i].ListRaids {
+				r.Filter[i].ListWanted = true
+			}
+		}
+		r.Version = 2
+		changed = true
+	}
+	return
+}
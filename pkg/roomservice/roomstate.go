@@ -8,6 +8,12 @@ type RoomState struct {
 	Spawns map[string]*SpawnState
 	// Raid hash -> RaidState
 	Raids map[string]*RaidState
+	// Invasion hash -> EventState
+	Invasions map[string]*EventState
+	// Quest hash -> EventState
+	Quests map[string]*EventState
+	// Lure hash -> EventState
+	Lures map[string]*EventState
 }
 
 // SpawnState tracks if a spawn was already posted as long as it has not elapsed
@@ -20,23 +26,38 @@ type SpawnState struct {
 type RaidState struct {
 	EndTime int64
 	Posted  bool
+	Egg     bool // posted as egg, the hatched raid boss hasn't been posted yet
+}
+
+// EventState tracks if an event at a fort was already posted as long as it has not elapsed
+type EventState struct {
+	EndTime int64
+	Posted  bool
 }
 
 // NewRoomState creates a RoomState object
 func NewRoomState() *RoomState {
 	return &RoomState{
-		Spawns: make(map[string]*SpawnState),
-		Raids:  make(map[string]*RaidState),
+		Spawns:    make(map[string]*SpawnState),
+		Raids:     make(map[string]*RaidState),
+		Invasions: make(map[string]*EventState),
+		Quests:    make(map[string]*EventState),
+		Lures:     make(map[string]*EventState),
 	}
 }
 
-func (r *RoomState) clear(spawns, raids bool) {
+func (r *RoomState) clear(spawns, raids, events bool) {
 	if spawns {
 		r.Spawns = make(map[string]*SpawnState)
 	}
 	if raids {
 		r.Raids = make(map[string]*RaidState)
 	}
+	if events {
+		r.Invasions = make(map[string]*EventState)
+		r.Quests = make(map[string]*EventState)
+		r.Lures = make(map[string]*EventState)
+	}
 }
 
 func (r *RoomState) raidIsPosted(hash string) bool {
@@ -44,15 +65,57 @@ func (r *RoomState) raidIsPosted(hash string) bool {
 	return found && s.Posted
 }
 
+func (r *RoomState) raidIsPostedEgg(hash string) bool {
+	s, found := r.Raids[hash]
+	return found && s.Posted && s.Egg
+}
+
 func (r *RoomState) spawnIsPosted(encounterID string) bool {
 	s, found := r.Spawns[encounterID]
 	return found && s.Posted
 }
 
+func (r *RoomState) invasionIsPosted(hash string) bool {
+	s, found := r.Invasions[hash]
+	return found && s.Posted
+}
+
+func (r *RoomState) postedInvasion(i *pogo.Invasion, posted bool) {
+	r.Invasions[i.Hash] = &EventState{
+		EndTime: i.EndTime,
+		Posted:  posted,
+	}
+}
+
+func (r *RoomState) questIsPosted(hash string) bool {
+	s, found := r.Quests[hash]
+	return found && s.Posted
+}
+
+func (r *RoomState) postedQuest(q *pogo.Quest, posted bool) {
+	r.Quests[q.Hash] = &EventState{
+		EndTime: q.EndTime,
+		Posted:  posted,
+	}
+}
+
+func (r *RoomState) lureIsPosted(hash string) bool {
+	s, found := r.Lures[hash]
+	return found && s.Posted
+}
+
+func (r *RoomState) postedLure(l *pogo.Lure, posted bool) {
+	r.Lures[l.Hash] = &EventState{
+		EndTime: l.EndTime,
+		Posted:  posted,
+	}
+}
+
 func (r *RoomState) postedRaid(s *pogo.Raid, posted bool) {
 	r.Raids[s.Hash] = &RaidState{
 		EndTime: s.EndTime,
 		Posted:  posted,
+		Egg:     s.IsEgg(),
 	}
 }
 
@@ -77,5 +140,23 @@ func (r *RoomState) removeExpired(before int64) (deleted int) {
 			deleted++
 		}
 	}
+	for k, st := range r.Invasions {
+		if st.EndTime < before {
+			delete(r.Invasions, k)
+			deleted++
+		}
+	}
+	for k, st := range r.Quests {
+		if st.EndTime < before {
+			delete(r.Quests, k)
+			deleted++
+		}
+	}
+	for k, st := range r.Lures {
+		if st.EndTime < before {
+			delete(r.Lures, k)
+			deleted++
+		}
+	}
 	return
 }
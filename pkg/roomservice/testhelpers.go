@@ -1,6 +1,7 @@
 package roomservice
 
 import (
+	"sync"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
@@ -13,9 +14,15 @@ type testChatter struct {
 	MessageReceived                         chan bool
 	Counter                                 int
 	LastExpectedMessage                     int
+
+	// messages can be sent by Poster.Run() and command handlers at the same time
+	mu sync.Mutex
 }
 
 func (c *testChatter) SendText(roomID, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.LastRoomID = roomID
 	c.LastText = text
 	c.LastFormattedText = ""
@@ -25,6 +32,9 @@ func (c *testChatter) SendText(roomID, text string) {
 }
 
 func (c *testChatter) SendFormattedText(roomID, text, formattedText string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.LastRoomID = roomID
 	c.LastText = text
 	c.LastFormattedText = formattedText
@@ -36,15 +46,73 @@ func (c *testChatter) SendFormattedText(roomID, text, formattedText string) {
 func (c *testChatter) ExpectMessage(t *testing.T) {
 	<-c.MessageReceived
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	assert.Equal(t, c.LastExpectedMessage+1, c.Counter, "message counter didn't increase by one between messages")
 
 	c.LastExpectedMessage = c.Counter
 }
 
+// ExpectMessages waits for multiple messages which might be sent back-to-back, e.g. to different rooms
+func (c *testChatter) ExpectMessages(t *testing.T, count int) {
+	for i := 0; i < count; i++ {
+		<-c.MessageReceived
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Equal(t, c.LastExpectedMessage+count, c.Counter, "message counter didn't increase by count")
+
+	c.LastExpectedMessage = c.Counter
+}
+
 func (c *testChatter) ExpectNoMessage(t *testing.T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	assert.Equal(t, c.LastExpectedMessage, c.Counter, "a message was sent that shouldn't have been sent")
 }
 
 func (c *testChatter) PrintLastMessage() {
 	log.Println("<Bot>", c.LastText)
 }
+
+// testAdminID is a bot admin in tests that need privileged commands
+const testAdminID = "@admin:example.com"
+
+// testPowerLevels mocks up matrix power levels for permission checks
+type testPowerLevels struct {
+	levels map[string]int
+	err    error
+}
+
+func (t *testPowerLevels) GetUserPowerLevel(roomID, userID string) (level int, err error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	return t.levels[userID], nil
+}
+
+// testLoginLinks records the login links handed out by the web command
+type testLoginLinks struct {
+	roomID string
+	userID string
+}
+
+func (t *testLoginLinks) CreateLoginLink(roomID, userID string) (link string, err error) {
+	t.roomID = roomID
+	t.userID = userID
+	return "https://silpht.example.com/ui/#login=abc", nil
+}
+
+// testStream records the published posts
+type testStream struct {
+	events []PostEvent
+}
+
+func (t *testStream) Publish(event PostEvent) {
+	t.events = append(t.events, event)
+}
+
+func (t *testStream) StreamURL(roomID string) string {
+	return "https://silpht.example.com/stream/" + roomID
+}
@@ -7,7 +7,7 @@ cd "`dirname \"$0\"`"
 DIRS="./cmd/* ./internal/* ./pkg/*"
 
 set -x
-go test -cover $DIRS
+go test -race -cover $DIRS
 go vet $DIRS
 golint $DIRS
 